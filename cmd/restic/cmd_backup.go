@@ -96,8 +96,13 @@ type BackupOptions struct {
 	ExcludeCaches     bool
 	ExcludeLargerThan string
 	Stdin             bool
+	StdinFromTar      bool
+	StdinFromCommand  bool
 	StdinFilename     string
 	Tags              restic.TagLists
+	Labels            restic.LabelMap
+	Description       string
+	DescriptionFile   string
 	Host              string
 	FilesFrom         []string
 	FilesFromVerbatim []string
@@ -110,6 +115,8 @@ type BackupOptions struct {
 	DryRun            bool
 	ReadConcurrency   uint
 	NoScan            bool
+	ChangesFrom       string
+	DetectMoves       bool
 }
 
 var backupOptions BackupOptions
@@ -133,8 +140,13 @@ func init() {
 	f.BoolVar(&backupOptions.ExcludeCaches, "exclude-caches", false, `excludes cache directories that are marked with a CACHEDIR.TAG file. See https://bford.info/cachedir/ for the Cache Directory Tagging Standard`)
 	f.StringVar(&backupOptions.ExcludeLargerThan, "exclude-larger-than", "", "max `size` of the files to be backed up (allowed suffixes: k/K, m/M, g/G, t/T)")
 	f.BoolVar(&backupOptions.Stdin, "stdin", false, "read backup from stdin")
+	f.BoolVar(&backupOptions.StdinFromTar, "stdin-from-tar", false, "read a tar archive from stdin and save its contents as a directory (zip archives are supported if stdin is a file, tar archives are spooled to $TMPDIR)")
+	f.BoolVar(&backupOptions.StdinFromCommand, "stdin-from-command", false, "read backup from the output of the command given as arguments, the backup fails if the command exits with a non-zero code")
 	f.StringVar(&backupOptions.StdinFilename, "stdin-filename", "stdin", "`filename` to use when reading from stdin")
 	f.Var(&backupOptions.Tags, "tag", "add `tags` for the new snapshot in the format `tag[,tag,...]` (can be specified multiple times)")
+	f.StringVar(&backupOptions.Description, "description", "", "set the description `text` for the new snapshot")
+	f.StringVar(&backupOptions.DescriptionFile, "description-file", "", "read the description for the new snapshot from `file`")
+	f.Var(&backupOptions.Labels, "label", "add the label `key=value` to the new snapshot (can be specified multiple times)")
 	f.UintVar(&backupOptions.ReadConcurrency, "read-concurrency", 0, "read `n` files concurrently (default: $RESTIC_READ_CONCURRENCY or 2)")
 	f.StringVarP(&backupOptions.Host, "host", "H", "", "set the `hostname` for the snapshot manually. To prevent an expensive rescan use the \"parent\" flag")
 	f.StringVar(&backupOptions.Host, "hostname", "", "set the `hostname` for the snapshot manually")
@@ -150,8 +162,10 @@ func init() {
 	f.BoolVar(&backupOptions.WithAtime, "with-atime", false, "store the atime for all files and directories")
 	f.BoolVar(&backupOptions.IgnoreInode, "ignore-inode", false, "ignore inode number changes when checking for modified files")
 	f.BoolVar(&backupOptions.IgnoreCtime, "ignore-ctime", false, "ignore ctime changes when checking for modified files")
+	f.BoolVar(&backupOptions.DetectMoves, "detect-moves", false, "find moved or renamed files in the parent snapshot by their inode number to avoid reading them again")
 	f.BoolVarP(&backupOptions.DryRun, "dry-run", "n", false, "do not upload or write any data, just show what would be done")
 	f.BoolVar(&backupOptions.NoScan, "no-scan", false, "do not run scanner to estimate size of backup")
+	f.StringVar(&backupOptions.ChangesFrom, "changes-from", "", "only visit the paths reported as modified since the parent snapshot by `provider:arg` ("+strings.Join(archiver.ChangeSourceProviders(), ", ")+")")
 	if runtime.GOOS == "windows" {
 		f.BoolVar(&backupOptions.UseFsSnapshot, "use-fs-snapshot", false, "use filesystem snapshot where possible (currently only Windows VSS)")
 	}
@@ -181,6 +195,20 @@ func filterExisting(items []string) (result []string, err error) {
 	return
 }
 
+// readDescription returns the snapshot description given either directly or
+// via a file. Surrounding whitespace is removed.
+func readDescription(opts BackupOptions) (string, error) {
+	if opts.DescriptionFile == "" {
+		return strings.TrimSpace(opts.Description), nil
+	}
+
+	data, err := textfile.Read(opts.DescriptionFile)
+	if err != nil {
+		return "", errors.Fatalf("unable to read description file: %v", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
 // readLines reads all lines from the named file and returns them as a
 // string slice.
 //
@@ -275,7 +303,7 @@ func readFilenamesRaw(r io.Reader) (names []string, err error) {
 // Check returns an error when an invalid combination of options was set.
 func (opts BackupOptions) Check(gopts GlobalOptions, args []string) error {
 	if gopts.password == "" {
-		if opts.Stdin {
+		if opts.Stdin || opts.StdinFromTar {
 			return errors.Fatal("cannot read both password and data from stdin")
 		}
 
@@ -287,43 +315,67 @@ func (opts BackupOptions) Check(gopts GlobalOptions, args []string) error {
 		}
 	}
 
+	if opts.Description != "" && opts.DescriptionFile != "" {
+		return errors.Fatal("--description and --description-file cannot be used together")
+	}
+
+	var stdinFlags []string
 	if opts.Stdin {
+		stdinFlags = append(stdinFlags, "--stdin")
+	}
+	if opts.StdinFromTar {
+		stdinFlags = append(stdinFlags, "--stdin-from-tar")
+	}
+	if opts.StdinFromCommand {
+		stdinFlags = append(stdinFlags, "--stdin-from-command")
+	}
+	if len(stdinFlags) > 1 {
+		return errors.Fatalf("%s cannot be used together", strings.Join(stdinFlags, " and "))
+	}
+
+	if len(stdinFlags) == 1 {
+		flag := stdinFlags[0]
+
 		if len(opts.FilesFrom) > 0 {
-			return errors.Fatal("--stdin and --files-from cannot be used together")
+			return errors.Fatalf("%s and --files-from cannot be used together", flag)
 		}
 		if len(opts.FilesFromVerbatim) > 0 {
-			return errors.Fatal("--stdin and --files-from-verbatim cannot be used together")
+			return errors.Fatalf("%s and --files-from-verbatim cannot be used together", flag)
 		}
 		if len(opts.FilesFromRaw) > 0 {
-			return errors.Fatal("--stdin and --files-from-raw cannot be used together")
+			return errors.Fatalf("%s and --files-from-raw cannot be used together", flag)
+		}
+		if opts.ChangesFrom != "" {
+			return errors.Fatalf("%s and --changes-from cannot be used together", flag)
 		}
 
-		if len(args) > 0 {
-			return errors.Fatal("--stdin was specified and files/dirs were listed as arguments")
+		if opts.StdinFromCommand {
+			if len(args) == 0 {
+				return errors.Fatal("--stdin-from-command requires the command to run as arguments, e.g. `restic backup --stdin-from-command -- pg_dump mydb`")
+			}
+		} else if len(args) > 0 {
+			return errors.Fatalf("%s was specified and files/dirs were listed as arguments", flag)
 		}
 	}
 
+	if opts.DetectMoves && opts.IgnoreInode {
+		return errors.Fatal("--detect-moves and --ignore-inode cannot be used together")
+	}
+
+	if opts.ChangesFrom != "" && opts.Force {
+		return errors.Fatal("--force and --changes-from cannot be used together")
+	}
+
 	return nil
 }
 
 // collectRejectByNameFuncs returns a list of all functions which may reject data
 // from being saved in a snapshot based on path only
 func collectRejectByNameFuncs(opts BackupOptions, repo *repository.Repository) (fs []RejectByNameFunc, err error) {
-	// exclude restic cache
-	if repo.Cache != nil {
-		f, err := rejectResticCache(repo)
-		if err != nil {
-			return nil, err
-		}
-
-		fs = append(fs, f)
-	}
-
-	fsPatterns, err := opts.excludePatternOptions.CollectPatterns()
+	fs, err = collectRejectByPatternFuncs(opts, repo)
 	if err != nil {
 		return nil, err
 	}
-	fs = append(fs, fsPatterns...)
 
 	if opts.ExcludeCaches {
 		opts.ExcludeIfPresent = append(opts.ExcludeIfPresent, "CACHEDIR.TAG:Signature: 8a477f597d28d172789f06886806bc55")
@@ -341,11 +393,33 @@ func collectRejectByNameFuncs(opts BackupOptions, repo *repository.Repository) (
 	return fs, nil
 }
 
+// collectRejectByPatternFuncs returns the functions from collectRejectByNameFuncs
+// which only match the path and do not access the file system.
+func collectRejectByPatternFuncs(opts BackupOptions, repo *repository.Repository) (fs []RejectByNameFunc, err error) {
+	// exclude restic cache
+	if repo.Cache != nil {
+		f, err := rejectResticCache(repo)
+		if err != nil {
+			return nil, err
+		}
+
+		fs = append(fs, f)
+	}
+
+	fsPatterns, err := opts.excludePatternOptions.CollectPatterns()
+	if err != nil {
+		return nil, err
+	}
+	fs = append(fs, fsPatterns...)
+
+	return fs, nil
+}
+
 // collectRejectFuncs returns a list of all functions which may reject data
 // from being saved in a snapshot based on path and file info
 func collectRejectFuncs(opts BackupOptions, targets []string) (fs []RejectFunc, err error) {
 	// allowed devices
-	if opts.ExcludeOtherFS && !opts.Stdin {
+	if opts.ExcludeOtherFS && !opts.Stdin && !opts.StdinFromTar && !opts.StdinFromCommand {
 		f, err := rejectByDevice(targets)
 		if err != nil {
 			return nil, err
@@ -353,7 +427,7 @@ func collectRejectFuncs(opts BackupOptions, targets []string) (fs []RejectFunc,
 		fs = append(fs, f)
 	}
 
-	if len(opts.ExcludeLargerThan) != 0 && !opts.Stdin {
+	if len(opts.ExcludeLargerThan) != 0 && !opts.Stdin && !opts.StdinFromCommand {
 		f, err := rejectBySize(opts.ExcludeLargerThan)
 		if err != nil {
 			return nil, err
@@ -366,7 +440,7 @@ func collectRejectFuncs(opts BackupOptions, targets []string) (fs []RejectFunc,
 
 // collectTargets returns a list of target files/dirs from several sources.
 func collectTargets(opts BackupOptions, args []string) (targets []string, err error) {
-	if opts.Stdin {
+	if opts.Stdin || opts.StdinFromTar || opts.StdinFromCommand {
 		return nil, nil
 	}
 
@@ -472,7 +546,8 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 		return err
 	}
 
-	timeStamp := time.Now()
+	backupStart := time.Now()
+	timeStamp := backupStart
 	if opts.TimeStamp != "" {
 		timeStamp, err = time.ParseInLocation(TimeFormat, opts.TimeStamp, time.Local)
 		if err != nil {
@@ -480,6 +555,11 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 		}
 	}
 
+	description, err := readDescription(opts)
+	if err != nil {
+		return err
+	}
+
 	if gopts.verbosity >= 2 && !gopts.JSON {
 		Verbosef("open repository\n")
 	}
@@ -525,7 +605,7 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 	}
 
 	var parentSnapshot *restic.Snapshot
-	if !opts.Stdin {
+	if !opts.Stdin && !opts.StdinFromTar && !opts.StdinFromCommand {
 		parentSnapshot, err = findParentSnapshot(ctx, repo, opts, targets, timeStamp)
 		if err != nil {
 			return err
@@ -540,6 +620,18 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 		}
 	}
 
+	var changes archiver.ChangeSource
+	if opts.ChangesFrom != "" {
+		if parentSnapshot == nil {
+			Warnf("no parent snapshot found, ignoring --changes-from\n")
+		} else {
+			changes, err = archiver.NewChangeSource(opts.ChangesFrom)
+			if err != nil {
+				return errors.Fatalf("unable to load changes: %v", err)
+			}
+		}
+	}
+
 	if !gopts.JSON {
 		progressPrinter.V("load index files")
 	}
@@ -586,8 +678,17 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 		defer localVss.DeleteSnapshots()
 		targetFS = localVss
 	}
-	if opts.Stdin {
-		if !gopts.JSON {
+	if opts.Stdin || opts.StdinFromCommand {
+		var source io.ReadCloser = os.Stdin
+		if opts.StdinFromCommand {
+			if !gopts.JSON {
+				progressPrinter.V("read data from command %v", strings.Join(args, " "))
+			}
+			source, err = fs.NewCommandReader(ctx, args, globalOptions.stderr)
+			if err != nil {
+				return err
+			}
+		} else if !gopts.JSON {
 			progressPrinter.V("read data from stdin")
 		}
 		filename := path.Join("/", opts.StdinFilename)
@@ -595,16 +696,31 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 			ModTime:    timeStamp,
 			Name:       filename,
 			Mode:       0644,
-			ReadCloser: os.Stdin,
+			ReadCloser: source,
 		}
 		targets = []string{filename}
 	}
+	if opts.StdinFromTar {
+		if !gopts.JSON {
+			progressPrinter.V("read archive from stdin")
+		}
+		archive, err := fs.OpenArchive(os.Stdin, opts.StdinFilename)
+		if err != nil {
+			return errors.Fatalf("unable to read archive: %v", err)
+		}
+		defer func() {
+			_ = archive.Close()
+		}()
+		targetFS = archive
+		targets = []string{archive.Name}
+	}
 
 	wg, wgCtx := errgroup.WithContext(ctx)
 	cancelCtx, cancel := context.WithCancel(wgCtx)
 	defer cancel()
 
-	if !opts.NoScan {
+	// the scanner would visit all files, which defeats the purpose of --changes-from
+	if !opts.NoScan && changes == nil {
 		sc := archiver.NewScanner(targetFS)
 		sc.SelectByName = selectByNameFilter
 		sc.Select = selectFilter
@@ -621,10 +737,34 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 	arch.SelectByName = selectByNameFilter
 	arch.Select = selectFilter
 	arch.WithAtime = opts.WithAtime
+	arch.Changes = changes
+	if changes != nil {
+		// exclude patterns may have changed since the parent snapshot was
+		// created, thus check them for the items taken from it
+		rejectByPatternFuncs, err := collectRejectByPatternFuncs(opts, repo)
+		if err != nil {
+			return err
+		}
+		arch.SelectUnchanged = func(item string) bool {
+			for _, reject := range rejectByPatternFuncs {
+				if reject(item) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	arch.DetectMoves = opts.DetectMoves
 	success := true
 	arch.Error = func(item string, err error) error {
 		success = false
-		return progressReporter.Error(item, err)
+		reterr := progressReporter.Error(item, err)
+		// abort the backup on fatal errors, e.g. if the command for
+		// --stdin-from-command failed, so that no snapshot is saved
+		if errors.IsFatal(err) {
+			return err
+		}
+		return reterr
 	}
 	arch.CompleteItem = progressReporter.CompleteItem
 	arch.StartFile = progressReporter.StartFile
@@ -642,9 +782,15 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 	snapshotOpts := archiver.SnapshotOptions{
 		Excludes:       opts.Excludes,
 		Tags:           opts.Tags.Flatten(),
+		Labels:         opts.Labels,
+		Description:    description,
 		Time:           timeStamp,
 		Hostname:       opts.Host,
 		ParentSnapshot: parentSnapshot,
+		BackupStart:    backupStart,
+	}
+	if opts.StdinFromCommand {
+		snapshotOpts.Command = args
 	}
 
 	if !gopts.JSON {
@@ -660,6 +806,9 @@ func runBackup(ctx context.Context, opts BackupOptions, gopts GlobalOptions, ter
 
 	// return original error
 	if err != nil {
+		if errors.IsFatal(err) {
+			return err
+		}
 		return errors.Fatalf("unable to save snapshot: %v", err)
 	}
 
@@ -566,3 +566,31 @@ func linkEqual(source, dest []string) bool {
 
 	return true
 }
+
+func TestBackupStdinFromCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires sh")
+	}
+
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testRunInit(t, env.gopts)
+
+	opts := BackupOptions{StdinFromCommand: true, StdinFilename: "dump.sql"}
+	command := []string{"sh", "-c", "echo data"}
+	testRunBackup(t, "", command, opts, env.gopts)
+
+	ids := testListSnapshots(t, env.gopts, 1)
+	repo, err := OpenRepository(context.TODO(), env.gopts)
+	rtest.OK(t, err)
+	sn, err := restic.LoadSnapshot(context.TODO(), repo, ids[0])
+	rtest.OK(t, err)
+	rtest.Equals(t, []string{"/dump.sql"}, sn.Paths)
+	rtest.Equals(t, command, sn.Command)
+
+	// a failing command must not create a snapshot
+	err = testRunBackupAssumeFailure(t, "", []string{"sh", "-c", "echo partial; exit 1"}, opts, env.gopts)
+	rtest.Assert(t, err != nil, "backup of failing command did not fail")
+	testListSnapshots(t, env.gopts, 1)
+}
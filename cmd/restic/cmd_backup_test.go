@@ -110,3 +110,24 @@ func TestReadFilenamesRaw(t *testing.T) {
 	rtest.Assert(t, strings.Contains(err.Error(), "zero byte"),
 		"wrong error message: %v", err.Error())
 }
+
+func TestBackupOptionsCheckChangesFrom(t *testing.T) {
+	gopts := GlobalOptions{password: "secret"}
+
+	for _, test := range []struct {
+		opts BackupOptions
+		args []string
+		err  string
+	}{
+		{BackupOptions{ChangesFrom: "file:changes", Stdin: true}, nil, "--stdin and --changes-from"},
+		{BackupOptions{ChangesFrom: "file:changes", StdinFromTar: true}, nil, "--stdin-from-tar and --changes-from"},
+		{BackupOptions{ChangesFrom: "file:changes", StdinFromCommand: true}, []string{"true"}, "--stdin-from-command and --changes-from"},
+		{BackupOptions{ChangesFrom: "file:changes", Force: true}, []string{"dir"}, "--force and --changes-from"},
+	} {
+		err := test.opts.Check(gopts, test.args)
+		rtest.Assert(t, err != nil, "expected error for %+v", test.opts)
+		rtest.Assert(t, strings.Contains(err.Error(), test.err), "wrong error message: %v", err)
+	}
+
+	rtest.OK(t, BackupOptions{ChangesFrom: "file:changes"}.Check(gopts, []string{"dir"}))
+}
@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"encoding/binary"
+	"encoding/json"
 	"math/rand"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -28,6 +31,14 @@ finds. It can also be used to read all data and therefore simulate a restore.
 By default, the "check" command will always load all data directly from the
 repository and not use a local cache.
 
+If damaged or missing data is found, check lists the files and directories of
+all snapshots which reference this data. These have to be backed up again.
+
+With --json, each problem is printed as a JSON object on a separate line,
+followed by the affected snapshots and a summary. Each object names the kind of
+problem, the affected IDs and, if available, a command which repairs the
+problem.
+
 EXIT STATUS
 ===========
 
@@ -44,10 +55,12 @@ Exit status is 0 if the command was successful, and non-zero if there was any er
 
 // CheckOptions bundles all options for the 'check' command.
 type CheckOptions struct {
-	ReadData       bool
-	ReadDataSubset string
-	CheckUnused    bool
-	WithCache      bool
+	ReadData               bool
+	ReadDataSubset         string
+	ReadDataSinceLastCheck bool
+	ReverifyEvery          uint
+	CheckUnused            bool
+	WithCache              bool
 }
 
 var checkOptions CheckOptions
@@ -58,6 +71,8 @@ func init() {
 	f := cmdCheck.Flags()
 	f.BoolVar(&checkOptions.ReadData, "read-data", false, "read all data blobs")
 	f.StringVar(&checkOptions.ReadDataSubset, "read-data-subset", "", "read a `subset` of data packs, specified as 'n/t' for specific part, or either 'x%' or 'x.y%' or a size in bytes with suffixes k/K, m/M, g/G, t/T for a random subset")
+	f.BoolVar(&checkOptions.ReadDataSinceLastCheck, "read-data-since-last-check", false, "only read data packs which were not yet verified by an earlier run of this option (requires repository version 3)")
+	f.UintVar(&checkOptions.ReverifyEvery, "reverify-every", 0, "with --read-data-since-last-check, also read already verified packs such that each pack is read at least once every `n` runs")
 	var ignored bool
 	f.BoolVar(&ignored, "check-unused", false, "find unused blobs")
 	err := f.MarkDeprecated("check-unused", "`--check-unused` is deprecated and will be ignored")
@@ -72,6 +87,12 @@ func checkFlags(opts CheckOptions) error {
 	if opts.ReadData && opts.ReadDataSubset != "" {
 		return errors.Fatal("check flags --read-data and --read-data-subset cannot be used together")
 	}
+	if opts.ReadDataSinceLastCheck && (opts.ReadData || opts.ReadDataSubset != "") {
+		return errors.Fatal("check flag --read-data-since-last-check cannot be used together with --read-data or --read-data-subset")
+	}
+	if opts.ReverifyEvery > 0 && !opts.ReadDataSinceLastCheck {
+		return errors.Fatal("check flag --reverify-every requires --read-data-since-last-check")
+	}
 	if opts.ReadDataSubset != "" {
 		dataSubset, err := stringToIntSlice(opts.ReadDataSubset)
 		argumentError := errors.Fatal("check flag --read-data-subset has invalid value, please see documentation")
@@ -180,7 +201,9 @@ func prepareCheckCache(opts CheckOptions, gopts *GlobalOptions) (cleanup func())
 	}
 
 	gopts.CacheDir = tempdir
-	Verbosef("using temporary cache in %v\n", tempdir)
+	if !gopts.JSON {
+		Verbosef("using temporary cache in %v\n", tempdir)
+	}
 
 	cleanup = func() {
 		err := fs.RemoveAll(tempdir)
@@ -196,6 +219,10 @@ func runCheck(ctx context.Context, opts CheckOptions, gopts GlobalOptions, args
 	if len(args) != 0 {
 		return errors.Fatal("the check command expects no arguments, only options - please see `restic help check` for usage and flags")
 	}
+	if opts.ReadDataSinceLastCheck && gopts.NoLock {
+		// updating the check records requires that no other check runs concurrently
+		return errors.Fatal("check flag --read-data-since-last-check cannot be used together with --no-lock")
+	}
 
 	cleanup := prepareCheckCache(opts, &gopts)
 	AddCleanupHandler(func(code int) (int, error) {
@@ -208,8 +235,16 @@ func runCheck(ctx context.Context, opts CheckOptions, gopts GlobalOptions, args
 		return err
 	}
 
+	if opts.ReadDataSinceLastCheck {
+		if err := repo.Config().Supports(restic.CheckFile); err != nil {
+			return err
+		}
+	}
+
+	printer := newCheckPrinter(gopts.JSON)
+
 	if !gopts.NoLock {
-		Verbosef("create exclusive lock for repository\n")
+		printer.V("create exclusive lock for repository\n")
 		var lock *restic.Lock
 		lock, ctx, err = lockRepoExclusive(ctx, repo, gopts.RetryLock, gopts.JSON)
 		defer unlockRepo(lock)
@@ -219,12 +254,13 @@ func runCheck(ctx context.Context, opts CheckOptions, gopts GlobalOptions, args
 	}
 
 	chkr := checker.New(repo, opts.CheckUnused)
+	printer.chkr = chkr
 	err = chkr.LoadSnapshots(ctx)
 	if err != nil {
 		return err
 	}
 
-	Verbosef("load indexes\n")
+	printer.V("load indexes\n")
 	hints, errs := chkr.LoadIndex(ctx)
 
 	errorsFound := false
@@ -233,61 +269,65 @@ func runCheck(ctx context.Context, opts CheckOptions, gopts GlobalOptions, args
 	for _, hint := range hints {
 		switch hint.(type) {
 		case *checker.ErrDuplicatePacks, *checker.ErrOldIndexFormat:
-			Printf("%v\n", hint)
+			printer.P("%v\n", hint)
 			suggestIndexRebuild = true
 		case *checker.ErrMixedPack:
-			Printf("%v\n", hint)
+			printer.P("%v\n", hint)
 			mixedFound = true
 		default:
-			Warnf("error: %v\n", hint)
+			printer.E("error: %v\n", hint)
 			errorsFound = true
 		}
+		printer.Report(hint)
 	}
 
 	if suggestIndexRebuild {
-		Printf("Duplicate packs/old indexes are non-critical, you can run `restic repair index' to correct this.\n")
+		printer.P("Duplicate packs/old indexes are non-critical, you can run `restic repair index' to correct this.\n")
 	}
 	if mixedFound {
-		Printf("Mixed packs with tree and data blobs are non-critical, you can run `restic prune` to correct this.\n")
+		printer.P("Mixed packs with tree and data blobs are non-critical, you can run `restic prune` to correct this.\n")
 	}
 
 	if len(errs) > 0 {
 		for _, err := range errs {
-			Warnf("error: %v\n", err)
+			printer.E("error: %v\n", err)
+			printer.report(checkIssue{Kind: "index_error", Severity: "error", Message: err.Error(), Repair: "restic repair index"})
 		}
+		printer.Finish()
 		return errors.Fatal("LoadIndex returned errors")
 	}
 
 	orphanedPacks := 0
 	errChan := make(chan error)
 
-	Verbosef("check all packs\n")
+	printer.V("check all packs\n")
 	go chkr.Packs(ctx, errChan)
 
 	for err := range errChan {
 		if checker.IsOrphanedPack(err) {
 			orphanedPacks++
-			Verbosef("%v\n", err)
+			printer.V("%v\n", err)
 		} else if err == checker.ErrLegacyLayout {
-			Verbosef("repository still uses the S3 legacy layout\nPlease run `restic migrate s3legacy` to correct this.\n")
+			printer.V("repository still uses the S3 legacy layout\nPlease run `restic migrate s3legacy` to correct this.\n")
 		} else {
 			errorsFound = true
-			Warnf("%v\n", err)
+			printer.E("%v\n", err)
 		}
+		printer.Report(err)
 	}
 
 	if orphanedPacks > 0 {
-		Verbosef("%d additional files were found in the repo, which likely contain duplicate data.\nThis is non-critical, you can run `restic prune` to correct this.\n", orphanedPacks)
+		printer.V("%d additional files were found in the repo, which likely contain duplicate data.\nThis is non-critical, you can run `restic prune` to correct this.\n", orphanedPacks)
 	}
 
-	Verbosef("check snapshots, trees and blobs\n")
+	printer.V("check snapshots, trees and blobs\n")
 	errChan = make(chan error)
 	var wg sync.WaitGroup
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		bar := newProgressMax(!gopts.Quiet, 0, "snapshots")
+		bar := newProgressMax(!gopts.Quiet && !gopts.JSON, 0, "snapshots")
 		defer bar.Done()
 		chkr.Structure(ctx, bar, errChan)
 	}()
@@ -299,13 +339,14 @@ func runCheck(ctx context.Context, opts CheckOptions, gopts GlobalOptions, args
 			if stdoutCanUpdateStatus() {
 				clean = clearLine(0)
 			}
-			Warnf(clean+"error for tree %v:\n", e.ID.Str())
+			printer.E(clean+"error for tree %v:\n", e.ID.Str())
 			for _, treeErr := range e.Errors {
-				Warnf("  %v\n", treeErr)
+				printer.E("  %v\n", treeErr)
 			}
 		} else {
-			Warnf("error: %v\n", err)
+			printer.E("error: %v\n", err)
 		}
+		printer.Report(err)
 	}
 
 	// Wait for the progress bar to be complete before printing more below.
@@ -314,8 +355,10 @@ func runCheck(ctx context.Context, opts CheckOptions, gopts GlobalOptions, args
 	wg.Wait()
 
 	if opts.CheckUnused {
-		for _, id := range chkr.UnusedBlobs(ctx) {
-			Verbosef("unused blob %v\n", id)
+		for _, h := range chkr.UnusedBlobs(ctx) {
+			printer.V("unused blob %v\n", h)
+			id := h.ID
+			printer.report(checkIssue{Kind: "blob_unused", Severity: "error", BlobID: &id, Message: "unused blob " + h.ID.String(), Repair: "restic prune"})
 			errorsFound = true
 		}
 	}
@@ -323,21 +366,22 @@ func runCheck(ctx context.Context, opts CheckOptions, gopts GlobalOptions, args
 	doReadData := func(packs map[restic.ID]int64) {
 		packCount := uint64(len(packs))
 
-		p := newProgressMax(!gopts.Quiet, packCount, "packs")
+		p := newProgressMax(!gopts.Quiet && !gopts.JSON, packCount, "packs")
 		errChan := make(chan error)
 
 		go chkr.ReadPacks(ctx, packs, p, errChan)
 
 		for err := range errChan {
 			errorsFound = true
-			Warnf("%v\n", err)
+			printer.E("%v\n", err)
+			printer.Report(err)
 		}
 		p.Done()
 	}
 
 	switch {
 	case opts.ReadData:
-		Verbosef("read all data\n")
+		printer.V("read all data\n")
 		doReadData(selectPacksByBucket(chkr.GetPacks(), 1, 1))
 	case opts.ReadDataSubset != "":
 		var packs map[restic.ID]int64
@@ -347,12 +391,12 @@ func runCheck(ctx context.Context, opts CheckOptions, gopts GlobalOptions, args
 			totalBuckets := dataSubset[1]
 			packs = selectPacksByBucket(chkr.GetPacks(), bucket, totalBuckets)
 			packCount := uint64(len(packs))
-			Verbosef("read group #%d of %d data packs (out of total %d packs in %d groups)\n", bucket, packCount, chkr.CountPacks(), totalBuckets)
+			printer.V("read group #%d of %d data packs (out of total %d packs in %d groups)\n", bucket, packCount, chkr.CountPacks(), totalBuckets)
 		} else if strings.HasSuffix(opts.ReadDataSubset, "%") {
 			percentage, err := parsePercentage(opts.ReadDataSubset)
 			if err == nil {
 				packs = selectRandomPacksByPercentage(chkr.GetPacks(), percentage)
-				Verbosef("read %.1f%% of data packs\n", percentage)
+				printer.V("read %.1f%% of data packs\n", percentage)
 			}
 		} else {
 			repoSize := int64(0)
@@ -368,23 +412,313 @@ func runCheck(ctx context.Context, opts CheckOptions, gopts GlobalOptions, args
 				subsetSize = repoSize
 			}
 			packs = selectRandomPacksByFileSize(chkr.GetPacks(), subsetSize, repoSize)
-			Verbosef("read %d bytes of data packs\n", subsetSize)
+			printer.V("read %d bytes of data packs\n", subsetSize)
 		}
 		if packs == nil {
 			return errors.Fatal("internal error: failed to select packs to check")
 		}
 		doReadData(packs)
+	case opts.ReadDataSinceLastCheck:
+		records, runs, verified, err := restic.LoadCheckRecords(ctx, repo.Backend(), repo)
+		if err != nil {
+			return errors.Fatalf("failed to load check records: %v", err)
+		}
+		allPacks := chkr.GetPacks()
+		packs := selectUnverifiedPacks(allPacks, verified, runs, opts.ReverifyEvery)
+		printer.V("read %d of %d data packs which were not verified yet or are due for verification\n", len(packs), len(allPacks))
+		doReadData(packs)
+
+		updateVerifiedPacks(verified, allPacks, packs, chkr.VerifiedPacks(), time.Now())
+		_, err = restic.SaveCheckRecord(ctx, repo, restic.NewCheckRecord(runs+1, verified))
+		if err != nil {
+			return errors.Fatalf("failed to save check record: %v", err)
+		}
+		DeleteFiles(ctx, gopts, repo, records, restic.CheckFile)
+	}
+
+	if damaged := chkr.DamagedBlobs(); len(damaged) > 0 {
+		printer.DamagedBlobs(damaged)
+		printer.V("find snapshots affected by %d damaged blobs\n", len(damaged))
+		affected, err := chkr.AnalyzeDamage(ctx, damaged)
+		if err != nil {
+			printer.E("unable to determine the affected snapshots: %v\n", err)
+		} else {
+			printer.Damage(affected)
+		}
 	}
 
+	printer.Finish()
+
 	if errorsFound {
 		return errors.Fatal("repository contains errors")
 	}
 
-	Verbosef("no errors were found\n")
+	printer.V("no errors were found\n")
 
 	return nil
 }
 
+// checkIssue describes a single problem found by check.
+type checkIssue struct {
+	MessageType string     `json:"message_type"` // "error" or "warning", same as Severity
+	Kind        string     `json:"kind"`
+	Severity    string     `json:"severity"` // "error" or "warning"
+	PackID      *restic.ID `json:"pack_id,omitempty"`
+	TreeID      *restic.ID `json:"tree_id,omitempty"`
+	BlobID      *restic.ID `json:"blob_id,omitempty"`
+	IndexIDs    restic.IDs `json:"index_ids,omitempty"`
+	Snapshots   restic.IDs `json:"snapshots,omitempty"`
+	Message     string     `json:"message"`
+	Repair      string     `json:"repair,omitempty"`
+}
+
+// checkDamage lists the files and directories of a snapshot which reference
+// damaged data.
+type checkDamage struct {
+	MessageType string    `json:"message_type"` // "damage"
+	Snapshot    restic.ID `json:"snapshot"`
+	Time        time.Time `json:"time"`
+	Hostname    string    `json:"hostname"`
+	Paths       []string  `json:"paths"`
+}
+
+// checkSummary is printed after all problems when JSON output is requested.
+type checkSummary struct {
+	MessageType       string     `json:"message_type"` // "summary"
+	NumErrors         uint       `json:"num_errors"`
+	NumWarnings       uint       `json:"num_warnings"`
+	SuggestedRepairs  []string   `json:"suggested_repairs"`
+	AffectedSnapshots restic.IDs `json:"affected_snapshots"`
+	DamagedBlobs      restic.IDs `json:"damaged_blobs"` // can be passed to "restic repair blobs"
+}
+
+// checkRepairOrder lists the repair commands in the order in which they must
+// be run, see the troubleshooting section of the documentation.
+var checkRepairOrder = []string{
+	"restic migrate",
+	"restic repair index",
+	"restic repair blobs",
+	"restic forget",
+	"restic repair snapshots",
+	"restic prune",
+}
+
+func checkRepairRank(repair string) int {
+	for i, prefix := range checkRepairOrder {
+		if strings.HasPrefix(repair, prefix) {
+			return i
+		}
+	}
+	return len(checkRepairOrder)
+}
+
+// checkIssues converts an error returned by the checker into one issue per
+// problem.
+func checkIssues(chkr *checker.Checker, err error) []checkIssue {
+	issue := checkIssue{Kind: "error", Severity: "error", Message: err.Error()}
+
+	switch e := err.(type) {
+	case *checker.ErrDuplicatePacks:
+		issue.Kind, issue.Severity, issue.Repair = "duplicate_pack", "warning", "restic repair index"
+		issue.PackID = &e.PackID
+		issue.IndexIDs = e.Indexes.List()
+	case *checker.ErrOldIndexFormat:
+		issue.Kind, issue.Severity, issue.Repair = "old_index_format", "warning", "restic repair index"
+		issue.IndexIDs = restic.IDs{e.ID}
+	case *checker.ErrMixedPack:
+		issue.Kind, issue.Severity, issue.Repair = "mixed_pack", "warning", "restic prune"
+		issue.PackID = &e.PackID
+	case *checker.PackError:
+		issue.PackID = &e.ID
+		switch {
+		case e.Orphaned:
+			issue.Kind, issue.Severity, issue.Repair = "pack_orphaned", "warning", "restic prune"
+		case e.Truncated:
+			issue.Kind, issue.Repair = "pack_size_mismatch", "restic repair index"
+		default:
+			issue.Kind, issue.Repair = "pack_missing", "restic repair index"
+		}
+	case *checker.ErrPackData:
+		issue.Kind, issue.Repair = "pack_damaged", "restic repair blobs"
+		issue.PackID = &e.PackID
+	case *checker.SnapshotError:
+		issue.Kind, issue.Repair = "snapshot_unreadable", "restic forget "+e.ID.String()
+		issue.Snapshots = restic.IDs{e.ID}
+	case *checker.TreeError:
+		issues := make([]checkIssue, 0, len(e.Errors))
+		for _, treeErr := range e.Errors {
+			issue := checkIssue{
+				Kind:      "tree_unreadable",
+				Severity:  "error",
+				TreeID:    &e.ID,
+				Snapshots: chkr.SnapshotsForTree(e.ID),
+				Message:   treeErr.Error(),
+				Repair:    "restic repair snapshots --forget",
+			}
+			if te, ok := treeErr.(*checker.Error); ok {
+				issue.Kind = "tree_invalid"
+				if !te.BlobID.IsNull() {
+					issue.Kind = "blob_missing"
+					issue.BlobID = &te.BlobID
+				}
+			}
+			issues = append(issues, issue)
+		}
+		return issues
+	default:
+		if err == checker.ErrLegacyLayout {
+			issue.Kind, issue.Severity, issue.Repair = "legacy_layout", "warning", "restic migrate s3legacy"
+		}
+	}
+
+	return []checkIssue{issue}
+}
+
+// checkPrinter prints the messages of the check command either as text or, if
+// JSON output is requested, prints the problems found as one JSON object per
+// line followed by a summary.
+type checkPrinter struct {
+	json    bool
+	enc     *json.Encoder
+	chkr    *checker.Checker
+	summary checkSummary
+}
+
+func newCheckPrinter(jsonOutput bool) *checkPrinter {
+	return &checkPrinter{
+		json:    jsonOutput,
+		enc:     json.NewEncoder(globalOptions.stdout),
+		summary: checkSummary{MessageType: "summary", SuggestedRepairs: []string{}, AffectedSnapshots: restic.IDs{}, DamagedBlobs: restic.IDs{}},
+	}
+}
+
+// P prints a message unless JSON output is requested.
+func (p *checkPrinter) P(msg string, args ...interface{}) {
+	if !p.json {
+		Printf(msg, args...)
+	}
+}
+
+// V prints a verbose message unless JSON output is requested.
+func (p *checkPrinter) V(msg string, args ...interface{}) {
+	if !p.json {
+		Verbosef(msg, args...)
+	}
+}
+
+// E prints an error message unless JSON output is requested.
+func (p *checkPrinter) E(msg string, args ...interface{}) {
+	if !p.json {
+		Warnf(msg, args...)
+	}
+}
+
+// Report prints the problems described by err if JSON output is requested.
+func (p *checkPrinter) Report(err error) {
+	if !p.json {
+		return
+	}
+	for _, issue := range checkIssues(p.chkr, err) {
+		p.report(issue)
+	}
+}
+
+func (p *checkPrinter) report(issue checkIssue) {
+	if !p.json {
+		return
+	}
+
+	// non-critical problems must not be mistaken for errors by monitoring tools
+	issue.MessageType = issue.Severity
+	if issue.Severity == "warning" {
+		p.summary.NumWarnings++
+	} else {
+		p.summary.NumErrors++
+	}
+
+	if issue.Repair != "" {
+		found := false
+		for _, repair := range p.summary.SuggestedRepairs {
+			if repair == issue.Repair {
+				found = true
+				break
+			}
+		}
+		if !found {
+			p.summary.SuggestedRepairs = append(p.summary.SuggestedRepairs, issue.Repair)
+		}
+	}
+
+	p.encode(issue)
+}
+
+// DamagedBlobs lists the IDs of the damaged blobs in the summary, or prints
+// them if the verbosity is at least 2.
+func (p *checkPrinter) DamagedBlobs(damaged restic.BlobSet) {
+	ids := restic.NewIDSet()
+	for h := range damaged {
+		ids.Insert(h.ID)
+	}
+	if p.json {
+		p.summary.DamagedBlobs = ids.List()
+		return
+	}
+
+	Verboseff("damaged blobs, which can be restored using `restic repair blobs`:\n")
+	for _, id := range ids.List() {
+		Verboseff("  %v\n", id)
+	}
+}
+
+// Damage prints the files and directories of the affected snapshots.
+func (p *checkPrinter) Damage(affected []checker.AffectedSnapshot) {
+	if !p.json {
+		if len(affected) == 0 {
+			Printf("the damaged data is not referenced by any snapshot\n")
+			return
+		}
+		Printf("the damaged data affects %d snapshots:\n", len(affected))
+		for _, a := range affected {
+			Printf("\nsnapshot %s of %v at %s:\n", a.Snapshot.ID().Str(), a.Snapshot.Paths, a.Snapshot.Time)
+			for _, path := range a.Paths {
+				Printf("  %s\n", path)
+			}
+		}
+		Printf("\n")
+		return
+	}
+
+	for _, a := range affected {
+		p.summary.AffectedSnapshots = append(p.summary.AffectedSnapshots, *a.Snapshot.ID())
+		p.encode(checkDamage{
+			MessageType: "damage",
+			Snapshot:    *a.Snapshot.ID(),
+			Time:        a.Snapshot.Time,
+			Hostname:    a.Snapshot.Hostname,
+			Paths:       a.Paths,
+		})
+	}
+}
+
+// Finish prints the summary if JSON output is requested.
+func (p *checkPrinter) Finish() {
+	if !p.json {
+		return
+	}
+
+	sort.SliceStable(p.summary.SuggestedRepairs, func(i, j int) bool {
+		return checkRepairRank(p.summary.SuggestedRepairs[i]) < checkRepairRank(p.summary.SuggestedRepairs[j])
+	})
+	p.encode(p.summary)
+}
+
+func (p *checkPrinter) encode(v interface{}) {
+	err := p.enc.Encode(v)
+	if err != nil {
+		Warnf("JSON encode failed: %v\n", err)
+	}
+}
+
 // selectPacksByBucket selects subsets of packs by ranges of buckets.
 func selectPacksByBucket(allPacks map[restic.ID]int64, bucket, totalBuckets uint) map[restic.ID]int64 {
 	packs := make(map[restic.ID]int64)
@@ -398,6 +732,40 @@ func selectPacksByBucket(allPacks map[restic.ID]int64, bucket, totalBuckets uint
 	return packs
 }
 
+// selectUnverifiedPacks selects the packs which are not contained in verified.
+// If reverifyEvery is set, verified packs are split into reverifyEvery groups
+// and the group for the given number of runs is selected as well, such that
+// each pack is selected at least once in reverifyEvery runs.
+func selectUnverifiedPacks(allPacks map[restic.ID]int64, verified map[restic.ID]time.Time, runs uint64, reverifyEvery uint) map[restic.ID]int64 {
+	packs := make(map[restic.ID]int64)
+	for pack, size := range allPacks {
+		_, ok := verified[pack]
+		if !ok || (reverifyEvery > 0 && binary.LittleEndian.Uint64(pack[:8])%uint64(reverifyEvery) == runs%uint64(reverifyEvery)) {
+			packs[pack] = size
+		}
+	}
+	return packs
+}
+
+// updateVerifiedPacks records the time at which the packs in ok were read
+// without errors. Packs which were read but are not contained in ok are
+// damaged and must be read again by the next check, packs which no longer
+// exist are forgotten.
+func updateVerifiedPacks(verified map[restic.ID]time.Time, allPacks map[restic.ID]int64, read map[restic.ID]int64, ok restic.IDSet, now time.Time) {
+	for id := range read {
+		if ok.Has(id) {
+			verified[id] = now
+		} else {
+			delete(verified, id)
+		}
+	}
+	for id := range verified {
+		if _, ok := allPacks[id]; !ok {
+			delete(verified, id)
+		}
+	}
+}
+
 // selectRandomPacksByPercentage selects the given percentage of packs which are randomly choosen.
 func selectRandomPacksByPercentage(allPacks map[restic.ID]int64, percentage float64) map[restic.ID]int64 {
 	packCount := len(allPacks)
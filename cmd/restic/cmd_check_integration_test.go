@@ -2,8 +2,15 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/restic/restic/internal/repository"
+	"github.com/restic/restic/internal/restic"
 	rtest "github.com/restic/restic/internal/test"
 )
 
@@ -32,3 +39,181 @@ func testRunCheckOutput(gopts GlobalOptions, checkUnused bool) (string, error) {
 	})
 	return buf.String(), err
 }
+
+func TestCheckReadDataSinceLastCheck(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	opts := BackupOptions{}
+	testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9", "2")}, opts, env.gopts)
+
+	checkOpts := CheckOptions{ReadDataSinceLastCheck: true}
+	loadRecords := func() (uint64, map[restic.ID]time.Time) {
+		repo, err := OpenRepository(context.TODO(), env.gopts)
+		rtest.OK(t, err)
+		_, runs, verified, err := restic.LoadCheckRecords(context.TODO(), repo.Backend(), repo)
+		rtest.OK(t, err)
+		return runs, verified
+	}
+
+	// check records are only written if requested
+	rtest.OK(t, runCheck(context.TODO(), CheckOptions{ReadData: true}, env.gopts, nil))
+	rtest.Equals(t, 0, len(testRunList(t, "checks", env.gopts)))
+
+	// the records are not updated without the exclusive lock
+	noLockOpts := env.gopts
+	noLockOpts.NoLock = true
+	rtest.Assert(t, runCheck(context.TODO(), checkOpts, noLockOpts, nil) != nil, "check without lock succeeded")
+	rtest.Equals(t, 0, len(testRunList(t, "checks", env.gopts)))
+
+	rtest.OK(t, runCheck(context.TODO(), checkOpts, env.gopts, nil))
+	runs, verified := loadRecords()
+	rtest.Equals(t, uint64(1), runs)
+	rtest.Equals(t, listPacks(env.gopts, t), restic.NewIDSet(idsFromTimes(verified)...))
+
+	// only the packs of the new backup are read, the others keep their time
+	testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9", "3")}, opts, env.gopts)
+	rtest.OK(t, runCheck(context.TODO(), checkOpts, env.gopts, nil))
+	runs, verifiedAfter := loadRecords()
+	rtest.Equals(t, uint64(2), runs)
+	rtest.Equals(t, listPacks(env.gopts, t), restic.NewIDSet(idsFromTimes(verifiedAfter)...))
+	for id, ts := range verified {
+		rtest.Assert(t, verifiedAfter[id].Equal(ts), "pack %v was verified again", id.Str())
+	}
+	rtest.Equals(t, 1, len(testRunList(t, "checks", env.gopts)))
+
+	// reverifying every run reads all packs again
+	checkOpts.ReverifyEvery = 1
+	rtest.OK(t, runCheck(context.TODO(), checkOpts, env.gopts, nil))
+	_, verified = loadRecords()
+	for id, ts := range verifiedAfter {
+		rtest.Assert(t, verified[id].After(ts), "pack %v was not verified again", id.Str())
+	}
+
+	// a pack which is damaged when reading it again is no longer verified
+	damaged := idsFromTimes(verified)[0]
+	name := filepath.Join(env.repo, "data", damaged.String()[:2], damaged.String())
+	buf, err := os.ReadFile(name)
+	rtest.OK(t, err)
+	buf[len(buf)/2] ^= 0xff
+	rtest.OK(t, os.Chmod(name, 0644))
+	rtest.OK(t, os.WriteFile(name, buf, 0644))
+
+	rtest.Assert(t, runCheck(context.TODO(), checkOpts, env.gopts, nil) != nil, "expected error for damaged pack")
+	_, verifiedAfter = loadRecords()
+	_, ok := verifiedAfter[damaged]
+	rtest.Assert(t, !ok, "damaged pack %v is still verified", damaged.Str())
+	rtest.Equals(t, len(verified)-1, len(verifiedAfter))
+}
+
+func TestCheckReadDataSinceLastCheckRepoVersion(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	repository.TestUseLowSecurityKDFParameters(t)
+	restic.TestDisableCheckPolynomial(t)
+	restic.TestSetLockTimeout(t, 0)
+	rtest.OK(t, runInit(context.TODO(), InitOptions{RepositoryVersion: "2"}, env.gopts, nil))
+	err := runCheck(context.TODO(), CheckOptions{ReadDataSinceLastCheck: true}, env.gopts, nil)
+	rtest.Assert(t, err != nil, "check with --read-data-since-last-check succeeded for repository version 2")
+}
+
+func idsFromTimes(m map[restic.ID]time.Time) restic.IDs {
+	var ids restic.IDs
+	for id := range m {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestCheckJSON(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9", "2")}, BackupOptions{}, env.gopts)
+
+	var missing restic.ID
+	for id := range listPacks(env.gopts, t) {
+		missing = id
+		break
+	}
+	removePacks(env.gopts, t, restic.NewIDSet(missing))
+
+	gopts := env.gopts
+	gopts.JSON = true
+	buf, err := withCaptureStdout(func() error {
+		return runCheck(context.TODO(), CheckOptions{}, gopts, nil)
+	})
+	rtest.Assert(t, err != nil, "expected error for damaged repository")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	foundMissing := false
+	numIssues := 0
+	for _, line := range lines[:len(lines)-1] {
+		var issue checkIssue
+		rtest.OK(t, json.Unmarshal([]byte(line), &issue))
+		if issue.MessageType == "damage" {
+			continue
+		}
+		rtest.Equals(t, issue.Severity, issue.MessageType)
+		numIssues++
+		if issue.Kind == "pack_missing" {
+			foundMissing = true
+			rtest.Equals(t, missing, *issue.PackID)
+			rtest.Equals(t, "error", issue.Severity)
+		}
+	}
+	rtest.Assert(t, foundMissing, "missing pack not reported in %v", lines)
+
+	var summary checkSummary
+	rtest.OK(t, json.Unmarshal([]byte(lines[len(lines)-1]), &summary))
+	rtest.Equals(t, "summary", summary.MessageType)
+	rtest.Equals(t, uint(numIssues), summary.NumErrors+summary.NumWarnings)
+	rtest.Equals(t, "restic repair index", summary.SuggestedRepairs[0])
+}
+
+func TestCheckDamage(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9", "2")}, BackupOptions{}, env.gopts)
+	snapshotIDs := testListSnapshots(t, env.gopts, 1)
+	removePacksExcept(env.gopts, t, restic.NewIDSet(), false)
+
+	buf, err := withCaptureStdout(func() error {
+		return runCheck(context.TODO(), CheckOptions{}, env.gopts, nil)
+	})
+	rtest.Assert(t, err != nil, "expected error for damaged repository")
+	rtest.Assert(t, strings.Contains(buf.String(), "the damaged data affects 1 snapshots"), "affected snapshots missing in output:\n%v", buf.String())
+
+	gopts := env.gopts
+	gopts.JSON = true
+	buf, err = withCaptureStdout(func() error {
+		return runCheck(context.TODO(), CheckOptions{}, gopts, nil)
+	})
+	rtest.Assert(t, err != nil, "expected error for damaged repository")
+
+	var damage []checkDamage
+	var summary checkSummary
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var msg checkDamage
+		rtest.OK(t, json.Unmarshal([]byte(line), &msg))
+		switch msg.MessageType {
+		case "damage":
+			damage = append(damage, msg)
+		case "summary":
+			rtest.OK(t, json.Unmarshal([]byte(line), &summary))
+		}
+	}
+
+	rtest.Equals(t, 1, len(damage))
+	rtest.Equals(t, snapshotIDs[0], damage[0].Snapshot)
+	rtest.Assert(t, len(damage[0].Paths) > 0, "no affected paths reported")
+	for _, path := range damage[0].Paths {
+		rtest.Assert(t, strings.Contains(path, filepath.ToSlash(filepath.Join("0", "0", "9", "2"))), "unexpected path %v", path)
+	}
+	rtest.Equals(t, snapshotIDs, summary.AffectedSnapshots)
+}
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
 	"math"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/restic/restic/internal/checker"
+	"github.com/restic/restic/internal/errors"
 	"github.com/restic/restic/internal/restic"
 	rtest "github.com/restic/restic/internal/test"
 )
@@ -92,6 +98,65 @@ func TestSelectPacksByBucket(t *testing.T) {
 	}
 }
 
+func TestSelectUnverifiedPacks(t *testing.T) {
+	var testPacks = make(map[restic.ID]int64)
+	verified := make(map[restic.ID]time.Time)
+	for i := 0; i < 10; i++ {
+		id := restic.NewRandomID()
+		// ensure relevant part of generated id is reproducable
+		binary.LittleEndian.PutUint64(id[:8], uint64(i))
+		testPacks[id] = 0
+		if i < 8 {
+			verified[id] = time.Now()
+		}
+	}
+
+	selectedPacks := selectUnverifiedPacks(testPacks, verified, 0, 0)
+	rtest.Assert(t, len(selectedPacks) == 2, "Expected 2 selected packs, got %v", len(selectedPacks))
+	for id := range selectedPacks {
+		_, ok := verified[id]
+		rtest.Assert(t, !ok, "Expected only unverified packs")
+	}
+
+	// each verified pack is selected once every 4 runs
+	seen := restic.NewIDSet()
+	for runs := uint64(0); runs < 4; runs++ {
+		selectedPacks = selectUnverifiedPacks(testPacks, verified, runs+4, 4)
+		rtest.Assert(t, len(selectedPacks) == 4, "Expected 4 selected packs, got %v", len(selectedPacks))
+		for id := range selectedPacks {
+			if _, ok := verified[id]; ok {
+				rtest.Assert(t, !seen.Has(id), "pack %v selected twice", id.Str())
+				seen.Insert(id)
+			}
+		}
+	}
+	rtest.Equals(t, len(verified), len(seen))
+}
+
+func TestUpdateVerifiedPacks(t *testing.T) {
+	before := time.Now().Add(-time.Hour)
+	now := time.Now()
+
+	allPacks := make(map[restic.ID]int64)
+	verified := make(map[restic.ID]time.Time)
+	var ids restic.IDs
+	for i := 0; i < 4; i++ {
+		id := restic.NewRandomID()
+		ids = append(ids, id)
+		allPacks[id] = 0
+		verified[id] = before
+	}
+	// a pack which no longer exists
+	removed := restic.NewRandomID()
+	verified[removed] = before
+
+	// the first two packs are read again, but only the first one is intact
+	read := map[restic.ID]int64{ids[0]: 0, ids[1]: 0}
+	updateVerifiedPacks(verified, allPacks, read, restic.NewIDSet(ids[0]), now)
+
+	rtest.Equals(t, map[restic.ID]time.Time{ids[0]: now, ids[2]: before, ids[3]: before}, verified)
+}
+
 func TestSelectRandomPacksByPercentage(t *testing.T) {
 	var testPacks = make(map[restic.ID]int64)
 	for i := 1; i <= 10; i++ {
@@ -163,3 +228,46 @@ func TestSelectNoRandomPacksByFileSize(t *testing.T) {
 	selectedPacks := selectRandomPacksByFileSize(testPacks, 10, 500)
 	rtest.Assert(t, len(selectedPacks) == 0, "Expected 0 selected packs")
 }
+
+func TestCheckIssues(t *testing.T) {
+	packID, treeID, blobID := restic.NewRandomID(), restic.NewRandomID(), restic.NewRandomID()
+
+	issues := checkIssues(checker.New(nil, false), &checker.PackError{ID: packID, Truncated: true, Err: errors.New("unexpected file size")})
+	rtest.Equals(t, 1, len(issues))
+	rtest.Equals(t, "pack_size_mismatch", issues[0].Kind)
+	rtest.Equals(t, packID, *issues[0].PackID)
+
+	// each error of a tree is reported separately
+	issues = checkIssues(checker.New(nil, false), &checker.TreeError{ID: treeID, Errors: []error{
+		&checker.Error{TreeID: treeID, BlobID: blobID, Err: errors.New("blob not found in index")},
+		&checker.Error{TreeID: treeID, Err: errors.New("node with empty name")},
+	}})
+	rtest.Equals(t, 2, len(issues))
+	rtest.Equals(t, "blob_missing", issues[0].Kind)
+	rtest.Equals(t, blobID, *issues[0].BlobID)
+	rtest.Equals(t, "tree_invalid", issues[1].Kind)
+	rtest.Assert(t, issues[1].BlobID == nil, "unexpected blob ID %v", issues[1].BlobID)
+	for _, issue := range issues {
+		rtest.Equals(t, treeID, *issue.TreeID)
+		rtest.Equals(t, "restic repair snapshots --forget", issue.Repair)
+	}
+
+	issues = checkIssues(checker.New(nil, false), checker.ErrLegacyLayout)
+	rtest.Equals(t, "legacy_layout", issues[0].Kind)
+	rtest.Equals(t, "warning", issues[0].Severity)
+}
+
+func TestCheckPrinterWarning(t *testing.T) {
+	buf := &bytes.Buffer{}
+	p := newCheckPrinter(true)
+	p.enc = json.NewEncoder(buf)
+	p.chkr = checker.New(nil, false)
+
+	p.Report(&checker.ErrOldIndexFormat{ID: restic.NewRandomID()})
+	var issue checkIssue
+	rtest.OK(t, json.Unmarshal(buf.Bytes(), &issue))
+	rtest.Equals(t, "warning", issue.MessageType)
+	rtest.Equals(t, "old_index_format", issue.Kind)
+	rtest.Equals(t, uint(1), p.summary.NumWarnings)
+	rtest.Equals(t, uint(0), p.summary.NumErrors)
+}
@@ -6,8 +6,12 @@ import (
 	"io"
 
 	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/repository"
 	"github.com/restic/restic/internal/restic"
+	"github.com/restic/restic/internal/ui"
+	"github.com/restic/restic/internal/ui/table"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var cmdForget = &cobra.Command{
@@ -38,19 +42,32 @@ Exit status is 0 if the command was successful, and non-zero if there was any er
 
 // ForgetOptions collects all options for the forget command.
 type ForgetOptions struct {
-	Last          int
-	Hourly        int
-	Daily         int
-	Weekly        int
-	Monthly       int
-	Yearly        int
-	Within        restic.Duration
-	WithinHourly  restic.Duration
-	WithinDaily   restic.Duration
-	WithinWeekly  restic.Duration
-	WithinMonthly restic.Duration
-	WithinYearly  restic.Duration
-	KeepTags      restic.TagLists
+	Last            int
+	Hourly          int
+	Daily           int
+	Weekly          int
+	Monthly         int
+	Quarterly       int
+	Yearly          int
+	Within          restic.Duration
+	WithinHourly    restic.Duration
+	WithinDaily     restic.Duration
+	WithinWeekly    restic.Duration
+	WithinMonthly   restic.Duration
+	WithinQuarterly restic.Duration
+	WithinYearly    restic.Duration
+	KeepTags        restic.TagLists
+
+	// Bucketing
+	KeepOldest bool
+	WeekStart  restic.Weekday
+	Timezone   restic.Timezone
+
+	SizeBudget      string
+	sizeBudgetBytes uint64
+
+	UseRepoPolicy bool
+	Explain       string
 
 	restic.SnapshotFilter
 	Compact bool
@@ -67,19 +84,9 @@ func init() {
 	cmdRoot.AddCommand(cmdForget)
 
 	f := cmdForget.Flags()
-	f.IntVarP(&forgetOptions.Last, "keep-last", "l", 0, "keep the last `n` snapshots (use '-1' to keep all snapshots)")
-	f.IntVarP(&forgetOptions.Hourly, "keep-hourly", "H", 0, "keep the last `n` hourly snapshots (use '-1' to keep all hourly snapshots)")
-	f.IntVarP(&forgetOptions.Daily, "keep-daily", "d", 0, "keep the last `n` daily snapshots (use '-1' to keep all daily snapshots)")
-	f.IntVarP(&forgetOptions.Weekly, "keep-weekly", "w", 0, "keep the last `n` weekly snapshots (use '-1' to keep all weekly snapshots)")
-	f.IntVarP(&forgetOptions.Monthly, "keep-monthly", "m", 0, "keep the last `n` monthly snapshots (use '-1' to keep all monthly snapshots)")
-	f.IntVarP(&forgetOptions.Yearly, "keep-yearly", "y", 0, "keep the last `n` yearly snapshots (use '-1' to keep all yearly snapshots)")
-	f.VarP(&forgetOptions.Within, "keep-within", "", "keep snapshots that are newer than `duration` (eg. 1y5m7d2h) relative to the latest snapshot")
-	f.VarP(&forgetOptions.WithinHourly, "keep-within-hourly", "", "keep hourly snapshots that are newer than `duration` (eg. 1y5m7d2h) relative to the latest snapshot")
-	f.VarP(&forgetOptions.WithinDaily, "keep-within-daily", "", "keep daily snapshots that are newer than `duration` (eg. 1y5m7d2h) relative to the latest snapshot")
-	f.VarP(&forgetOptions.WithinWeekly, "keep-within-weekly", "", "keep weekly snapshots that are newer than `duration` (eg. 1y5m7d2h) relative to the latest snapshot")
-	f.VarP(&forgetOptions.WithinMonthly, "keep-within-monthly", "", "keep monthly snapshots that are newer than `duration` (eg. 1y5m7d2h) relative to the latest snapshot")
-	f.VarP(&forgetOptions.WithinYearly, "keep-within-yearly", "", "keep yearly snapshots that are newer than `duration` (eg. 1y5m7d2h) relative to the latest snapshot")
-	f.Var(&forgetOptions.KeepTags, "keep-tag", "keep snapshots with this `taglist` (can be specified multiple times)")
+	initExpirePolicyFlags(f, &forgetOptions)
+	f.BoolVar(&forgetOptions.UseRepoPolicy, "use-repo-policy", false, "apply the retention policy stored in the repository instead of the --keep-* options")
+	f.StringVar(&forgetOptions.Explain, "explain", "", "explain why the snapshot `ID` would be kept or removed by the policy, without removing anything")
 
 	initMultiSnapshotFilter(f, &forgetOptions.SnapshotFilter, false)
 	f.StringArrayVar(&forgetOptions.Hosts, "hostname", nil, "only consider snapshots with the given `hostname` (can be specified multiple times)")
@@ -99,19 +106,87 @@ func init() {
 	addPruneOptions(cmdForget)
 }
 
+// initExpirePolicyFlags adds the --keep-* options to the flag set.
+func initExpirePolicyFlags(f *pflag.FlagSet, opts *ForgetOptions) {
+	f.IntVarP(&opts.Last, "keep-last", "l", 0, "keep the last `n` snapshots (use '-1' to keep all snapshots)")
+	f.IntVarP(&opts.Hourly, "keep-hourly", "H", 0, "keep the last `n` hourly snapshots (use '-1' to keep all hourly snapshots)")
+	f.IntVarP(&opts.Daily, "keep-daily", "d", 0, "keep the last `n` daily snapshots (use '-1' to keep all daily snapshots)")
+	f.IntVarP(&opts.Weekly, "keep-weekly", "w", 0, "keep the last `n` weekly snapshots (use '-1' to keep all weekly snapshots)")
+	f.IntVarP(&opts.Monthly, "keep-monthly", "m", 0, "keep the last `n` monthly snapshots (use '-1' to keep all monthly snapshots)")
+	f.IntVar(&opts.Quarterly, "keep-quarterly", 0, "keep the last `n` quarterly snapshots (use '-1' to keep all quarterly snapshots)")
+	f.IntVarP(&opts.Yearly, "keep-yearly", "y", 0, "keep the last `n` yearly snapshots (use '-1' to keep all yearly snapshots)")
+	f.VarP(&opts.Within, "keep-within", "", "keep snapshots that are newer than `duration` (eg. 1y5m7d2h) relative to the latest snapshot")
+	f.VarP(&opts.WithinHourly, "keep-within-hourly", "", "keep hourly snapshots that are newer than `duration` (eg. 1y5m7d2h) relative to the latest snapshot")
+	f.VarP(&opts.WithinDaily, "keep-within-daily", "", "keep daily snapshots that are newer than `duration` (eg. 1y5m7d2h) relative to the latest snapshot")
+	f.VarP(&opts.WithinWeekly, "keep-within-weekly", "", "keep weekly snapshots that are newer than `duration` (eg. 1y5m7d2h) relative to the latest snapshot")
+	f.VarP(&opts.WithinMonthly, "keep-within-monthly", "", "keep monthly snapshots that are newer than `duration` (eg. 1y5m7d2h) relative to the latest snapshot")
+	f.VarP(&opts.WithinQuarterly, "keep-within-quarterly", "", "keep quarterly snapshots that are newer than `duration` (eg. 1y5m7d2h) relative to the latest snapshot")
+	f.VarP(&opts.WithinYearly, "keep-within-yearly", "", "keep yearly snapshots that are newer than `duration` (eg. 1y5m7d2h) relative to the latest snapshot")
+	f.Var(&opts.KeepTags, "keep-tag", "keep snapshots with this `taglist` (can be specified multiple times)")
+	f.StringVar(&opts.SizeBudget, "keep-size-budget", "", "only keep the newest snapshots until the data they reference reaches `size` (allowed suffixes: k/K, m/M, g/G, t/T)")
+	f.BoolVar(&opts.KeepOldest, "keep-oldest-per-period", false, "keep the oldest instead of the newest snapshot for each hour, day, week, month, quarter and year")
+	f.Var(&opts.WeekStart, "week-start", "start weeks on `day` for --keep-weekly and --keep-within-weekly (default: monday)")
+	f.Var(&opts.Timezone, "timezone", "use time zone `tz` (eg. Europe/Berlin) to determine the hour, day, week etc. of a snapshot (default: the time zone recorded in the snapshot)")
+}
+
+// expirePolicy returns the ExpirePolicy configured by the --keep-* options.
+func (opts *ForgetOptions) expirePolicy() restic.ExpirePolicy {
+	return restic.ExpirePolicy{
+		Last:            opts.Last,
+		Hourly:          opts.Hourly,
+		Daily:           opts.Daily,
+		Weekly:          opts.Weekly,
+		Monthly:         opts.Monthly,
+		Quarterly:       opts.Quarterly,
+		Yearly:          opts.Yearly,
+		Within:          opts.Within,
+		WithinHourly:    opts.WithinHourly,
+		WithinDaily:     opts.WithinDaily,
+		WithinWeekly:    opts.WithinWeekly,
+		WithinMonthly:   opts.WithinMonthly,
+		WithinQuarterly: opts.WithinQuarterly,
+		WithinYearly:    opts.WithinYearly,
+		Tags:            opts.KeepTags,
+		SizeBudget:      opts.sizeBudgetBytes,
+		Buckets: restic.BucketOptions{
+			KeepOldest: opts.KeepOldest,
+			WeekStart:  opts.WeekStart,
+			Timezone:   opts.Timezone,
+		},
+	}
+}
+
 func verifyForgetOptions(opts *ForgetOptions) error {
 	if opts.Last < -1 || opts.Hourly < -1 || opts.Daily < -1 || opts.Weekly < -1 ||
-		opts.Monthly < -1 || opts.Yearly < -1 {
+		opts.Monthly < -1 || opts.Quarterly < -1 || opts.Yearly < -1 {
 		return errors.Fatal("negative values other than -1 are not allowed for --keep-*")
 	}
 
 	for _, d := range []restic.Duration{opts.Within, opts.WithinHourly, opts.WithinDaily,
-		opts.WithinMonthly, opts.WithinWeekly, opts.WithinYearly} {
+		opts.WithinMonthly, opts.WithinWeekly, opts.WithinQuarterly, opts.WithinYearly} {
 		if d.Hours < 0 || d.Days < 0 || d.Months < 0 || d.Years < 0 {
 			return errors.Fatal("durations containing negative values are not allowed for --keep-within*")
 		}
 	}
 
+	if opts.SizeBudget != "" {
+		size, err := parseSizeStr(opts.SizeBudget)
+		if err != nil {
+			return errors.Fatalf("invalid size for --keep-size-budget: %v", err)
+		}
+		if size <= 0 {
+			return errors.Fatal("--keep-size-budget must be greater than zero")
+		}
+		opts.sizeBudgetBytes = uint64(size)
+	}
+
+	if opts.UseRepoPolicy && !opts.expirePolicy().Empty() {
+		return errors.Fatal("--use-repo-policy cannot be combined with --keep-* options")
+	}
+	if opts.UseRepoPolicy && opts.expirePolicy().Buckets != (restic.BucketOptions{}) {
+		return errors.Fatal("--use-repo-policy cannot be combined with --keep-oldest-per-period, --week-start or --timezone")
+	}
+
 	return nil
 }
 
@@ -126,11 +201,26 @@ func runForget(ctx context.Context, opts ForgetOptions, gopts GlobalOptions, arg
 		return err
 	}
 
+	if opts.UseRepoPolicy && len(args) > 0 {
+		return errors.Fatal("--use-repo-policy cannot be combined with snapshot IDs")
+	}
+
+	if opts.Explain != "" && len(args) > 0 {
+		return errors.Fatal("--explain cannot be combined with snapshot IDs")
+	}
+	if opts.Explain != "" && opts.Prune {
+		return errors.Fatal("--explain cannot be combined with --prune")
+	}
+
 	repo, err := OpenRepository(ctx, gopts)
 	if err != nil {
 		return err
 	}
 
+	if opts.Explain != "" {
+		return runForgetExplain(ctx, opts, gopts, repo)
+	}
+
 	if gopts.NoLock && !opts.DryRun {
 		return errors.Fatal("--no-lock is only applicable in combination with --dry-run for forget command")
 	}
@@ -156,38 +246,49 @@ func runForget(ctx context.Context, opts ForgetOptions, gopts GlobalOptions, arg
 	if len(args) > 0 {
 		// When explicit snapshots args are given, remove them immediately.
 		for _, sn := range snapshots {
+			if sn.Hold {
+				Warnf("snapshot %v is on hold, not removing it\n", sn.ID().Str())
+				continue
+			}
 			removeSnIDs.Insert(*sn.ID())
 		}
 	} else {
-		snapshotGroups, _, err := restic.GroupSnapshots(snapshots, opts.GroupBy)
+		policy := opts.expirePolicy()
+		groupBy := opts.GroupBy
+
+		var repoPolicy *restic.RetentionPolicy
+		if opts.UseRepoPolicy {
+			repoPolicy, err = loadForgetRepoPolicy(ctx, repo)
+			if err != nil {
+				return err
+			}
+			groupBy = repoPolicy.GroupBy
+		}
+
+		snapshotGroups, _, err := restic.GroupSnapshots(snapshots, groupBy)
 		if err != nil {
 			return err
 		}
 
-		policy := restic.ExpirePolicy{
-			Last:          opts.Last,
-			Hourly:        opts.Hourly,
-			Daily:         opts.Daily,
-			Weekly:        opts.Weekly,
-			Monthly:       opts.Monthly,
-			Yearly:        opts.Yearly,
-			Within:        opts.Within,
-			WithinHourly:  opts.WithinHourly,
-			WithinDaily:   opts.WithinDaily,
-			WithinWeekly:  opts.WithinWeekly,
-			WithinMonthly: opts.WithinMonthly,
-			WithinYearly:  opts.WithinYearly,
-			Tags:          opts.KeepTags,
+		if policy.SizeBudget > 0 || (repoPolicy != nil && repoPolicy.NeedsIndex()) {
+			// the index is required to determine the size of the snapshots
+			if err = repo.LoadIndex(ctx); err != nil {
+				return err
+			}
 		}
 
-		if policy.Empty() && len(args) == 0 {
+		if repoPolicy != nil {
+			if !gopts.JSON {
+				Verbosef("Applying repository policy with %d rules, grouping by %q\n", len(repoPolicy.Rules), groupBy)
+			}
+		} else if policy.Empty() {
 			if !gopts.JSON {
 				Verbosef("no policy was specified, no snapshots will be removed\n")
 			}
 		}
 
-		if !policy.Empty() {
-			if !gopts.JSON {
+		if !policy.Empty() || repoPolicy != nil {
+			if !policy.Empty() && !gopts.JSON {
 				Verbosef("Applying Policy: %v\n", policy)
 			}
 
@@ -204,12 +305,35 @@ func runForget(ctx context.Context, opts ForgetOptions, gopts GlobalOptions, arg
 					return err
 				}
 
+				policy := policy
+				if repoPolicy != nil {
+					var found bool
+					policy, found = repoPolicy.Lookup(key)
+					if !found {
+						if !gopts.JSON {
+							Verbosef("no rule of the repository policy matches, keeping all snapshots\n\n")
+						}
+						continue
+					}
+					if !gopts.JSON {
+						Verbosef("Applying Policy: %v\n", policy)
+					}
+				}
+
 				var fg ForgetGroup
 				fg.Tags = key.Tags
 				fg.Host = key.Hostname
 				fg.Paths = key.Paths
 
-				keep, remove, reasons := restic.ApplyPolicy(snapshotGroup, policy)
+				var sizer *restic.RepoSnapshotSizer
+				if policy.SizeBudget > 0 {
+					sizer = restic.NewRepoSnapshotSizer(ctx, repo)
+				}
+
+				keep, remove, reasons, overBudget := restic.ApplyPolicy(snapshotGroup, policy, sizer)
+				if sizer != nil && sizer.Err() != nil {
+					return sizer.Err()
+				}
 
 				if len(keep) != 0 && !gopts.Quiet && !gopts.JSON {
 					Printf("keep %d snapshots:\n", len(keep))
@@ -225,6 +349,15 @@ func runForget(ctx context.Context, opts ForgetOptions, gopts GlobalOptions, arg
 				}
 				addJSONSnapshots(&fg.Remove, remove)
 
+				if len(overBudget) != 0 && !gopts.Quiet && !gopts.JSON {
+					Printf("%d of the removed snapshots exceed the size budget of %s:\n", len(overBudget), ui.FormatBytes(policy.SizeBudget))
+					for _, sn := range overBudget {
+						Printf("  %v\n", sn.ID().Str())
+					}
+					Printf("\n")
+				}
+				addJSONSnapshots(&fg.OverBudget, overBudget)
+
 				fg.Reasons = reasons
 
 				jsonGroups = append(jsonGroups, &fg)
@@ -271,6 +404,159 @@ func runForget(ctx context.Context, opts ForgetOptions, gopts GlobalOptions, arg
 	return nil
 }
 
+// loadForgetRepoPolicy loads the retention policy stored in the repository,
+// which must exist.
+func loadForgetRepoPolicy(ctx context.Context, repo restic.Repository) (*restic.RetentionPolicy, error) {
+	repoPolicy, err := restic.LoadRetentionPolicy(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	if repoPolicy == nil {
+		return nil, errors.Fatal("the repository does not contain a retention policy, use \"restic policy set\" to add one")
+	}
+	if err := repoPolicy.Validate(); err != nil {
+		return nil, errors.Fatalf("invalid retention policy: %v", err)
+	}
+	return repoPolicy, nil
+}
+
+// ForgetExplanation is used to print the result of forget --explain in JSON.
+type ForgetExplanation struct {
+	Snapshot  Snapshot              `json:"snapshot"`
+	Tags      []string              `json:"tags"`
+	Host      string                `json:"host"`
+	Paths     []string              `json:"paths"`
+	Policy    string                `json:"policy"`
+	Keep      bool                  `json:"keep"`
+	Decisions []restic.RuleDecision `json:"decisions"`
+}
+
+// runForgetExplain explains why the snapshot opts.Explain is kept or removed by
+// the policy. Nothing is removed from the repository.
+func runForgetExplain(ctx context.Context, opts ForgetOptions, gopts GlobalOptions, repo *repository.Repository) error {
+	var err error
+	if !gopts.NoLock {
+		var lock *restic.Lock
+		lock, ctx, err = lockRepo(ctx, repo, gopts.RetryLock, gopts.JSON)
+		defer unlockRepo(lock)
+		if err != nil {
+			return err
+		}
+	}
+
+	target, err := opts.SnapshotFilter.FindLatest(ctx, repo.Backend(), repo, opts.Explain)
+	if err != nil {
+		return errors.Fatalf("failed to find snapshot: %v", err)
+	}
+
+	var snapshots restic.Snapshots
+	var sn *restic.Snapshot
+	for s := range FindFilteredSnapshots(ctx, repo.Backend(), repo, &opts.SnapshotFilter, nil) {
+		if s.ID().Equal(*target.ID()) {
+			sn = s
+		}
+		snapshots = append(snapshots, s)
+	}
+	if sn == nil {
+		return errors.Fatalf("snapshot %v does not match the --host, --tag and --path options", target.ID().Str())
+	}
+
+	policy := opts.expirePolicy()
+	groupBy := opts.GroupBy
+	var repoPolicy *restic.RetentionPolicy
+	if opts.UseRepoPolicy {
+		repoPolicy, err = loadForgetRepoPolicy(ctx, repo)
+		if err != nil {
+			return err
+		}
+		groupBy = repoPolicy.GroupBy
+	}
+
+	snapshotGroups, _, err := restic.GroupSnapshots(snapshots, groupBy)
+	if err != nil {
+		return err
+	}
+
+	var groupKey string
+	var group restic.Snapshots
+	for k, snapshotGroup := range snapshotGroups {
+		for _, s := range snapshotGroup {
+			if s == sn {
+				groupKey, group = k, snapshotGroup
+			}
+		}
+	}
+
+	var key restic.SnapshotGroupKey
+	if err := json.Unmarshal([]byte(groupKey), &key); err != nil {
+		return err
+	}
+
+	res := ForgetExplanation{
+		Tags:  key.Tags,
+		Host:  key.Hostname,
+		Paths: key.Paths,
+		Keep:  true,
+	}
+	res.Snapshot = Snapshot{Snapshot: sn, ID: sn.ID(), ShortID: sn.ID().Str()}
+
+	found := true
+	if repoPolicy != nil {
+		policy, found = repoPolicy.Lookup(key)
+	}
+
+	switch {
+	case !found:
+		res.Policy = "no rule of the repository policy matches"
+	case policy.Empty():
+		res.Policy = "no policy was specified"
+	default:
+		res.Policy = policy.String()
+
+		var sizer *restic.RepoSnapshotSizer
+		if policy.SizeBudget > 0 {
+			// the index is required to determine the size of the snapshots
+			if err = repo.LoadIndex(ctx); err != nil {
+				return err
+			}
+			sizer = restic.NewRepoSnapshotSizer(ctx, repo)
+		}
+
+		res.Keep, res.Decisions = restic.ExplainPolicy(group, policy, sizer, sn)
+		if sizer != nil && sizer.Err() != nil {
+			return sizer.Err()
+		}
+	}
+
+	if gopts.JSON {
+		return json.NewEncoder(globalOptions.stdout).Encode(res)
+	}
+
+	if res.Keep {
+		Printf("snapshot %v would be kept\n", sn.ID().Str())
+	} else {
+		Printf("snapshot %v would be removed\n", sn.ID().Str())
+	}
+	if err := PrintSnapshotGroupHeader(globalOptions.stdout, groupKey); err != nil {
+		return err
+	}
+	Printf("policy: %v\n", res.Policy)
+	if len(res.Decisions) == 0 {
+		return nil
+	}
+	Printf("\n")
+
+	tab := table.New()
+	tab.AddColumn("Rule", "{{ .Rule }}")
+	tab.AddColumn("Period", "{{ .Bucket }}")
+	tab.AddColumn("Keep", "{{ if .Keep }}yes{{ else }}no{{ end }}")
+	tab.AddColumn("Reason", "{{ .Reason }}")
+	for _, d := range res.Decisions {
+		tab.AddRow(d)
+	}
+	return tab.Write(globalOptions.stdout)
+}
+
 // ForgetGroup helps to print what is forgotten in JSON.
 type ForgetGroup struct {
 	Tags    []string            `json:"tags"`
@@ -279,6 +565,10 @@ type ForgetGroup struct {
 	Keep    []Snapshot          `json:"keep"`
 	Remove  []Snapshot          `json:"remove"`
 	Reasons []restic.KeepReason `json:"reasons"`
+
+	// OverBudget contains the snapshots from Remove which were only removed
+	// because of the size budget.
+	OverBudget []Snapshot `json:"over_size_budget,omitempty"`
 }
 
 func addJSONSnapshots(js *[]Snapshot, list restic.Snapshots) {
@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"path/filepath"
 	"testing"
 
+	"github.com/restic/restic/internal/restic"
 	rtest "github.com/restic/restic/internal/test"
 )
 
@@ -11,3 +14,84 @@ func testRunForget(t testing.TB, gopts GlobalOptions, args ...string) {
 	opts := ForgetOptions{}
 	rtest.OK(t, runForget(context.TODO(), opts, gopts, args))
 }
+
+func TestForgetSizeBudget(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	opts := BackupOptions{}
+	testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9", "2")}, opts, env.gopts)
+	testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9", "3")}, opts, env.gopts)
+	testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9", "4")}, opts, env.gopts)
+	testListSnapshots(t, env.gopts, 3)
+
+	buf, err := withCaptureStdout(func() error {
+		gopts := env.gopts
+		gopts.JSON = true
+		opts := ForgetOptions{
+			DryRun:     true,
+			SizeBudget: "1",
+			GroupBy:    restic.SnapshotGroupByOptions{Host: true},
+		}
+		rtest.OK(t, verifyForgetOptions(&opts))
+		return runForget(context.TODO(), opts, gopts, nil)
+	})
+	rtest.OK(t, err)
+
+	var forgets []*ForgetGroup
+	rtest.OK(t, json.Unmarshal(buf.Bytes(), &forgets))
+
+	rtest.Assert(t, len(forgets) == 1, "expected 1 snapshot group, got %v", len(forgets))
+	rtest.Assert(t, len(forgets[0].Keep) == 1,
+		"expected only the newest snapshot to be kept, got %v", len(forgets[0].Keep))
+	rtest.Assert(t, len(forgets[0].Remove) == 2,
+		"expected 2 snapshots to be removed, got %v", len(forgets[0].Remove))
+	rtest.Equals(t, forgets[0].Remove, forgets[0].OverBudget)
+}
+
+func testRunForgetExplain(t testing.TB, gopts GlobalOptions, opts ForgetOptions, id string) ForgetExplanation {
+	buf, err := withCaptureStdout(func() error {
+		gopts.JSON = true
+		opts.Explain = id
+		opts.GroupBy = restic.SnapshotGroupByOptions{Host: true}
+		return runForget(context.TODO(), opts, gopts, nil)
+	})
+	rtest.OK(t, err)
+
+	var res ForgetExplanation
+	rtest.OK(t, json.Unmarshal(buf.Bytes(), &res))
+	return res
+}
+
+func TestForgetExplain(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	opts := BackupOptions{}
+	testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9", "2")}, opts, env.gopts)
+	testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9", "3")}, opts, env.gopts)
+	snapshotIDs := testListSnapshots(t, env.gopts, 2)
+
+	var ids []string
+	for _, id := range snapshotIDs {
+		ids = append(ids, id.String())
+	}
+
+	res := testRunForgetExplain(t, env.gopts, ForgetOptions{Last: 1}, ids[0])
+	res2 := testRunForgetExplain(t, env.gopts, ForgetOptions{Last: 1}, ids[1])
+	if res.Keep {
+		res, res2 = res2, res
+	}
+
+	// the older snapshot would be removed, the newer one is kept as the last snapshot
+	rtest.Assert(t, !res.Keep, "expected one snapshot to be removed")
+	rtest.Equals(t, []restic.RuleDecision{{KeepRule: restic.KeepRule{Rule: "last"}, Keep: false, Reason: "only the last 1 snapshots are kept"}}, res.Decisions)
+	rtest.Assert(t, res2.Keep, "expected one snapshot to be kept")
+	rtest.Equals(t, 1, len(res2.Decisions))
+	rtest.Equals(t, restic.KeepRule{Rule: "last", Position: 1}, res2.Decisions[0].KeepRule)
+
+	// nothing was removed
+	testListSnapshots(t, env.gopts, 2)
+}
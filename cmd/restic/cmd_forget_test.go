@@ -39,6 +39,9 @@ func TestForgetOptionValues(t *testing.T) {
 		{ForgetOptions{Weekly: -2}, true, negValErrorMsg},
 		{ForgetOptions{Monthly: -2}, true, negValErrorMsg},
 		{ForgetOptions{Yearly: -2}, true, negValErrorMsg},
+		{ForgetOptions{Quarterly: 1}, false, ""},
+		{ForgetOptions{Quarterly: -1}, false, ""},
+		{ForgetOptions{Quarterly: -2}, true, negValErrorMsg},
 		{ForgetOptions{Within: restic.ParseDurationOrPanic("1y2m3d3h")}, false, ""},
 		{ForgetOptions{WithinHourly: restic.ParseDurationOrPanic("1y2m3d3h")}, false, ""},
 		{ForgetOptions{WithinDaily: restic.ParseDurationOrPanic("1y2m3d3h")}, false, ""},
@@ -51,6 +54,11 @@ func TestForgetOptionValues(t *testing.T) {
 		{ForgetOptions{WithinWeekly: restic.ParseDurationOrPanic("1y2m3d-3h")}, true, negDurationValErrorMsg},
 		{ForgetOptions{WithinMonthly: restic.ParseDurationOrPanic("-2y4m6d8h")}, true, negDurationValErrorMsg},
 		{ForgetOptions{WithinYearly: restic.ParseDurationOrPanic("2y-4m6d8h")}, true, negDurationValErrorMsg},
+		{ForgetOptions{WithinQuarterly: restic.ParseDurationOrPanic("1y-2m")}, true, negDurationValErrorMsg},
+		{ForgetOptions{UseRepoPolicy: true, KeepOldest: true}, true, "Fatal: --use-repo-policy cannot be combined with --keep-oldest-per-period, --week-start or --timezone"},
+		{ForgetOptions{SizeBudget: "10M"}, false, ""},
+		{ForgetOptions{SizeBudget: "0"}, true, "Fatal: --keep-size-budget must be greater than zero"},
+		{ForgetOptions{SizeBudget: "10X"}, true, "Fatal: invalid size for --keep-size-budget: strconv.ParseInt: parsing \"10X\": invalid syntax"},
 	}
 
 	for _, testCase := range testCases {
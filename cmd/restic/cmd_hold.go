@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/restic"
+
+	"github.com/spf13/cobra"
+)
+
+var cmdHold = &cobra.Command{
+	Use:   "hold",
+	Short: "Protect snapshots from being removed",
+	Long: `
+The "hold" command manages holds on snapshots. A snapshot on hold is always
+kept by "forget", regardless of the policy, cannot be removed by passing its ID
+to "forget", and its original is not removed by "rewrite --forget". "prune" never
+removes the data referenced by snapshots on hold.
+`,
+}
+
+var cmdHoldAdd = &cobra.Command{
+	Use:   "add [flags] [snapshot-ID ...]",
+	Short: "Put snapshots on hold",
+	Long: `
+The "hold add" command puts snapshots on hold.
+
+When no snapshot-ID is given, all snapshots matching the host, tag and path filter criteria are modified.
+
+EXIT STATUS
+===========
+
+Exit status is 0 if the command was successful, and non-zero if there was any error.
+`,
+	DisableAutoGenTag: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runHold(cmd.Context(), holdAddOptions, globalOptions, args, true)
+	},
+}
+
+var cmdHoldRemove = &cobra.Command{
+	Use:   "remove [flags] [snapshot-ID ...]",
+	Short: "Release the hold on snapshots",
+	Long: `
+The "hold remove" command releases the hold on snapshots, such that they can be
+removed by "forget" again.
+
+When no snapshot-ID is given, all snapshots matching the host, tag and path filter criteria are modified.
+
+EXIT STATUS
+===========
+
+Exit status is 0 if the command was successful, and non-zero if there was any error.
+`,
+	DisableAutoGenTag: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runHold(cmd.Context(), holdRemoveOptions, globalOptions, args, false)
+	},
+}
+
+var cmdHoldList = &cobra.Command{
+	Use:   "list [flags] [snapshot-ID ...]",
+	Short: "List snapshots on hold",
+	Long: `
+The "hold list" command lists all snapshots which are on hold.
+
+EXIT STATUS
+===========
+
+Exit status is 0 if the command was successful, and non-zero if there was any error.
+`,
+	DisableAutoGenTag: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runHoldList(cmd.Context(), holdListOptions, globalOptions, args)
+	},
+}
+
+// HoldOptions bundles all options for the hold commands.
+type HoldOptions struct {
+	restic.SnapshotFilter
+	Compact bool
+}
+
+var holdAddOptions HoldOptions
+var holdRemoveOptions HoldOptions
+var holdListOptions HoldOptions
+
+func init() {
+	cmdRoot.AddCommand(cmdHold)
+	cmdHold.AddCommand(cmdHoldAdd)
+	cmdHold.AddCommand(cmdHoldRemove)
+	cmdHold.AddCommand(cmdHoldList)
+
+	initMultiSnapshotFilter(cmdHoldAdd.Flags(), &holdAddOptions.SnapshotFilter, true)
+	initMultiSnapshotFilter(cmdHoldRemove.Flags(), &holdRemoveOptions.SnapshotFilter, true)
+
+	f := cmdHoldList.Flags()
+	initMultiSnapshotFilter(f, &holdListOptions.SnapshotFilter, true)
+	f.BoolVarP(&holdListOptions.Compact, "compact", "c", false, "use compact output format")
+}
+
+func runHold(ctx context.Context, opts HoldOptions, gopts GlobalOptions, args []string, hold bool) error {
+	repo, err := OpenRepository(ctx, gopts)
+	if err != nil {
+		return err
+	}
+
+	if !gopts.NoLock {
+		Verbosef("create exclusive lock for repository\n")
+		var lock *restic.Lock
+		lock, ctx, err = lockRepoExclusive(ctx, repo, gopts.RetryLock, gopts.JSON)
+		defer unlockRepo(lock)
+		if err != nil {
+			return err
+		}
+	}
+
+	changeCnt := 0
+	for sn := range FindFilteredSnapshots(ctx, repo.Backend(), repo, &opts.SnapshotFilter, args) {
+		if sn.Hold == hold {
+			continue
+		}
+
+		sn.Hold = hold
+		if err := replaceSnapshot(ctx, repo, sn); err != nil {
+			Warnf("unable to modify the hold for snapshot ID %q, ignoring: %v\n", sn.ID(), err)
+			continue
+		}
+		changeCnt++
+	}
+
+	if changeCnt == 0 {
+		Verbosef("no snapshots were modified\n")
+	} else if hold {
+		Verbosef("put %v snapshots on hold\n", changeCnt)
+	} else {
+		Verbosef("released the hold on %v snapshots\n", changeCnt)
+	}
+	return nil
+}
+
+func runHoldList(ctx context.Context, opts HoldOptions, gopts GlobalOptions, args []string) error {
+	repo, err := OpenRepository(ctx, gopts)
+	if err != nil {
+		return err
+	}
+
+	if !gopts.NoLock {
+		var lock *restic.Lock
+		lock, ctx, err = lockRepo(ctx, repo, gopts.RetryLock, gopts.JSON)
+		defer unlockRepo(lock)
+		if err != nil {
+			return err
+		}
+	}
+
+	var snapshots restic.Snapshots
+	for sn := range FindFilteredSnapshots(ctx, repo.Backend(), repo, &opts.SnapshotFilter, args) {
+		if sn.Hold {
+			snapshots = append(snapshots, sn)
+		}
+	}
+	sort.Sort(sort.Reverse(snapshots))
+
+	if gopts.JSON {
+		list := []Snapshot{}
+		addJSONSnapshots(&list, snapshots)
+		err = json.NewEncoder(globalOptions.stdout).Encode(list)
+		if err != nil {
+			return errors.Wrap(err, "Encode")
+		}
+		return nil
+	}
+
+	PrintSnapshots(globalOptions.stdout, snapshots, nil, opts.Compact)
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/restic/restic/internal/restic"
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func testRunHold(t testing.TB, gopts GlobalOptions, hold bool, args ...string) {
+	rtest.OK(t, runHold(context.TODO(), HoldOptions{}, gopts, args, hold))
+}
+
+func testRunHoldList(t testing.TB, gopts GlobalOptions) []Snapshot {
+	buf, err := withCaptureStdout(func() error {
+		gopts.JSON = true
+		return runHoldList(context.TODO(), HoldOptions{}, gopts, nil)
+	})
+	rtest.OK(t, err)
+
+	var snapshots []Snapshot
+	rtest.OK(t, json.Unmarshal(buf.Bytes(), &snapshots))
+	return snapshots
+}
+
+func TestHold(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	opts := BackupOptions{}
+	testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9", "2")}, opts, env.gopts)
+	held := testListSnapshots(t, env.gopts, 1)[0]
+	testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9", "3")}, opts, env.gopts)
+	testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9", "4")}, opts, env.gopts)
+	testListSnapshots(t, env.gopts, 3)
+
+	rtest.Equals(t, 0, len(testRunHoldList(t, env.gopts)))
+	testRunHold(t, env.gopts, true, held.String())
+	list := testRunHoldList(t, env.gopts)
+	rtest.Equals(t, 1, len(list))
+	rtest.Assert(t, list[0].Hold, "snapshot is not on hold")
+	held = *list[0].ID
+
+	// the snapshot on hold is kept regardless of the policy
+	buf, err := withCaptureStdout(func() error {
+		gopts := env.gopts
+		gopts.JSON = true
+		opts := ForgetOptions{
+			DryRun:  true,
+			Last:    1,
+			GroupBy: restic.SnapshotGroupByOptions{Host: true},
+		}
+		return runForget(context.TODO(), opts, gopts, nil)
+	})
+	rtest.OK(t, err)
+	var forgets []*ForgetGroup
+	rtest.OK(t, json.Unmarshal(buf.Bytes(), &forgets))
+	rtest.Equals(t, 1, len(forgets))
+	rtest.Equals(t, 2, len(forgets[0].Keep))
+	rtest.Equals(t, 1, len(forgets[0].Remove))
+	rtest.Assert(t, forgets[0].Reasons[1].Snapshot.Hold, "expected snapshot on hold to be kept")
+	rtest.Equals(t, []string{"hold"}, forgets[0].Reasons[1].Matches)
+
+	// explicitly removing the snapshot is refused as well
+	testRunForget(t, env.gopts, held.String())
+	testListSnapshots(t, env.gopts, 3)
+
+	testRunHold(t, env.gopts, false, held.String())
+	rtest.Equals(t, 0, len(testRunHoldList(t, env.gopts)))
+	testRunForget(t, env.gopts, testListSnapshots(t, env.gopts, 3)[0].String())
+	testListSnapshots(t, env.gopts, 2)
+}
+
+func TestRewriteForgetHold(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+	createBasicRewriteRepo(t, env)
+	testRunHold(t, env.gopts, true)
+
+	// the original snapshot is not removed as it is on hold
+	testRunRewriteExclude(t, env.gopts, []string{"3"}, true)
+	snapshotIDs := testRunList(t, "snapshots", env.gopts)
+	rtest.Assert(t, len(snapshotIDs) == 2, "expected two snapshots, got %v", snapshotIDs)
+	testRunCheck(t, env.gopts)
+}
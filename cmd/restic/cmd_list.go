@@ -11,7 +11,7 @@ import (
 )
 
 var cmdList = &cobra.Command{
-	Use:   "list [flags] [blobs|packs|index|snapshots|keys|locks]",
+	Use:   "list [flags] [blobs|packs|index|snapshots|keys|locks|pending|checks]",
 	Short: "List objects in the repository",
 	Long: `
 The "list" command allows listing objects in the repository based on type.
@@ -62,6 +62,10 @@ func runList(ctx context.Context, cmd *cobra.Command, gopts GlobalOptions, args
 		t = restic.KeyFile
 	case "locks":
 		t = restic.LockFile
+	case "pending":
+		t = restic.PendingFile
+	case "checks":
+		t = restic.CheckFile
 	case "blobs":
 		return index.ForAllIndexes(ctx, repo, func(id restic.ID, idx *index.Index, oldFormat bool, err error) error {
 			if err != nil {
@@ -75,6 +79,9 @@ func runList(ctx context.Context, cmd *cobra.Command, gopts GlobalOptions, args
 	default:
 		return errors.Fatal("invalid type")
 	}
+	if err := repo.Config().Supports(t); err != nil {
+		return err
+	}
 
 	return repo.List(ctx, t, func(id restic.ID, size int64) error {
 		Printf("%s\n", id)
@@ -211,9 +211,10 @@ func runLs(ctx context.Context, opts LsOptions, gopts GlobalOptions, args []stri
 	}
 
 	sn, err := (&restic.SnapshotFilter{
-		Hosts: opts.Hosts,
-		Paths: opts.Paths,
-		Tags:  opts.Tags,
+		Hosts:  opts.Hosts,
+		Paths:  opts.Paths,
+		Tags:   opts.Tags,
+		Labels: opts.Labels,
 	}).FindLatest(ctx, snapshotLister, repo, args[0])
 	if err != nil {
 		return err
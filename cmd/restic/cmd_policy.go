@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/restic"
+	"github.com/restic/restic/internal/ui/table"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var cmdPolicy = &cobra.Command{
+	Use:   "policy",
+	Short: "Manage the retention policy stored in the repository",
+	Long: `
+The "policy" command manages the retention policy stored in the repository.
+The policy consists of rules which assign "--keep-*" options to groups of
+snapshots. It is applied by running "forget --use-repo-policy", such that all
+clients use the same retention policy.
+
+Storing a policy requires repository version 3, use "restic migrate
+upgrade_repo_v3" to upgrade a repository.
+`,
+}
+
+var cmdPolicyShow = &cobra.Command{
+	Use:   "show",
+	Short: "Show the retention policy",
+	Long: `
+The "policy show" command prints the retention policy stored in the repository.
+
+EXIT STATUS
+===========
+
+Exit status is 0 if the command was successful, and non-zero if there was any error.
+`,
+	DisableAutoGenTag: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			return errors.Fatal("the policy show command expects no arguments")
+		}
+		return runPolicyShow(cmd.Context(), globalOptions)
+	},
+}
+
+var cmdPolicySet = &cobra.Command{
+	Use:   "set [flags]",
+	Short: "Set the retention policy for a group of snapshots",
+	Long: `
+The "policy set" command adds a rule to the retention policy stored in the
+repository. The rule applies the given "--keep-*" options to all snapshot groups
+which match "--host", "--path" and "--tag". If none of these are specified, the
+rule applies to all snapshot groups. An existing rule for the same snapshot
+groups is replaced. If several rules match a snapshot group, the rule with the
+most matching criteria is used.
+
+The grouping of snapshots is set for the whole policy using "--group-by". Only
+criteria which are part of the grouping can be used to select snapshot groups.
+
+EXIT STATUS
+===========
+
+Exit status is 0 if the command was successful, and non-zero if there was any error.
+`,
+	DisableAutoGenTag: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			return errors.Fatal("the policy set command expects no arguments")
+		}
+		policySetOptions.groupBySet = cmd.Flags().Changed("group-by")
+		return runPolicySet(cmd.Context(), policySetOptions, globalOptions)
+	},
+}
+
+var cmdPolicyRemove = &cobra.Command{
+	Use:   "remove [flags]",
+	Short: "Remove rules from the retention policy",
+	Long: `
+The "policy remove" command removes the rule for the snapshot groups selected by
+"--host", "--path" and "--tag" from the retention policy stored in the
+repository. Use "--all" to remove the whole retention policy.
+
+EXIT STATUS
+===========
+
+Exit status is 0 if the command was successful, and non-zero if there was any error.
+`,
+	DisableAutoGenTag: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			return errors.Fatal("the policy remove command expects no arguments")
+		}
+		return runPolicyRemove(cmd.Context(), policyRemoveOptions, globalOptions)
+	},
+}
+
+// PolicyOptions collects all options for the policy set and remove commands.
+type PolicyOptions struct {
+	// Keep contains the --keep-* options
+	Keep    ForgetOptions
+	GroupBy restic.SnapshotGroupByOptions
+	All     bool
+
+	Hostname string
+	Paths    []string
+	Tags     restic.TagList
+
+	groupBySet bool
+}
+
+var policySetOptions PolicyOptions
+var policyRemoveOptions PolicyOptions
+
+func init() {
+	cmdRoot.AddCommand(cmdPolicy)
+	cmdPolicy.AddCommand(cmdPolicyShow)
+	cmdPolicy.AddCommand(cmdPolicySet)
+	cmdPolicy.AddCommand(cmdPolicyRemove)
+
+	f := cmdPolicySet.Flags()
+	initExpirePolicyFlags(f, &policySetOptions.Keep)
+	initPolicyRuleFlags(f, &policySetOptions)
+	policySetOptions.GroupBy = restic.SnapshotGroupByOptions{Host: true, Path: true}
+	f.VarP(&policySetOptions.GroupBy, "group-by", "g", "`group` snapshots by host, paths and/or tags, separated by comma (disable grouping with ''). Applies to all rules of the policy")
+	f.SortFlags = false
+
+	f = cmdPolicyRemove.Flags()
+	initPolicyRuleFlags(f, &policyRemoveOptions)
+	f.BoolVar(&policyRemoveOptions.All, "all", false, "remove the whole retention policy")
+}
+
+// initPolicyRuleFlags adds the options to select the snapshot groups of a rule.
+func initPolicyRuleFlags(f *pflag.FlagSet, opts *PolicyOptions) {
+	f.StringVar(&opts.Hostname, "host", "", "select snapshot groups with the given `hostname`")
+	f.StringArrayVar(&opts.Paths, "path", nil, "select snapshot groups with the given `path` (can be specified multiple times)")
+	f.Var(&opts.Tags, "tag", "select snapshot groups with the given `taglist`")
+}
+
+func runPolicyShow(ctx context.Context, gopts GlobalOptions) error {
+	repo, err := OpenRepository(ctx, gopts)
+	if err != nil {
+		return err
+	}
+
+	if !gopts.NoLock {
+		var lock *restic.Lock
+		lock, ctx, err = lockRepo(ctx, repo, gopts.RetryLock, gopts.JSON)
+		defer unlockRepo(lock)
+		if err != nil {
+			return err
+		}
+	}
+
+	policy, err := restic.LoadRetentionPolicy(ctx, repo)
+	if err != nil {
+		return err
+	}
+
+	if gopts.JSON {
+		return json.NewEncoder(globalOptions.stdout).Encode(policy)
+	}
+
+	if policy == nil {
+		Printf("the repository does not contain a retention policy\n")
+		return nil
+	}
+
+	Printf("group snapshots by: %q\n\n", policy.GroupBy)
+
+	tab := table.New()
+	tab.AddColumn("Snapshot groups", "{{ .Groups }}")
+	tab.AddColumn("Policy", "{{ .Policy }}")
+	for _, rule := range policy.Rules {
+		tab.AddRow(rule)
+	}
+
+	return tab.Write(globalOptions.stdout)
+}
+
+func runPolicySet(ctx context.Context, opts PolicyOptions, gopts GlobalOptions) error {
+	err := verifyForgetOptions(&opts.Keep)
+	if err != nil {
+		return err
+	}
+
+	expirePolicy := opts.Keep.expirePolicy()
+	if expirePolicy.Empty() {
+		return errors.Fatal("no --keep-* options specified")
+	}
+
+	repo, err := OpenRepository(ctx, gopts)
+	if err != nil {
+		return err
+	}
+
+	lock, ctx, err := lockRepoExclusive(ctx, repo, gopts.RetryLock, gopts.JSON)
+	defer unlockRepo(lock)
+	if err != nil {
+		return err
+	}
+
+	policy, err := restic.LoadRetentionPolicy(ctx, repo)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		policy = &restic.RetentionPolicy{GroupBy: opts.GroupBy}
+	}
+	if opts.groupBySet {
+		policy.GroupBy = opts.GroupBy
+	}
+
+	rule := restic.NewRetentionRule(opts.Hostname, opts.Paths, opts.Tags, expirePolicy)
+	policy.SetRule(rule)
+
+	if err := policy.Validate(); err != nil {
+		return errors.Fatalf("invalid retention policy: %v", err)
+	}
+
+	err = restic.SaveRetentionPolicy(ctx, repo, policy)
+	if err != nil {
+		return err
+	}
+
+	Verbosef("set retention policy for %v: %v\n", rule.Groups(), expirePolicy)
+	return nil
+}
+
+func runPolicyRemove(ctx context.Context, opts PolicyOptions, gopts GlobalOptions) error {
+	if opts.All && (opts.Hostname != "" || len(opts.Paths) > 0 || len(opts.Tags) > 0) {
+		return errors.Fatal("--all cannot be combined with --host, --path or --tag")
+	}
+
+	repo, err := OpenRepository(ctx, gopts)
+	if err != nil {
+		return err
+	}
+
+	lock, ctx, err := lockRepoExclusive(ctx, repo, gopts.RetryLock, gopts.JSON)
+	defer unlockRepo(lock)
+	if err != nil {
+		return err
+	}
+
+	policy, err := restic.LoadRetentionPolicy(ctx, repo)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		return errors.Fatal("the repository does not contain a retention policy")
+	}
+
+	if !opts.All {
+		rule := restic.NewRetentionRule(opts.Hostname, opts.Paths, opts.Tags, restic.ExpirePolicy{})
+		if !policy.RemoveRule(rule) {
+			return errors.Fatalf("the retention policy contains no rule for %v", rule.Groups())
+		}
+		Verbosef("removed retention policy for %v\n", rule.Groups())
+
+		if len(policy.Rules) > 0 {
+			return restic.SaveRetentionPolicy(ctx, repo, policy)
+		}
+	}
+
+	err = restic.RemoveRetentionPolicy(ctx, repo)
+	if err != nil {
+		return err
+	}
+
+	Verbosef("removed retention policy\n")
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/restic/restic/internal/restic"
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func testRunPolicySet(t testing.TB, gopts GlobalOptions, opts PolicyOptions) {
+	rtest.OK(t, runPolicySet(context.TODO(), opts, gopts))
+}
+
+func testRunPolicyShow(t testing.TB, gopts GlobalOptions) *restic.RetentionPolicy {
+	buf, err := withCaptureStdout(func() error {
+		gopts.JSON = true
+		return runPolicyShow(context.TODO(), gopts)
+	})
+	rtest.OK(t, err)
+
+	var policy *restic.RetentionPolicy
+	rtest.OK(t, json.Unmarshal(buf.Bytes(), &policy))
+	return policy
+}
+
+func testRunForgetRepoPolicy(t testing.TB, gopts GlobalOptions) []*ForgetGroup {
+	buf, err := withCaptureStdout(func() error {
+		gopts.JSON = true
+		opts := ForgetOptions{
+			DryRun:        true,
+			UseRepoPolicy: true,
+		}
+		return runForget(context.TODO(), opts, gopts, nil)
+	})
+	rtest.OK(t, err)
+
+	var forgets []*ForgetGroup
+	if buf.Len() == 0 {
+		// no output if no group was processed
+		return forgets
+	}
+	rtest.OK(t, json.Unmarshal(buf.Bytes(), &forgets))
+	return forgets
+}
+
+func TestPolicy(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	opts := BackupOptions{}
+	testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9", "2")}, opts, env.gopts)
+	testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9", "3")}, opts, env.gopts)
+	testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9", "4")}, opts, env.gopts)
+	testListSnapshots(t, env.gopts, 3)
+
+	rtest.Assert(t, testRunPolicyShow(t, env.gopts) == nil, "expected no policy in new repository")
+	err := runForget(context.TODO(), ForgetOptions{UseRepoPolicy: true}, env.gopts, nil)
+	rtest.Assert(t, err != nil, "expected forget to fail without a repository policy")
+
+	// a rule for a different host does not remove anything
+	testRunPolicySet(t, env.gopts, PolicyOptions{
+		Keep:       ForgetOptions{Last: 1},
+		GroupBy:    restic.SnapshotGroupByOptions{Host: true},
+		Hostname:   "other",
+		groupBySet: true,
+	})
+	forgets := testRunForgetRepoPolicy(t, env.gopts)
+	rtest.Equals(t, 0, len(forgets))
+
+	// selecting paths is not possible if snapshots are not grouped by path
+	err = runPolicySet(context.TODO(), PolicyOptions{
+		Keep:  ForgetOptions{Last: 1},
+		Paths: []string{"/home"},
+	}, env.gopts)
+	rtest.Assert(t, err != nil, "expected error for rule selecting paths")
+
+	testRunPolicySet(t, env.gopts, PolicyOptions{Keep: ForgetOptions{Last: 2}})
+	policy := testRunPolicyShow(t, env.gopts)
+	rtest.Equals(t, restic.SnapshotGroupByOptions{Host: true}, policy.GroupBy)
+	rtest.Equals(t, 2, len(policy.Rules))
+
+	forgets = testRunForgetRepoPolicy(t, env.gopts)
+	rtest.Equals(t, 1, len(forgets))
+	rtest.Equals(t, 2, len(forgets[0].Keep))
+	rtest.Equals(t, 1, len(forgets[0].Remove))
+
+	rtest.OK(t, runPolicyRemove(context.TODO(), PolicyOptions{}, env.gopts))
+	policy = testRunPolicyShow(t, env.gopts)
+	rtest.Equals(t, 1, len(policy.Rules))
+
+	rtest.OK(t, runPolicyRemove(context.TODO(), PolicyOptions{All: true}, env.gopts))
+	rtest.Assert(t, testRunPolicyShow(t, env.gopts) == nil, "expected policy to be removed")
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"math"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/restic/restic/internal/debug"
 	"github.com/restic/restic/internal/errors"
@@ -14,6 +16,7 @@ import (
 	"github.com/restic/restic/internal/repository"
 	"github.com/restic/restic/internal/restic"
 	"github.com/restic/restic/internal/ui"
+	"github.com/restic/restic/internal/ui/progress"
 
 	"github.com/spf13/cobra"
 )
@@ -56,8 +59,28 @@ type PruneOptions struct {
 	RepackCachableOnly bool
 	RepackSmall        bool
 	RepackUncompressed bool
+
+	MaxDuration     time.Duration
+	deadline        time.Time        // time after which no further pack is repacked
+	now             func() time.Time // returns the current time for --max-duration
+	repackBatchSize uint64           // amount of data repacked before the index is saved
+
+	NonExclusive      bool
+	DeletePending     bool
+	MaxBackupDuration time.Duration
+
+	ReadCost           float64
+	WriteCost          float64
+	DeleteCost         float64
+	StorageCost        float64
+	MinStorageDuration restic.Duration
+	MaxCost            float64
 }
 
+// defaultRepackBatchSize is the amount of data repacked before the index is
+// saved if --max-duration is set.
+const defaultRepackBatchSize = 1024 * 1024 * 1024
+
 var pruneOptions PruneOptions
 
 func init() {
@@ -65,6 +88,9 @@ func init() {
 	f := cmdPrune.Flags()
 	f.BoolVarP(&pruneOptions.DryRun, "dry-run", "n", false, "do not modify the repository, just print what would be done")
 	f.StringVarP(&pruneOptions.UnsafeNoSpaceRecovery, "unsafe-recover-no-free-space", "", "", "UNSAFE, READ THE DOCUMENTATION BEFORE USING! Try to recover a repository stuck with no free space. Do not use without trying out 'prune --max-repack-size 0' first.")
+	f.BoolVar(&pruneOptions.NonExclusive, "non-exclusive", false, "only use a non-exclusive lock and mark packs for deletion instead of deleting them, see --delete-pending")
+	f.BoolVar(&pruneOptions.DeletePending, "delete-pending", false, "delete the packs marked for deletion by 'prune --non-exclusive' once --max-backup-duration has passed")
+	f.DurationVar(&pruneOptions.MaxBackupDuration, "max-backup-duration", 24*time.Hour, "longest `duration` a backup can take, packs are deleted by --delete-pending once they were marked for deletion for this long")
 	addPruneOptions(cmdPrune)
 }
 
@@ -75,6 +101,13 @@ func addPruneOptions(c *cobra.Command) {
 	f.BoolVar(&pruneOptions.RepackCachableOnly, "repack-cacheable-only", false, "only repack packs which are cacheable")
 	f.BoolVar(&pruneOptions.RepackSmall, "repack-small", false, "repack pack files below 80% of target pack size")
 	f.BoolVar(&pruneOptions.RepackUncompressed, "repack-uncompressed", false, "repack all uncompressed data")
+	f.DurationVar(&pruneOptions.MaxDuration, "max-duration", 0, "stop repacking after `duration` (eg. 2h) and continue in the next run (default: no limit)")
+	f.Float64Var(&pruneOptions.ReadCost, "read-cost", 0, "`price` per GiB read from the backend, used to estimate the cost of prune")
+	f.Float64Var(&pruneOptions.WriteCost, "write-cost", 0, "`price` per GiB written to the backend")
+	f.Float64Var(&pruneOptions.DeleteCost, "delete-cost", 0, "`price` per GiB deleted from the backend")
+	f.Float64Var(&pruneOptions.StorageCost, "storage-cost", 0, "`price` per GiB and month stored in the backend, charged for files deleted before --min-storage-duration")
+	f.Var(&pruneOptions.MinStorageDuration, "min-storage-duration", "minimum `duration` (e.g. 90d) the backend charges for storing a file")
+	f.Float64Var(&pruneOptions.MaxCost, "max-cost", 0, "stop repacking once the estimated cost reaches `price` (default: no limit)")
 }
 
 func verifyPruneOptions(opts *PruneOptions) error {
@@ -91,6 +124,35 @@ func verifyPruneOptions(opts *PruneOptions) error {
 		opts.MaxRepackBytes = 0
 	}
 
+	if opts.MaxDuration < 0 {
+		return errors.Fatal("--max-duration must not be negative")
+	}
+	if opts.now == nil {
+		opts.now = time.Now
+	}
+	if opts.repackBatchSize == 0 {
+		opts.repackBatchSize = defaultRepackBatchSize
+	}
+
+	for _, c := range []float64{opts.ReadCost, opts.WriteCost, opts.DeleteCost, opts.StorageCost, opts.MaxCost} {
+		if c < 0 {
+			return errors.Fatal("prices must not be negative")
+		}
+	}
+	if opts.MaxCost > 0 && !opts.costs().enabled() {
+		return errors.Fatal("--max-cost requires at least one of --read-cost, --write-cost, --delete-cost or --storage-cost")
+	}
+
+	if opts.MaxBackupDuration < 0 {
+		return errors.Fatal("--max-backup-duration must not be negative")
+	}
+	if opts.NonExclusive && opts.DeletePending {
+		return errors.Fatal("--non-exclusive and --delete-pending are mutually exclusive")
+	}
+	if opts.NonExclusive && opts.UnsafeNoSpaceRecovery != "" {
+		return errors.Fatal("--non-exclusive and --unsafe-recover-no-free-space are mutually exclusive")
+	}
+
 	maxUnused := strings.TrimSpace(opts.MaxUnused)
 	if maxUnused == "" {
 		return errors.Fatalf("invalid value for --max-unused: %q", opts.MaxUnused)
@@ -167,16 +229,44 @@ func runPrune(ctx context.Context, opts PruneOptions, gopts GlobalOptions) error
 		opts.unsafeRecovery = true
 	}
 
+	if opts.NonExclusive || opts.DeletePending {
+		if err := repo.Config().Supports(restic.PendingFile); err != nil {
+			return err
+		}
+	}
+
+	if opts.NonExclusive {
+		// concurrent backups are safe as packs are only deleted once they
+		// cannot be referenced by a running backup anymore. The prune lock
+		// prevents a second prune from repacking the same packs and from
+		// rewriting the index files concurrently.
+		lock, ctx, err := lockRepoPrune(ctx, repo, gopts.RetryLock, gopts.JSON)
+		defer unlockRepo(lock)
+		if err != nil {
+			return err
+		}
+		return runPruneWithRepo(ctx, opts, gopts, repo, restic.NewIDSet())
+	}
+
+	// the exclusive lock also prevents concurrent runs of --delete-pending
+	// from processing the same pending deletions
 	lock, ctx, err := lockRepoExclusive(ctx, repo, gopts.RetryLock, gopts.JSON)
 	defer unlockRepo(lock)
 	if err != nil {
 		return err
 	}
 
+	if opts.DeletePending {
+		return deletePendingPacks(ctx, opts, gopts, repo)
+	}
 	return runPruneWithRepo(ctx, opts, gopts, repo, restic.NewIDSet())
 }
 
 func runPruneWithRepo(ctx context.Context, opts PruneOptions, gopts GlobalOptions, repo *repository.Repository, ignoreSnapshots restic.IDSet) error {
+	if opts.MaxDuration > 0 {
+		opts.deadline = opts.now().Add(opts.MaxDuration)
+	}
+
 	// we do not need index updates while pruning!
 	repo.DisableAutoIndexUpdate()
 
@@ -184,14 +274,21 @@ func runPruneWithRepo(ctx context.Context, opts PruneOptions, gopts GlobalOption
 		Print("warning: running prune without a cache, this may be very slow!\n")
 	}
 
+	// load the snapshots before the index, snapshots created by concurrent
+	// backups when running without an exclusive lock may otherwise reference
+	// index files which were not loaded
+	snapshotTrees, err := loadSnapshotTrees(ctx, repo, ignoreSnapshots)
+	if err != nil {
+		return err
+	}
+
 	Verbosef("loading indexes...\n")
-	// loading the index before the snapshots is ok, as we use an exclusive lock here
-	err := repo.LoadIndex(ctx)
+	err = repo.LoadIndex(ctx)
 	if err != nil {
 		return err
 	}
 
-	plan, stats, err := planPrune(ctx, opts, repo, ignoreSnapshots, gopts.Quiet)
+	plan, stats, err := planPrune(ctx, opts, repo, snapshotTrees, gopts.Quiet)
 	if err != nil {
 		return err
 	}
@@ -200,7 +297,7 @@ func runPruneWithRepo(ctx context.Context, opts PruneOptions, gopts GlobalOption
 		Verbosef("\nWould have made the following changes:")
 	}
 
-	err = printPruneStats(stats)
+	err = printPruneStats(gopts, stats)
 	if err != nil {
 		return err
 	}
@@ -227,6 +324,12 @@ type pruneStats struct {
 		unref        uint64
 		uncompressed uint64
 	}
+	cost struct {
+		estimated bool
+		repack    float64
+		remove    float64
+		limited   uint // packs not repacked because of --max-cost
+	}
 	packs struct {
 		used       uint
 		unused     uint
@@ -241,6 +344,7 @@ type pruneStats struct {
 type prunePlan struct {
 	removePacksFirst restic.IDSet          // packs to remove first (unreferenced packs)
 	repackPacks      restic.IDSet          // packs to repack
+	repackOrder      []packInfoWithID      // packs to repack, in the order they are processed
 	keepBlobs        restic.CountedBlobSet // blobs to keep during repacking
 	removePacks      restic.IDSet          // packs to remove
 	ignorePacks      restic.IDSet          // packs to ignore when rebuilding the index
@@ -259,14 +363,15 @@ type packInfoWithID struct {
 	ID restic.ID
 	packInfo
 	mustCompress bool
+	cost         float64 // estimated cost of repacking
 }
 
 // planPrune selects which files to rewrite and which to delete and which blobs to keep.
 // Also some summary statistics are returned.
-func planPrune(ctx context.Context, opts PruneOptions, repo restic.Repository, ignoreSnapshots restic.IDSet, quiet bool) (prunePlan, pruneStats, error) {
+func planPrune(ctx context.Context, opts PruneOptions, repo restic.Repository, snapshotTrees restic.IDs, quiet bool) (prunePlan, pruneStats, error) {
 	var stats pruneStats
 
-	usedBlobs, err := getUsedBlobs(ctx, repo, ignoreSnapshots, quiet)
+	usedBlobs, err := getUsedBlobs(ctx, repo, snapshotTrees, quiet)
 	if err != nil {
 		return prunePlan{}, stats, err
 	}
@@ -457,10 +562,73 @@ func packInfoFromIndex(ctx context.Context, idx restic.MasterIndex, usedBlobs re
 	return usedBlobs, indexPack, nil
 }
 
+// pruneCosts estimates what modifying pack files costs on backends which charge
+// for reading, writing or deleting data, or for data deleted before a minimum
+// storage duration has passed.
+type pruneCosts struct {
+	read, write, delete float64 // price per GiB
+	storage             float64 // price per GiB and month
+	minStorage          restic.Duration
+	now                 time.Time
+}
+
+// hoursPerMonth is the length of a month used by backends to charge storage.
+const hoursPerMonth = 30 * 24
+
+func (opts PruneOptions) costs() pruneCosts {
+	return pruneCosts{
+		read:       opts.ReadCost,
+		write:      opts.WriteCost,
+		delete:     opts.DeleteCost,
+		storage:    opts.StorageCost,
+		minStorage: opts.MinStorageDuration,
+		now:        time.Now(),
+	}
+}
+
+// enabled returns true if any price is set.
+func (c pruneCosts) enabled() bool {
+	return c.read > 0 || c.write > 0 || c.delete > 0 || c.storage > 0
+}
+
+func gib(size uint64) float64 {
+	return float64(size) / (1 << 30)
+}
+
+// repack returns the cost of repacking p, which was created at the given
+// time: the pack is downloaded, its used blobs are uploaded again and the pack
+// is deleted.
+func (c pruneCosts) repack(p packInfoWithID, created time.Time) float64 {
+	size := p.usedSize + p.unusedSize
+	return c.remove(size, created) + gib(size)*c.read + gib(p.usedSize)*c.write
+}
+
+// remove returns the cost of deleting a pack with the given size and creation
+// time, including the storage charged for the remainder of the minimum storage
+// duration. A zero creation time means the backend did not report it.
+func (c pruneCosts) remove(size uint64, created time.Time) float64 {
+	cost := gib(size) * c.delete
+	if c.storage == 0 || c.minStorage.Zero() {
+		return cost
+	}
+
+	if created.IsZero() {
+		// assume the worst case if the backend does not report when the pack was created
+		created = c.now
+	}
+	d := c.minStorage
+	end := created.AddDate(d.Years, d.Months, d.Days).Add(time.Duration(d.Hours) * time.Hour)
+	if remaining := end.Sub(c.now); remaining > 0 {
+		cost += gib(size) * c.storage * remaining.Hours() / hoursPerMonth
+	}
+	return cost
+}
+
 func decidePackAction(ctx context.Context, opts PruneOptions, repo restic.Repository, indexPack map[restic.ID]packInfo, stats *pruneStats, quiet bool) (prunePlan, error) {
 	removePacksFirst := restic.NewIDSet()
 	removePacks := restic.NewIDSet()
 	repackPacks := restic.NewIDSet()
+	var repackOrder []packInfoWithID
 
 	var repackCandidates []packInfoWithID
 	var repackSmallCandidates []packInfoWithID
@@ -472,15 +640,34 @@ func decidePackAction(ctx context.Context, opts PruneOptions, repo restic.Reposi
 		targetPackSize = repo.PackSize() / 5 * 4
 	}
 
+	costs := opts.costs()
+	stats.cost.estimated = costs.enabled()
+	candidate := func(id restic.ID, p packInfo, mustCompress bool, created time.Time) packInfoWithID {
+		c := packInfoWithID{ID: id, packInfo: p, mustCompress: mustCompress}
+		c.cost = costs.repack(c, created)
+		return c
+	}
+
 	// loop over all packs and decide what to do
 	bar := newProgressMax(!quiet, uint64(len(indexPack)), "packs processed")
-	err := repo.List(ctx, restic.PackFile, func(id restic.ID, packSize int64) error {
+	err := repo.Backend().List(ctx, restic.PackFile, func(fi restic.FileInfo) error {
+		id, err := restic.ParseID(fi.Name)
+		if err != nil {
+			debug.Log("unable to parse %v as an ID", fi.Name)
+			return nil
+		}
+		packSize := fi.Size
+		// most backends report in the listing when a pack was created, which is
+		// used to estimate the cost of deleting it before the minimum storage duration
+		created := fi.ModTime
+
 		p, ok := indexPack[id]
 		if !ok {
 			// Pack was not referenced in index and is not used  => immediately remove!
 			Verboseff("will remove pack %v as it is unused and not indexed\n", id.Str())
 			removePacksFirst.Insert(id)
 			stats.size.unref += uint64(packSize)
+			stats.cost.remove += costs.remove(uint64(packSize), created)
 			return nil
 		}
 
@@ -520,6 +707,7 @@ func decidePackAction(ctx context.Context, opts PruneOptions, repo restic.Reposi
 			removePacks.Insert(id)
 			stats.blobs.remove += p.unusedBlobs
 			stats.size.remove += p.unusedSize
+			stats.cost.remove += costs.remove(uint64(packSize), created)
 
 		case opts.RepackCachableOnly && p.tpe == restic.DataBlob:
 			// if this is a data pack and --repack-cacheable-only is set => keep pack!
@@ -530,12 +718,12 @@ func decidePackAction(ctx context.Context, opts PruneOptions, repo restic.Reposi
 				// All blobs in pack are used and not mixed => keep pack!
 				stats.packs.keep++
 			} else {
-				repackSmallCandidates = append(repackSmallCandidates, packInfoWithID{ID: id, packInfo: p, mustCompress: mustCompress})
+				repackSmallCandidates = append(repackSmallCandidates, candidate(id, p, mustCompress, created))
 			}
 
 		default:
 			// all other packs are candidates for repacking
-			repackCandidates = append(repackCandidates, packInfoWithID{ID: id, packInfo: p, mustCompress: mustCompress})
+			repackCandidates = append(repackCandidates, candidate(id, p, mustCompress, created))
 		}
 
 		delete(indexPack, id)
@@ -586,7 +774,8 @@ func decidePackAction(ctx context.Context, opts PruneOptions, repo restic.Reposi
 	// This is equivalent to sorting by unused / total space.
 	// Instead of unused[i] / used[i] > unused[j] / used[j] we use
 	// unused[i] * used[j] > unused[j] * used[i] as uint32*uint32 < uint64
-	// Moreover packs containing trees and too small packs are sorted to the beginning
+	// Moreover packs containing trees and too small packs are sorted to the beginning.
+	// If prices are given, packs which reclaim the most space per cost are picked first.
 	sort.Slice(repackCandidates, func(i, j int) bool {
 		pi := repackCandidates[i].packInfo
 		pj := repackCandidates[j].packInfo
@@ -600,11 +789,17 @@ func decidePackAction(ctx context.Context, opts PruneOptions, repo restic.Reposi
 		case pj.unusedSize+pj.usedSize < uint64(targetPackSize) && pi.unusedSize+pi.usedSize >= uint64(targetPackSize):
 			return false
 		}
+		// instead of unused[i] / cost[i] > unused[j] / cost[j], which also handles zero costs
+		if ri, rj := float64(pi.unusedSize)*repackCandidates[j].cost, float64(pj.unusedSize)*repackCandidates[i].cost; ri != rj {
+			return ri > rj
+		}
 		return pi.unusedSize*pj.usedSize > pj.unusedSize*pi.usedSize
 	})
 
-	repack := func(id restic.ID, p packInfo) {
-		repackPacks.Insert(id)
+	repack := func(p packInfoWithID) {
+		repackPacks.Insert(p.ID)
+		repackOrder = append(repackOrder, p)
+		stats.cost.repack += p.cost
 		stats.blobs.repack += p.unusedBlobs + p.usedBlobs
 		stats.size.repack += p.unusedSize + p.usedSize
 		stats.blobs.repackrm += p.unusedBlobs
@@ -620,22 +815,27 @@ func decidePackAction(ctx context.Context, opts PruneOptions, repo restic.Reposi
 	for _, p := range repackCandidates {
 		reachedUnusedSizeAfter := (stats.size.unused-stats.size.remove-stats.size.repackrm < maxUnusedSizeAfter)
 		reachedRepackSize := stats.size.repack+p.unusedSize+p.usedSize >= opts.MaxRepackBytes
+		reachedCost := opts.MaxCost > 0 && stats.cost.repack+p.cost > opts.MaxCost
 		packIsLargeEnough := p.unusedSize+p.usedSize >= uint64(targetPackSize)
 
 		switch {
 		case reachedRepackSize:
 			stats.packs.keep++
 
+		case reachedCost:
+			stats.packs.keep++
+			stats.cost.limited++
+
 		case p.tpe != restic.DataBlob, p.mustCompress:
-			// repacking non-data packs / uncompressed-trees is only limited by repackSize
-			repack(p.ID, p.packInfo)
+			// repacking non-data packs / uncompressed-trees is only limited by repackSize and the cost
+			repack(p)
 
 		case reachedUnusedSizeAfter && packIsLargeEnough:
 			// for all other packs stop repacking if tolerated unused size is reached.
 			stats.packs.keep++
 
 		default:
-			repack(p.ID, p.packInfo)
+			repack(p)
 		}
 	}
 
@@ -651,12 +851,23 @@ func decidePackAction(ctx context.Context, opts PruneOptions, repo restic.Reposi
 	return prunePlan{removePacksFirst: removePacksFirst,
 		removePacks: removePacks,
 		repackPacks: repackPacks,
+		repackOrder: repackOrder,
 		ignorePacks: ignorePacks,
 	}, nil
 }
 
+// pruneCostEstimate is printed when JSON output is requested and prices are
+// given.
+type pruneCostEstimate struct {
+	MessageType  string  `json:"message_type"` // "cost_estimate"
+	TotalCost    float64 `json:"total_cost"`
+	RepackCost   float64 `json:"repack_cost"`
+	DeleteCost   float64 `json:"delete_cost"`
+	PacksLimited uint    `json:"packs_limited_by_max_cost"`
+}
+
 // printPruneStats prints out the statistics
-func printPruneStats(stats pruneStats) error {
+func printPruneStats(gopts GlobalOptions, stats pruneStats) error {
 	Verboseff("\nused:         %10d blobs / %s\n", stats.blobs.used, ui.FormatBytes(stats.size.used))
 	if stats.blobs.duplicate > 0 {
 		Verboseff("duplicates:   %10d blobs / %s\n", stats.blobs.duplicate, ui.FormatBytes(stats.size.duplicate))
@@ -683,6 +894,28 @@ func printPruneStats(stats pruneStats) error {
 	unusedAfter := unusedSize - stats.size.remove - stats.size.repackrm
 	Verbosef("unused size after prune: %s (%s of remaining size)\n",
 		ui.FormatBytes(unusedAfter), ui.FormatPercent(unusedAfter, totalSize-totalPruneSize))
+	if stats.cost.estimated {
+		// the estimate was explicitly requested by giving prices, thus print it
+		// regardless of the verbosity
+		if gopts.JSON {
+			err := json.NewEncoder(globalOptions.stdout).Encode(pruneCostEstimate{
+				MessageType:  "cost_estimate",
+				TotalCost:    stats.cost.repack + stats.cost.remove,
+				RepackCost:   stats.cost.repack,
+				DeleteCost:   stats.cost.remove,
+				PacksLimited: stats.cost.limited,
+			})
+			if err != nil {
+				return err
+			}
+		} else {
+			Printf("estimated cost: %.2f (repacking %.2f, deleting %.2f)\n",
+				stats.cost.repack+stats.cost.remove, stats.cost.repack, stats.cost.remove)
+			if stats.cost.limited > 0 {
+				Printf("not repacking %d packs as this would exceed --max-cost\n", stats.cost.limited)
+			}
+		}
+	}
 	Verbosef("\n")
 	Verboseff("totally used packs: %10d\n", stats.packs.used)
 	Verboseff("partly used packs:  %10d\n", stats.packs.partlyUsed)
@@ -717,8 +950,9 @@ func doPrune(ctx context.Context, opts PruneOptions, gopts GlobalOptions, repo r
 		return nil
 	}
 
-	// unreferenced packs can be safely deleted first
-	if len(plan.removePacksFirst) != 0 {
+	// unreferenced packs can be safely deleted first. Without an exclusive
+	// lock, they might belong to a backup which has not yet saved its index.
+	if len(plan.removePacksFirst) != 0 && !opts.NonExclusive {
 		Verbosef("deleting unreferenced packs\n")
 		DeleteFiles(ctx, gopts, repo, plan.removePacksFirst, restic.PackFile)
 	}
@@ -726,14 +960,32 @@ func doPrune(ctx context.Context, opts PruneOptions, gopts GlobalOptions, repo r
 	if len(plan.repackPacks) != 0 {
 		Verbosef("repacking packs\n")
 		bar := newProgressMax(!gopts.Quiet, uint64(len(plan.repackPacks)), "packs repacked")
-		_, err := repository.Repack(ctx, repo, repo, plan.repackPacks, plan.keepBlobs, bar)
+		repacked, err := repackInBatches(ctx, opts, repo, plan, bar)
 		bar.Done()
 		if err != nil {
 			return errors.Fatal(err.Error())
 		}
 
+		// blobs which are still needed must have been repacked or must be
+		// contained in a pack which was not yet repacked
+		remaining := restic.NewIDSet()
+		for id := range plan.repackPacks {
+			if !repacked.Has(id) {
+				remaining.Insert(id)
+			}
+		}
+		if len(remaining) != 0 {
+			Verbosef("time limit of %v reached, repacked %d of %d packs, run prune again to repack the remaining packs\n",
+				opts.MaxDuration, len(repacked), len(plan.repackPacks))
+			repo.Index().Each(ctx, func(blob restic.PackedBlob) {
+				if remaining.Has(blob.PackID) {
+					plan.keepBlobs.Delete(blob.BlobHandle)
+				}
+			})
+		}
+
 		// Also remove repacked packs
-		plan.removePacks.Merge(plan.repackPacks)
+		plan.removePacks.Merge(repacked)
 
 		if len(plan.keepBlobs) != 0 {
 			Warnf("%v was not repacked\n\n"+
@@ -767,7 +1019,19 @@ func doPrune(ctx context.Context, opts PruneOptions, gopts GlobalOptions, repo r
 		}
 	}
 
-	if len(plan.removePacks) != 0 {
+	if opts.NonExclusive {
+		pending := restic.NewIDSet()
+		pending.Merge(plan.removePacksFirst)
+		pending.Merge(plan.removePacks)
+		if len(pending) != 0 {
+			Verbosef("marking %d packs for deletion\n", len(pending))
+			_, err = restic.SavePendingDeletion(ctx, repo, restic.NewPendingDeletion(pending))
+			if err != nil {
+				return errors.Fatalf("unable to save pending deletion: %v", err)
+			}
+			Verbosef("run 'prune --delete-pending' after all backups which are running now have finished to delete them\n")
+		}
+	} else if len(plan.removePacks) != 0 {
 		Verbosef("removing %d old packs\n", len(plan.removePacks))
 		DeleteFiles(ctx, gopts, repo, plan.removePacks, restic.PackFile)
 	}
@@ -783,6 +1047,54 @@ func doPrune(ctx context.Context, opts PruneOptions, gopts GlobalOptions, repo r
 	return nil
 }
 
+// repackInBatches repacks the packs from plan.repackOrder and returns the
+// repacked packs. If opts.deadline is set, the packs are repacked in batches
+// in the order chosen by decidePackAction, and no further pack is loaded once
+// the deadline has passed. The index for the new packs is saved after each
+// batch, such that the repository remains consistent if prune is interrupted.
+func repackInBatches(ctx context.Context, opts PruneOptions, repo restic.Repository, plan prunePlan, bar *progress.Counter) (restic.IDSet, error) {
+	if opts.deadline.IsZero() {
+		_, err := repository.Repack(ctx, repo, repo, plan.repackPacks, plan.keepBlobs, bar)
+		return plan.repackPacks, err
+	}
+
+	deadlineReached := func() bool {
+		return opts.now().After(opts.deadline)
+	}
+
+	repacked := restic.NewIDSet()
+	order := plan.repackOrder
+	for len(order) > 0 {
+		batch := restic.NewIDSet()
+		var size uint64
+		for len(order) > 0 && (len(batch) == 0 || size < opts.repackBatchSize) {
+			p := order[0]
+			batch.Insert(p.ID)
+			size += p.usedSize + p.unusedSize
+			order = order[1:]
+		}
+
+		debug.Log("repacking batch of %d packs, %d bytes", len(batch), size)
+		done, err := repository.RepackUntil(ctx, repo, repo, batch, plan.keepBlobs, bar, deadlineReached)
+		if err != nil {
+			return nil, err
+		}
+		if len(done) != 0 {
+			err = repo.Index().(*index.MasterIndex).SaveIndex(ctx, repo)
+			if err != nil {
+				return nil, err
+			}
+			repacked.Merge(done)
+		}
+		if len(done) < len(batch) {
+			debug.Log("deadline reached, %d packs left to repack", len(batch)-len(done)+len(order))
+			break
+		}
+	}
+
+	return repacked, nil
+}
+
 func writeIndexFiles(ctx context.Context, gopts GlobalOptions, repo restic.Repository, removePacks restic.IDSet, extraObsolete restic.IDs) (restic.IDSet, error) {
 	Verbosef("rebuilding index\n")
 
@@ -802,11 +1114,19 @@ func rebuildIndexFiles(ctx context.Context, gopts GlobalOptions, repo restic.Rep
 	return DeleteFilesChecked(ctx, gopts, repo, obsoleteIndexes, restic.IndexFile)
 }
 
-func getUsedBlobs(ctx context.Context, repo restic.Repository, ignoreSnapshots restic.IDSet, quiet bool) (usedBlobs restic.CountedBlobSet, err error) {
-	var snapshotTrees restic.IDs
+// loadSnapshotTrees returns the trees of all snapshots except those in
+// ignoreSnapshots which are not on hold.
+func loadSnapshotTrees(ctx context.Context, repo restic.Repository, ignoreSnapshots restic.IDSet) (snapshotTrees restic.IDs, err error) {
 	Verbosef("loading all snapshots...\n")
-	err = restic.ForAllSnapshots(ctx, repo.Backend(), repo, ignoreSnapshots,
+	err = restic.ForAllSnapshots(ctx, repo.Backend(), repo, nil,
 		func(id restic.ID, sn *restic.Snapshot, err error) error {
+			if ignoreSnapshots.Has(id) {
+				// the data of snapshots on hold must never be removed
+				if err != nil || !sn.Hold {
+					return nil
+				}
+				Warnf("snapshot %v is on hold, keeping its data\n", id.Str())
+			}
 			if err != nil {
 				debug.Log("failed to load snapshot %v (error %v)", id, err)
 				return err
@@ -818,7 +1138,11 @@ func getUsedBlobs(ctx context.Context, repo restic.Repository, ignoreSnapshots r
 	if err != nil {
 		return nil, errors.Fatalf("failed loading snapshot: %v", err)
 	}
+	return snapshotTrees, nil
+}
 
+// getUsedBlobs returns all blobs referenced by the given snapshot trees.
+func getUsedBlobs(ctx context.Context, repo restic.Repository, snapshotTrees restic.IDs, quiet bool) (usedBlobs restic.CountedBlobSet, err error) {
 	Verbosef("finding data that is still in use for %d snapshots\n", len(snapshotTrees))
 
 	usedBlobs = restic.NewCountedBlobSet()
@@ -836,3 +1160,177 @@ func getUsedBlobs(ctx context.Context, repo restic.Repository, ignoreSnapshots r
 	}
 	return usedBlobs, nil
 }
+
+// deletePendingPacks deletes the packs marked for deletion by 'prune
+// --non-exclusive' at least opts.MaxBackupDuration ago. Packs which are
+// referenced by an index again are no longer pending. Packs which contain data
+// used by a snapshot, which was created by a backup running concurrently with
+// prune, are added back to the index.
+func deletePendingPacks(ctx context.Context, opts PruneOptions, gopts GlobalOptions, repo *repository.Repository) error {
+	// we do not need index updates while pruning!
+	repo.DisableAutoIndexUpdate()
+
+	Verbosef("loading pending deletions...\n")
+	cutoff := time.Now().Add(-opts.MaxBackupDuration)
+	records := restic.NewIDSet()
+	pending := restic.NewIDSet()
+	var oldest time.Time
+	notDue := 0
+	err := restic.ForAllPendingDeletions(ctx, repo.Backend(), repo, func(id restic.ID, p *restic.PendingDeletion, err error) error {
+		if err != nil {
+			return err
+		}
+		if p.Time.After(cutoff) {
+			notDue++
+			return nil
+		}
+		records.Insert(id)
+		pending.Merge(restic.NewIDSet(p.Packs...))
+		if oldest.IsZero() || p.Time.Before(oldest) {
+			oldest = p.Time
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Fatalf("failed loading pending deletions: %v", err)
+	}
+	if notDue > 0 {
+		Verbosef("%d pending deletions are younger than %v\n", notDue, opts.MaxBackupDuration)
+	}
+	if len(records) == 0 {
+		Verbosef("no packs to delete\n")
+		return nil
+	}
+
+	// snapshots must be loaded before the index, as snapshots created
+	// afterwards may reference index files we do not know about
+	snapshotTrees, err := loadSnapshotTrees(ctx, repo, restic.NewIDSet())
+	if err != nil {
+		return err
+	}
+
+	Verbosef("loading indexes...\n")
+	err = repo.LoadIndex(ctx)
+	if err != nil {
+		return err
+	}
+
+	mi := repo.Index().(*index.MasterIndex)
+	indexed := 0
+	for id := range mi.Packs(restic.NewIDSet()) {
+		if pending.Has(id) {
+			pending.Delete(id)
+			indexed++
+		}
+	}
+
+	packSizes := make(map[restic.ID]int64)
+	err = repo.List(ctx, restic.PackFile, func(id restic.ID, size int64) error {
+		if pending.Has(id) {
+			packSizes[id] = size
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for id := range pending {
+		if _, ok := packSizes[id]; !ok {
+			// already deleted
+			pending.Delete(id)
+		}
+	}
+
+	// a backup which started before the packs were removed from the index
+	// may have deduplicated its data against them. Add the pending packs to
+	// the in-memory index, such that all snapshots can be loaded.
+	keep := restic.NewIDSet()
+	if len(pending) != 0 {
+		Verbosef("reading headers of %d pending packs\n", len(pending))
+		bar := newProgressMax(!gopts.Quiet, uint64(len(pending)), "packs")
+		invalid, err := repo.CreateIndexFromPacks(ctx, packSizes, bar)
+		bar.Done()
+		if err != nil {
+			return err
+		}
+		for _, id := range invalid {
+			Warnf("unable to read header of pending pack %v, keeping it\n", id.Str())
+			pending.Delete(id)
+			keep.Insert(id)
+		}
+	}
+
+	usedBlobs, err := getUsedBlobs(ctx, repo, snapshotTrees, gopts.Quiet)
+	if err != nil {
+		return err
+	}
+
+	restore := restic.NewIDSet()
+	for bh := range usedBlobs {
+		var packs restic.IDs
+		found := false
+		for _, pb := range mi.Lookup(bh) {
+			if !pending.Has(pb.PackID) && !keep.Has(pb.PackID) {
+				found = true
+				break
+			}
+			packs = append(packs, pb.PackID)
+		}
+		switch {
+		case found:
+		case len(packs) == 0:
+			Warnf("blob %v used by a snapshot is missing from the repository\n", bh)
+			return errorIndexIncomplete
+		default:
+			restore.Insert(packs[0])
+		}
+	}
+
+	for id := range pending {
+		if restore.Has(id) {
+			pending.Delete(id)
+		}
+	}
+
+	if opts.DryRun {
+		Verbosef("\nWould have made the following changes:\n")
+		Verbosef("would add %d packs which are still in use to the index\n", len(restore))
+		Verbosef("would delete %d packs, %d packs are referenced by an index again\n", len(pending), indexed)
+		if !gopts.JSON && gopts.verbosity >= 2 {
+			Printf("Would have removed the following pending packs:\n%v\n\n", pending)
+		}
+		return nil
+	}
+
+	if len(restore) != 0 {
+		Verbosef("adding %d packs which are still in use to the index\n", len(restore))
+		idx := index.NewIndex()
+		for pb := range mi.ListPacks(ctx, restore) {
+			idx.StorePack(pb.PackID, pb.Blobs)
+		}
+		idx.Finalize()
+		_, err = index.SaveIndex(ctx, repo, idx)
+		if err != nil {
+			return errors.Fatalf("%s", err)
+		}
+	}
+
+	if len(pending) != 0 {
+		Verbosef("removing %d pending packs\n", len(pending))
+		DeleteFiles(ctx, gopts, repo, pending, restic.PackFile)
+	}
+
+	if len(keep) != 0 {
+		Verbosef("keeping %d packs marked for deletion\n", len(keep))
+		p := restic.NewPendingDeletion(keep)
+		p.Time = oldest
+		_, err = restic.SavePendingDeletion(ctx, repo, p)
+		if err != nil {
+			return errors.Fatalf("unable to save pending deletion: %v", err)
+		}
+	}
+	DeleteFiles(ctx, gopts, repo, records, restic.PendingFile)
+
+	Verbosef("done\n")
+	return nil
+}
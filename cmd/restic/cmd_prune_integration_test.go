@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/restic/restic/internal/repository"
 	"github.com/restic/restic/internal/restic"
 	rtest "github.com/restic/restic/internal/test"
 )
@@ -107,6 +110,248 @@ func testPrune(t *testing.T, pruneOpts PruneOptions, checkOpts CheckOptions) {
 
 var pruneDefaultOptions = PruneOptions{MaxUnused: "5%"}
 
+func TestPruneMaxDuration(t *testing.T) {
+	t.Run("Finished", func(t *testing.T) {
+		// repack each pack in a separate batch
+		opts := PruneOptions{MaxUnused: "0%", MaxDuration: time.Hour, repackBatchSize: 1}
+		checkOpts := CheckOptions{ReadData: true, CheckUnused: true}
+		testPrune(t, opts, checkOpts)
+	})
+
+	t.Run("Partial", func(t *testing.T) {
+		testPruneMaxDurationPartial(t, PruneOptions{MaxUnused: "0%"})
+	})
+
+	t.Run("PartialWithPrices", func(t *testing.T) {
+		testPruneMaxDurationPartial(t, PruneOptions{MaxUnused: "0%", ReadCost: 0.03, WriteCost: 0.3, DeleteCost: 0.01})
+	})
+}
+
+// testPruneMaxDurationPartial checks that prune with a time limit repacks the
+// packs in the order chosen by the planner and continues in the next run.
+func testPruneMaxDurationPartial(t *testing.T, opts PruneOptions) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	// every call advances the clock by one minute, the deadline is computed
+	// by the first call and checked before each pack is repacked
+	start := time.Now()
+	calls := 0
+	opts.now = func() time.Time {
+		calls++
+		return start.Add(time.Duration(calls) * time.Minute)
+	}
+	// repack each pack in a separate batch
+	opts.repackBatchSize = 1
+
+	// create several packs which are only partially used
+	testSetupBackupData(t, env)
+	dir := filepath.Join(env.testdata, "0", "0", "9")
+	for _, files := range [][]string{{"0", "1"}, {"2", "3"}, {"4", "5"}} {
+		var targets []string
+		for _, f := range files {
+			targets = append(targets, filepath.Join(dir, f))
+		}
+		testRunBackup(t, "", targets, BackupOptions{}, env.gopts)
+	}
+	var removeSnapshots []string
+	for _, id := range testListSnapshots(t, env.gopts, 3) {
+		removeSnapshots = append(removeSnapshots, id.String())
+	}
+	testRunBackup(t, "", []string{filepath.Join(dir, "0"), filepath.Join(dir, "2"), filepath.Join(dir, "4")}, BackupOptions{}, env.gopts)
+	testRunForget(t, env.gopts, removeSnapshots...)
+
+	order := testPlanRepackOrder(t, env.gopts, opts)
+	rtest.Assert(t, len(order) > 1, "expected several packs to repack, got %v", len(order))
+
+	// the time limit is exhausted before repacking starts
+	calls = 0
+	opts.MaxDuration = 30 * time.Second
+	testRunPrune(t, env.gopts, opts)
+	rtest.OK(t, runCheck(context.TODO(), CheckOptions{ReadData: true}, env.gopts, nil))
+	packs := listPacks(env.gopts, t)
+
+	// the time limit allows repacking a single pack, which must be the
+	// first one chosen by the planner
+	calls = 0
+	opts.MaxDuration = 90 * time.Second
+	testRunPrune(t, env.gopts, opts)
+	rtest.OK(t, runCheck(context.TODO(), CheckOptions{ReadData: true}, env.gopts, nil))
+	rtest.Assert(t, runCheck(context.TODO(), CheckOptions{CheckUnused: true}, env.gopts, nil) != nil,
+		"expected unused blobs to remain after the time limit was reached")
+	remaining := listPacks(env.gopts, t)
+	var repacked restic.IDs
+	for id := range packs {
+		if !remaining.Has(id) {
+			repacked = append(repacked, id)
+		}
+	}
+	rtest.Equals(t, restic.IDs{order[0].ID}, repacked)
+
+	// the next run continues where the previous one stopped
+	calls = 0
+	opts.MaxDuration = time.Hour
+	testRunPrune(t, env.gopts, opts)
+	rtest.OK(t, runCheck(context.TODO(), CheckOptions{ReadData: true, CheckUnused: true}, env.gopts, nil))
+}
+
+// testPlanRepackOrder returns the packs prune would repack, in the order they
+// are processed.
+func testPlanRepackOrder(t testing.TB, gopts GlobalOptions, opts PruneOptions) []packInfoWithID {
+	ctx := context.TODO()
+	rtest.OK(t, verifyPruneOptions(&opts))
+	repo, err := OpenRepository(ctx, gopts)
+	rtest.OK(t, err)
+	snapshotTrees, err := loadSnapshotTrees(ctx, repo, restic.NewIDSet())
+	rtest.OK(t, err)
+	rtest.OK(t, repo.LoadIndex(ctx))
+	plan, _, err := planPrune(ctx, opts, repo, snapshotTrees, true)
+	rtest.OK(t, err)
+
+	if opts.costs().enabled() {
+		// packs which reclaim the most space per cost come first
+		for i := 1; i < len(plan.repackOrder); i++ {
+			prev, cur := plan.repackOrder[i-1], plan.repackOrder[i]
+			if prev.tpe != restic.DataBlob || cur.tpe != restic.DataBlob {
+				continue
+			}
+			rtest.Assert(t, float64(prev.unusedSize)*cur.cost >= float64(cur.unusedSize)*prev.cost,
+				"pack %v reclaims less space per cost than pack %v", prev.ID.Str(), cur.ID.Str())
+		}
+	}
+	return plan.repackOrder
+}
+
+func TestPruneCostLimit(t *testing.T) {
+	t.Run("Unlimited", func(t *testing.T) {
+		opts := PruneOptions{MaxUnused: "0%", ReadCost: 0.01, WriteCost: 0.01, DeleteCost: 0.01}
+		checkOpts := CheckOptions{ReadData: true, CheckUnused: true}
+		testPrune(t, opts, checkOpts)
+	})
+
+	t.Run("Exhausted", func(t *testing.T) {
+		env, cleanup := withTestEnvironment(t)
+		defer cleanup()
+
+		createPrunableRepo(t, env)
+		// the budget does not suffice to repack a single pack
+		env.gopts.JSON = true
+		buf, err := withCaptureStdout(func() error {
+			testRunPrune(t, env.gopts, PruneOptions{MaxUnused: "0%", ReadCost: 1000, MaxCost: 1e-9})
+			return nil
+		})
+		rtest.OK(t, err)
+		env.gopts.JSON = false
+
+		var estimate *pruneCostEstimate
+		for _, line := range strings.Split(buf.String(), "\n") {
+			var msg pruneCostEstimate
+			if json.Unmarshal([]byte(line), &msg) == nil && msg.MessageType == "cost_estimate" {
+				estimate = &msg
+			}
+		}
+		rtest.Assert(t, estimate != nil, "cost estimate missing from JSON output %q", buf.String())
+		rtest.Equals(t, 0.0, estimate.RepackCost)
+		rtest.Assert(t, estimate.PacksLimited > 0, "expected packs to be limited by --max-cost, got %v", estimate.PacksLimited)
+		rtest.OK(t, runCheck(context.TODO(), CheckOptions{ReadData: true}, env.gopts, nil))
+		rtest.Assert(t, runCheck(context.TODO(), CheckOptions{CheckUnused: true}, env.gopts, nil) != nil,
+			"expected unused blobs to remain if the cost limit is exhausted")
+	})
+}
+
+func TestPruneNonExclusive(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	createPrunableRepo(t, env)
+	packs := listPacks(env.gopts, t)
+
+	// only one non-exclusive prune can run at a time
+	repo, err := OpenRepository(context.TODO(), env.gopts)
+	rtest.OK(t, err)
+	lock, err := restic.NewPruneLock(context.TODO(), repo)
+	rtest.OK(t, err)
+	err = runPrune(context.TODO(), PruneOptions{MaxUnused: "0%", NonExclusive: true}, env.gopts)
+	rtest.Assert(t, err != nil, "prune --non-exclusive succeeded while another one was running")
+	rtest.Assert(t, strings.Contains(err.Error(), "locked for pruning"), "unexpected error %v", err)
+	rtest.Equals(t, 0, len(testRunList(t, "pending", env.gopts)))
+	// other commands can still acquire a non-exclusive lock
+	otherLock, err := restic.NewLock(context.TODO(), repo)
+	rtest.OK(t, err)
+	rtest.OK(t, otherLock.Unlock())
+	rtest.OK(t, lock.Unlock())
+
+	// packs are removed from the index but not deleted
+	testRunPrune(t, env.gopts, PruneOptions{MaxUnused: "0%", NonExclusive: true})
+	rtest.Equals(t, 1, len(testRunList(t, "pending", env.gopts)))
+	remaining := listPacks(env.gopts, t)
+	for id := range packs {
+		rtest.Assert(t, remaining.Has(id), "pack %v was deleted", id.Str())
+	}
+	rtest.OK(t, runCheck(context.TODO(), CheckOptions{ReadData: true}, env.gopts, nil))
+
+	// the packs must not be deleted before a backup could have finished
+	testRunPrune(t, env.gopts, PruneOptions{MaxUnused: "5%", DeletePending: true, MaxBackupDuration: time.Hour})
+	rtest.Equals(t, len(remaining), len(listPacks(env.gopts, t)))
+	rtest.Equals(t, 1, len(testRunList(t, "pending", env.gopts)))
+
+	// deleting the packs requires an exclusive lock
+	lock, err = restic.NewLock(context.TODO(), repo)
+	rtest.OK(t, err)
+	err = runPrune(context.TODO(), PruneOptions{MaxUnused: "5%", DeletePending: true}, env.gopts)
+	rtest.Assert(t, err != nil, "prune --delete-pending succeeded while the repository was locked")
+	rtest.OK(t, lock.Unlock())
+
+	testRunPrune(t, env.gopts, PruneOptions{MaxUnused: "5%", DeletePending: true})
+	rtest.Equals(t, 0, len(testRunList(t, "pending", env.gopts)))
+	rtest.Assert(t, len(listPacks(env.gopts, t)) < len(remaining), "no packs were deleted")
+	rtest.OK(t, runCheck(context.TODO(), CheckOptions{ReadData: true, CheckUnused: true}, env.gopts, nil))
+}
+
+func TestPruneNonExclusiveRepoVersion(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	repository.TestUseLowSecurityKDFParameters(t)
+	restic.TestDisableCheckPolynomial(t)
+	restic.TestSetLockTimeout(t, 0)
+	rtest.OK(t, runInit(context.TODO(), InitOptions{RepositoryVersion: "2"}, env.gopts, nil))
+	for _, opts := range []PruneOptions{{NonExclusive: true}, {DeletePending: true}} {
+		opts.MaxUnused = "5%"
+		err := runPrune(context.TODO(), opts, env.gopts)
+		rtest.Assert(t, err != nil, "prune with %+v succeeded for repository version 2", opts)
+	}
+}
+
+func TestPruneNonExclusiveConcurrentBackup(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	opts := BackupOptions{}
+	testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9", "2")}, opts, env.gopts)
+	testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9", "3")}, opts, env.gopts)
+	forgotten := testListSnapshots(t, env.gopts, 2)[0]
+
+	repo, err := OpenRepository(context.TODO(), env.gopts)
+	rtest.OK(t, err)
+	buf, err := repo.LoadUnpacked(context.TODO(), restic.SnapshotFile, forgotten)
+	rtest.OK(t, err)
+
+	testRunForget(t, env.gopts, forgotten.String())
+	testRunPrune(t, env.gopts, PruneOptions{MaxUnused: "0%", NonExclusive: true})
+
+	// a backup which loaded the index before prune creates a snapshot which
+	// references data that is only contained in pending packs
+	_, err = repo.SaveUnpacked(context.TODO(), restic.SnapshotFile, buf)
+	rtest.OK(t, err)
+	testListSnapshots(t, env.gopts, 2)
+
+	testRunPrune(t, env.gopts, PruneOptions{MaxUnused: "5%", DeletePending: true})
+	rtest.Equals(t, 0, len(testRunList(t, "pending", env.gopts)))
+	rtest.OK(t, runCheck(context.TODO(), CheckOptions{ReadData: true, CheckUnused: true}, env.gopts, nil))
+}
+
 func TestPruneWithDamagedRepository(t *testing.T) {
 	env, cleanup := withTestEnvironment(t)
 	defer cleanup()
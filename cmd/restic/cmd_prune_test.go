@@ -0,0 +1,77 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/restic/restic/internal/restic"
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func TestPruneCostOptions(t *testing.T) {
+	testCases := []struct {
+		input    PruneOptions
+		errorMsg string
+	}{
+		{PruneOptions{ReadCost: 0.01, MaxCost: 1}, ""},
+		{PruneOptions{StorageCost: 0.004, MinStorageDuration: restic.Duration{Days: 90}}, ""},
+		{PruneOptions{ReadCost: -1}, "Fatal: prices must not be negative"},
+		{PruneOptions{MaxCost: -1}, "Fatal: prices must not be negative"},
+		{PruneOptions{MaxCost: 1}, "Fatal: --max-cost requires at least one of --read-cost, --write-cost, --delete-cost or --storage-cost"},
+	}
+
+	for _, testCase := range testCases {
+		opts := testCase.input
+		opts.MaxUnused = "5%"
+		err := verifyPruneOptions(&opts)
+		if testCase.errorMsg == "" {
+			rtest.OK(t, err)
+		} else {
+			rtest.Assert(t, err != nil, "expected error for %+v", testCase.input)
+			rtest.Equals(t, testCase.errorMsg, err.Error())
+		}
+	}
+}
+
+func TestPruneCosts(t *testing.T) {
+	const gib = 1 << 30
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	created := map[restic.ID]time.Time{
+		restic.NewRandomID(): now.AddDate(0, 0, -120),
+		restic.NewRandomID(): now.AddDate(0, 0, -60),
+		restic.NewRandomID(): {},
+	}
+
+	costs := PruneOptions{
+		ReadCost:           0.03,
+		WriteCost:          0.005,
+		DeleteCost:         0.001,
+		StorageCost:        0.004,
+		MinStorageDuration: restic.Duration{Days: 90},
+	}.costs()
+	costs.now = now
+
+	for id, ts := range created {
+		cost := costs.remove(2*gib, ts)
+
+		// deleting is charged, storage only until the minimum storage duration has passed
+		want := 2 * 0.001
+		switch {
+		case ts.IsZero():
+			want += 2 * 0.004 * 3
+		case now.Sub(ts) < 90*24*time.Hour:
+			want += 2 * 0.004 * 1
+		}
+		rtest.Assert(t, math.Abs(cost-want) < 1e-9, "wrong cost for pack created at %v, want %v, got %v", ts, want, cost)
+
+		p := packInfoWithID{ID: id, packInfo: packInfo{usedSize: gib, unusedSize: gib}}
+		repack := costs.repack(p, ts)
+		want += 2*0.03 + 0.005
+		rtest.Assert(t, math.Abs(repack-want) < 1e-9, "wrong repack cost for pack created at %v, want %v, got %v", ts, want, repack)
+	}
+
+	// no costs are estimated without prices
+	cost := PruneOptions{}.costs().repack(packInfoWithID{packInfo: packInfo{usedSize: gib, unusedSize: gib}}, now)
+	rtest.Equals(t, 0.0, cost)
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/restic/chunker"
+	"github.com/restic/restic/internal/backend"
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/fs"
+	"github.com/restic/restic/internal/repository"
+	"github.com/restic/restic/internal/restic"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/spf13/cobra"
+)
+
+var cmdRepairBlobs = &cobra.Command{
+	Use:   "blobs [flags] [blob ID...]",
+	Short: "Restore missing blobs from local files or another repository",
+	Long: `
+The "repair blobs" command restores blobs which are referenced by snapshots but
+are missing in the repository, for example because a pack file was lost. The
+snapshots are not modified.
+
+Blobs which are contained in the index but damaged, as reported by
+"check --read-data", are restored as well if their IDs are passed as
+arguments. "check --json" lists them in the "damaged_blobs" field of the
+summary. An intact copy of each damaged blob is stored in a new pack file,
+the damaged pack files are not modified.
+
+The command depends on a correct index, thus make sure to run "repair index"
+first!
+
+With --from-path, all files in the given directories are split into chunks
+using the chunker parameters of the repository. Each chunk which matches a
+missing data blob is stored in the repository again. Trees cannot be restored
+this way.
+
+With --from-repo, missing data blobs and trees are copied from another
+repository which contains the same data, for example a repository the snapshots
+were copied to using the "copy" command.
+
+Blobs which could not be restored are listed at the end. Use "repair snapshots"
+to remove them from the snapshots.
+
+EXIT STATUS
+===========
+
+Exit status is 0 if the command was successful, and non-zero if there was any
+error, including missing blobs which could not be restored.
+`,
+	DisableAutoGenTag: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRepairBlobs(cmd.Context(), repairBlobsOptions, globalOptions, args)
+	},
+}
+
+// RepairBlobsOptions collects all options for the repair blobs command.
+type RepairBlobsOptions struct {
+	secondaryRepoOptions
+	FromPaths []string
+	DryRun    bool
+}
+
+var repairBlobsOptions RepairBlobsOptions
+
+func init() {
+	cmdRepair.AddCommand(cmdRepairBlobs)
+
+	f := cmdRepairBlobs.Flags()
+	initSecondaryRepoOptions(f, &repairBlobsOptions.secondaryRepoOptions, "source", "to copy missing blobs from")
+	f.StringArrayVar(&repairBlobsOptions.FromPaths, "from-path", nil, "restore missing data blobs from the files in `dir` (can be specified multiple times)")
+	f.BoolVarP(&repairBlobsOptions.DryRun, "dry-run", "n", false, "do not do anything, just print what would be done")
+}
+
+func (opts RepairBlobsOptions) hasFromRepo() bool {
+	return opts.Repo != "" || opts.RepositoryFile != "" || opts.LegacyRepo != "" || opts.LegacyRepositoryFile != ""
+}
+
+func runRepairBlobs(ctx context.Context, opts RepairBlobsOptions, gopts GlobalOptions, args []string) error {
+	if len(opts.FromPaths) == 0 && !opts.hasFromRepo() {
+		return errors.Fatal("please specify where to restore missing blobs from using --from-path or --from-repo")
+	}
+
+	repo, err := OpenRepository(ctx, gopts)
+	if err != nil {
+		return err
+	}
+
+	if !gopts.NoLock {
+		var lock *restic.Lock
+		lock, ctx, err = lockRepo(ctx, repo, gopts.RetryLock, gopts.JSON)
+		defer unlockRepo(lock)
+		if err != nil {
+			return err
+		}
+	}
+
+	snapshotLister, err := backend.MemorizeList(ctx, repo.Backend(), restic.SnapshotFile)
+	if err != nil {
+		return err
+	}
+
+	Verbosef("load indexes\n")
+	if err = repo.LoadIndex(ctx); err != nil {
+		return err
+	}
+
+	damaged, err := lookupDamagedBlobs(repo, args)
+	if err != nil {
+		return err
+	}
+
+	Verbosef("find missing blobs\n")
+	missing, err := findMissingBlobs(ctx, repo, snapshotLister)
+	if err != nil {
+		return err
+	}
+	if len(damaged) > 0 {
+		Printf("found %d missing and %d damaged blobs\n", len(missing), len(damaged))
+		missing.Merge(damaged)
+	} else if len(missing) == 0 {
+		Printf("no missing blobs found\n")
+		return nil
+	} else {
+		Printf("found %d missing blobs\n", len(missing))
+	}
+
+	verb := "restored"
+	if opts.DryRun {
+		verb = "would restore"
+	}
+
+	if opts.hasFromRepo() {
+		srcGopts, _, err := fillSecondaryGlobalOpts(opts.secondaryRepoOptions, gopts, "source")
+		if err != nil {
+			return err
+		}
+
+		srcRepo, err := OpenRepository(ctx, srcGopts)
+		if err != nil {
+			return err
+		}
+
+		if !gopts.NoLock {
+			var srcLock *restic.Lock
+			srcLock, ctx, err = lockRepo(ctx, srcRepo, gopts.RetryLock, gopts.JSON)
+			defer unlockRepo(srcLock)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err = srcRepo.LoadIndex(ctx); err != nil {
+			return err
+		}
+
+		for {
+			restored, err := copyMissingBlobs(ctx, srcRepo, repo, missing, opts.DryRun, gopts.Quiet)
+			if err != nil {
+				return err
+			}
+			Printf("%s %d blobs from the source repository\n", verb, len(restored))
+
+			restoredTrees := false
+			for h := range restored {
+				missing.Delete(h)
+				damaged.Delete(h)
+				restoredTrees = restoredTrees || h.Type == restic.TreeBlob
+			}
+			if !restoredTrees || opts.DryRun {
+				break
+			}
+
+			// the restored trees can reference further missing blobs
+			missing, err = findMissingBlobs(ctx, repo, snapshotLister)
+			if err != nil {
+				return err
+			}
+			missing.Merge(damaged)
+		}
+	}
+
+	if len(opts.FromPaths) > 0 {
+		restored, err := restoreBlobsFromPaths(ctx, repo, opts.FromPaths, missing, opts.DryRun)
+		if err != nil {
+			return err
+		}
+		Printf("%s %d blobs from %v\n", verb, restored, strings.Join(opts.FromPaths, ", "))
+	}
+
+	if len(missing) > 0 {
+		Printf("\nthe following blobs could not be restored:\n")
+		for _, h := range missing.List() {
+			Printf("  %v\n", h)
+		}
+		return errors.Fatalf("%d blobs are still missing, run `restic repair snapshots` to remove them from the snapshots", len(missing))
+	}
+
+	if opts.DryRun {
+		Printf("all missing blobs can be restored\n")
+	} else {
+		Printf("all missing blobs were restored\n")
+	}
+	return nil
+}
+
+// lookupDamagedBlobs returns the handles of the blobs with the given IDs, which
+// must be contained in the index.
+func lookupDamagedBlobs(repo restic.Repository, args []string) (restic.BlobSet, error) {
+	damaged := restic.NewBlobSet()
+	for _, arg := range args {
+		id, err := restic.ParseID(arg)
+		if err != nil {
+			return nil, errors.Fatalf("invalid blob ID %q: %v", arg, err)
+		}
+
+		found := false
+		for _, tpe := range []restic.BlobType{restic.DataBlob, restic.TreeBlob} {
+			h := restic.BlobHandle{ID: id, Type: tpe}
+			if repo.Index().Has(h) {
+				damaged.Insert(h)
+				found = true
+			}
+		}
+		if !found {
+			// missing blobs are found anyway if a snapshot references them
+			Warnf("blob %v is not contained in the index, ignoring\n", id.Str())
+		}
+	}
+	return damaged, nil
+}
+
+// findMissingBlobs returns the trees and data blobs which are referenced by a
+// snapshot but are not contained in the index.
+func findMissingBlobs(ctx context.Context, repo restic.Repository, snapshotLister restic.Lister) (restic.BlobSet, error) {
+	var trees restic.IDs
+	err := restic.ForAllSnapshots(ctx, snapshotLister, repo, nil, func(id restic.ID, sn *restic.Snapshot, err error) error {
+		if err != nil {
+			Warnf("skipping snapshot: %v\n", err)
+			return nil
+		}
+		trees = append(trees, *sn.Tree)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	missing := restic.NewBlobSet()
+	visitedTrees := restic.NewIDSet()
+
+	wg, wgCtx := errgroup.WithContext(ctx)
+	treeStream := restic.StreamTrees(wgCtx, wg, repo, trees, func(treeID restic.ID) bool {
+		visited := visitedTrees.Has(treeID)
+		visitedTrees.Insert(treeID)
+		return visited
+	}, nil)
+
+	wg.Go(func() error {
+		for tree := range treeStream {
+			if tree.Error != nil {
+				h := restic.BlobHandle{ID: tree.ID, Type: restic.TreeBlob}
+				if !repo.Index().Has(h) {
+					missing.Insert(h)
+				} else {
+					Warnf("unable to load tree %v: %v\n", tree.ID.Str(), tree.Error)
+				}
+				continue
+			}
+
+			for _, node := range tree.Nodes {
+				for _, id := range node.Content {
+					h := restic.BlobHandle{ID: id, Type: restic.DataBlob}
+					if !repo.Index().Has(h) {
+						missing.Insert(h)
+					}
+				}
+			}
+		}
+		return nil
+	})
+
+	return missing, wg.Wait()
+}
+
+// copyMissingBlobs copies the missing blobs which are available in srcRepo to
+// dstRepo and returns them.
+func copyMissingBlobs(ctx context.Context, srcRepo, dstRepo restic.Repository, missing restic.BlobSet, dryRun bool, quiet bool) (restic.BlobSet, error) {
+	copyBlobs := restic.NewBlobSet()
+	packs := restic.NewIDSet()
+	for h := range missing {
+		pbs := srcRepo.Index().Lookup(h)
+		if len(pbs) == 0 {
+			continue
+		}
+		copyBlobs.Insert(h)
+		packs.Insert(pbs[0].PackID)
+	}
+
+	if dryRun || len(copyBlobs) == 0 {
+		return copyBlobs, nil
+	}
+
+	// Repack removes the processed blobs from the set passed to it
+	keepBlobs := restic.NewBlobSet()
+	keepBlobs.Merge(copyBlobs)
+
+	bar := newProgressMax(!quiet, uint64(len(packs)), "packs copied")
+	_, err := repository.Repack(ctx, srcRepo, dstRepo, packs, keepBlobs, bar)
+	bar.Done()
+	if err != nil {
+		return nil, errors.Fatal(err.Error())
+	}
+
+	return copyBlobs.Sub(keepBlobs), nil
+}
+
+var errAllBlobsRestored = errors.New("all blobs restored")
+
+// restoreBlobsFromPaths chunks all files in paths and saves the chunks which
+// match a missing data blob in repo. Restored blobs are removed from missing.
+func restoreBlobsFromPaths(ctx context.Context, repo restic.Repository, paths []string, missing restic.BlobSet, dryRun bool) (restored int, err error) {
+	missingData := 0
+	for h := range missing {
+		if h.Type == restic.DataBlob {
+			missingData++
+		}
+	}
+	if missingData == 0 {
+		return 0, nil
+	}
+
+	chnker := chunker.New(nil, repo.Config().ChunkerPolynomial)
+	buf := make([]byte, chunker.MaxSize)
+
+	wg, wgCtx := errgroup.WithContext(ctx)
+	if !dryRun {
+		repo.StartPackUploader(wgCtx, wg)
+	}
+
+	wg.Go(func() error {
+		for _, dir := range paths {
+			err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+				if err != nil {
+					Warnf("%v\n", err)
+					return nil
+				}
+				if !fi.Mode().IsRegular() {
+					return nil
+				}
+
+				n, err := restoreBlobsFromFile(wgCtx, repo, chnker, buf, path, missing, dryRun)
+				if err != nil {
+					return err
+				}
+				restored += n
+				missingData -= n
+				if missingData == 0 {
+					return errAllBlobsRestored
+				}
+				return nil
+			})
+			if err == errAllBlobsRestored {
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if dryRun {
+			return nil
+		}
+		err := repo.Flush(wgCtx)
+		if err != nil {
+			return errors.Fatalf("unable to save blobs to the repository: %v", err)
+		}
+		return nil
+	})
+
+	return restored, wg.Wait()
+}
+
+func restoreBlobsFromFile(ctx context.Context, repo restic.Repository, chnker *chunker.Chunker, buf []byte, path string, missing restic.BlobSet, dryRun bool) (restored int, err error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		Warnf("%v\n", err)
+		return 0, nil
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	chnker.Reset(f, repo.Config().ChunkerPolynomial)
+	for {
+		chunk, err := chnker.Next(buf)
+		if err == io.EOF {
+			return restored, nil
+		}
+		if err != nil {
+			Warnf("unable to read %v: %v\n", path, err)
+			return restored, nil
+		}
+
+		h := restic.BlobHandle{ID: restic.Hash(chunk.Data), Type: restic.DataBlob}
+		if !missing.Has(h) {
+			continue
+		}
+
+		if !dryRun {
+			// damaged blobs are contained in the index, thus store a duplicate
+			_, _, _, err = repo.SaveBlob(ctx, restic.DataBlob, chunk.Data, h.ID, true)
+			if err != nil {
+				return restored, err
+			}
+		}
+		Verboseff("restored blob %v from %v\n", h.ID.Str(), path)
+		missing.Delete(h)
+		restored++
+	}
+}
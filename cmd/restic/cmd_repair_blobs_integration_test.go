@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/restic/restic/internal/restic"
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func testRunRepairBlobs(gopts GlobalOptions, opts RepairBlobsOptions) error {
+	_, err := withCaptureStdout(func() error {
+		return runRepairBlobs(context.TODO(), opts, gopts, nil)
+	})
+	return err
+}
+
+func TestRepairBlobsFromPath(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	source := filepath.Join(env.testdata, "0", "0", "9")
+	testRunBackup(t, "", []string{filepath.Join(source, "2"), filepath.Join(source, "3")}, BackupOptions{}, env.gopts)
+
+	removePacksExcept(env.gopts, t, restic.NewIDSet(), false)
+	testRunRebuildIndex(t, env.gopts)
+	testRunCheckMustFail(t, env.gopts)
+
+	// a directory which only contains part of the data restores only that part
+	err := testRunRepairBlobs(env.gopts, RepairBlobsOptions{FromPaths: []string{filepath.Join(source, "2")}})
+	rtest.Assert(t, err != nil, "expected error as not all blobs could be restored")
+
+	// dry-run does not modify the repository
+	packs := listPacks(env.gopts, t)
+	rtest.OK(t, testRunRepairBlobs(env.gopts, RepairBlobsOptions{FromPaths: []string{source}, DryRun: true}))
+	rtest.Equals(t, packs, listPacks(env.gopts, t))
+
+	rtest.OK(t, testRunRepairBlobs(env.gopts, RepairBlobsOptions{FromPaths: []string{source}}))
+	testRunCheck(t, env.gopts)
+}
+
+func TestRepairBlobsFromRepo(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+	env2, cleanup2 := withTestEnvironment(t)
+	defer cleanup2()
+
+	testSetupBackupData(t, env)
+	testRunBackup(t, "", []string{filepath.Join(env.testdata, "0", "0", "9")}, BackupOptions{}, env.gopts)
+	testRunInit(t, env2.gopts)
+	testRunCopy(t, env.gopts, env2.gopts)
+
+	// lose data and trees
+	removePacksExcept(env.gopts, t, restic.NewIDSet(), false)
+	removePacksExcept(env.gopts, t, restic.NewIDSet(), true)
+	testRunRebuildIndex(t, env.gopts)
+	testRunCheckMustFail(t, env.gopts)
+
+	opts := RepairBlobsOptions{
+		secondaryRepoOptions: secondaryRepoOptions{
+			Repo:     env2.gopts.Repo,
+			password: env2.gopts.password,
+		},
+	}
+	rtest.OK(t, testRunRepairBlobs(env.gopts, opts))
+	testRunCheck(t, env.gopts)
+}
+
+func TestRepairBlobsDamaged(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	source := filepath.Join(env.testdata, "0", "0", "9", "2")
+	testRunBackup(t, "", []string{source}, BackupOptions{}, env.gopts)
+
+	// damage the first blob of all data packs
+	repo, err := OpenRepository(context.TODO(), env.gopts)
+	rtest.OK(t, err)
+	rtest.OK(t, repo.LoadIndex(context.TODO()))
+	dataPacks := restic.NewIDSet()
+	repo.Index().Each(context.TODO(), func(pb restic.PackedBlob) {
+		if pb.Type == restic.DataBlob {
+			dataPacks.Insert(pb.PackID)
+		}
+	})
+	for id := range dataPacks {
+		name := filepath.Join(env.repo, "data", id.String()[:2], id.String())
+		rtest.OK(t, os.Chmod(name, 0644))
+		f, err := os.OpenFile(name, os.O_WRONLY, 0)
+		rtest.OK(t, err)
+		_, err = f.WriteAt([]byte("damaged"), 0)
+		rtest.OK(t, err)
+		rtest.OK(t, f.Close())
+	}
+
+	gopts := env.gopts
+	gopts.JSON = true
+	buf, err := withCaptureStdout(func() error {
+		return runCheck(context.TODO(), CheckOptions{ReadData: true}, gopts, nil)
+	})
+	rtest.Assert(t, err != nil, "expected error for damaged repository")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var summary checkSummary
+	rtest.OK(t, json.Unmarshal([]byte(lines[len(lines)-1]), &summary))
+	rtest.Equals(t, len(dataPacks), len(summary.DamagedBlobs))
+
+	var args []string
+	for _, id := range summary.DamagedBlobs {
+		args = append(args, id.String())
+	}
+	_, err = withCaptureStdout(func() error {
+		return runRepairBlobs(context.TODO(), RepairBlobsOptions{FromPaths: []string{source}}, env.gopts, args)
+	})
+	rtest.OK(t, err)
+
+	// an intact copy of each damaged blob was stored
+	repo, err = OpenRepository(context.TODO(), env.gopts)
+	rtest.OK(t, err)
+	rtest.OK(t, repo.LoadIndex(context.TODO()))
+	for _, id := range summary.DamagedBlobs {
+		rtest.Equals(t, 2, len(repo.Index().Lookup(restic.BlobHandle{ID: id, Type: restic.DataBlob})))
+		_, err := repo.LoadBlob(context.TODO(), restic.DataBlob, id, nil)
+		rtest.OK(t, err)
+	}
+}
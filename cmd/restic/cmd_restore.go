@@ -71,8 +71,11 @@ type RestoreOptions struct {
 	InsensitiveInclude []string
 	Target             string
 	restic.SnapshotFilter
-	Sparse bool
-	Verify bool
+	Sparse    bool
+	Verify    bool
+	Overwrite restorer.OverwriteBehavior
+	Delete    bool
+	DryRun    bool
 }
 
 var restoreOptions RestoreOptions
@@ -90,6 +93,9 @@ func init() {
 	initSingleSnapshotFilter(flags, &restoreOptions.SnapshotFilter)
 	flags.BoolVar(&restoreOptions.Sparse, "sparse", false, "restore files as sparse")
 	flags.BoolVar(&restoreOptions.Verify, "verify", false, "verify restored files content")
+	flags.Var(&restoreOptions.Overwrite, "overwrite", "overwrite behavior, one of (always|if-changed|if-newer|never)")
+	flags.BoolVar(&restoreOptions.Delete, "delete", false, "delete files from the target directory which do not exist in the snapshot")
+	flags.BoolVar(&restoreOptions.DryRun, "dry-run", false, "do not write any data, just show what would be done")
 }
 
 func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
@@ -143,6 +149,10 @@ func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
 		return errors.Fatal("exclude and include patterns are mutually exclusive")
 	}
 
+	if opts.DryRun && opts.Verify {
+		return errors.Fatal("--dry-run and --verify are mutually exclusive")
+	}
+
 	snapshotIDString := args[0]
 
 	debug.Log("restore %v to %v", snapshotIDString, opts.Target)
@@ -162,9 +172,10 @@ func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
 	}
 
 	sn, err := (&restic.SnapshotFilter{
-		Hosts: opts.Hosts,
-		Paths: opts.Paths,
-		Tags:  opts.Tags,
+		Hosts:  opts.Hosts,
+		Paths:  opts.Paths,
+		Tags:   opts.Tags,
+		Labels: opts.Labels,
 	}).FindLatest(ctx, repo.Backend(), repo, snapshotIDString)
 	if err != nil {
 		return errors.Fatalf("failed to find snapshot: %v", err)
@@ -176,16 +187,29 @@ func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
 	}
 
 	var progress *restoreui.Progress
-	if !gopts.Quiet && !gopts.JSON {
-		progress = restoreui.NewProgress(restoreui.NewProgressPrinter(term), calculateProgressInterval(!gopts.Quiet, gopts.JSON))
+	if gopts.JSON {
+		printer := restoreui.NewJSONProgressPrinter(term, gopts.verbosity, opts.DryRun)
+		progress = restoreui.NewProgress(printer, calculateProgressInterval(!gopts.Quiet, gopts.JSON))
+	} else if !gopts.Quiet {
+		printer := restoreui.NewProgressPrinter(term, gopts.verbosity, opts.DryRun)
+		progress = restoreui.NewProgress(printer, calculateProgressInterval(!gopts.Quiet, gopts.JSON))
 	}
 
-	res := restorer.NewRestorer(repo, sn, opts.Sparse, progress)
+	res := restorer.NewRestorer(repo, sn, restorer.Options{
+		Sparse:    opts.Sparse,
+		Progress:  progress,
+		Overwrite: opts.Overwrite,
+		Delete:    opts.Delete,
+		DryRun:    opts.DryRun,
+	})
 
 	totalErrors := 0
 	res.Error = func(location string, err error) error {
-		Warnf("ignoring error for %s: %s\n", location, err)
 		totalErrors++
+		if progress != nil {
+			return progress.Error(location, err)
+		}
+		Warnf("ignoring error for %s: %s\n", location, err)
 		return nil
 	}
 
@@ -237,7 +261,13 @@ func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
 		res.SelectFilter = selectIncludeFilter
 	}
 
-	Verbosef("restoring %s to %s\n", res.Snapshot(), opts.Target)
+	if !gopts.JSON {
+		if opts.DryRun {
+			Verbosef("dry run: checking restore of %s to %s\n", res.Snapshot(), opts.Target)
+		} else {
+			Verbosef("restoring %s to %s\n", res.Snapshot(), opts.Target)
+		}
+	}
 
 	err = res.RestoreTo(ctx, opts.Target)
 	if err != nil {
@@ -253,7 +283,9 @@ func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
 	}
 
 	if opts.Verify {
-		Verbosef("verifying files in %s\n", opts.Target)
+		if !gopts.JSON {
+			Verbosef("verifying files in %s\n", opts.Target)
+		}
 		var count int
 		t0 := time.Now()
 		count, err = res.VerifyFiles(ctx, opts.Target)
@@ -263,8 +295,10 @@ func runRestore(ctx context.Context, opts RestoreOptions, gopts GlobalOptions,
 		if totalErrors > 0 {
 			return errors.Fatalf("There were %d errors\n", totalErrors)
 		}
-		Verbosef("finished verifying %d files in %s (took %s)\n", count, opts.Target,
-			time.Since(t0).Round(time.Millisecond))
+		if !gopts.JSON {
+			Verbosef("finished verifying %d files in %s (took %s)\n", count, opts.Target,
+				time.Since(t0).Round(time.Millisecond))
+		}
 	}
 
 	return nil
@@ -105,6 +105,10 @@ func rewriteSnapshot(ctx context.Context, repo *repository.Repository, sn *resti
 }
 
 func filterAndReplaceSnapshot(ctx context.Context, repo restic.Repository, sn *restic.Snapshot, filter func(ctx context.Context, sn *restic.Snapshot) (restic.ID, error), dryRun bool, forget bool, addTag string) (bool, error) {
+	if forget && sn.Hold {
+		Warnf("snapshot %v is on hold, the original snapshot will not be removed\n", sn.ID().Str())
+		forget = false
+	}
 
 	wg, wgCtx := errgroup.WithContext(ctx)
 	repo.StartPackUploader(wgCtx, wg)
@@ -125,6 +129,10 @@ func filterAndReplaceSnapshot(ctx context.Context, repo restic.Repository, sn *r
 	}
 
 	if filteredTree.IsNull() {
+		if sn.Hold {
+			Warnf("snapshot %v is on hold, not removing it although it would be empty\n", sn.ID().Str())
+			return false, nil
+		}
 		if dryRun {
 			Verbosef("would delete empty snapshot\n")
 		} else {
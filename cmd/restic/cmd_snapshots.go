@@ -171,7 +171,11 @@ func PrintSnapshots(stdout io.Writer, list restic.Snapshots, reasons []restic.Ke
 
 	// Determine the max widths for host and tag.
 	maxHost, maxTag := 10, 6
+	hasDescription := false
 	for _, sn := range list {
+		if sn.Description != "" {
+			hasDescription = true
+		}
 		if len(sn.Hostname) > maxHost {
 			maxHost = len(sn.Hostname)
 		}
@@ -194,6 +198,9 @@ func PrintSnapshots(stdout io.Writer, list restic.Snapshots, reasons []restic.Ke
 		tab.AddColumn("Time", "{{ .Timestamp }}")
 		tab.AddColumn("Host      ", "{{ .Hostname }}")
 		tab.AddColumn("Tags      ", `{{ join .Tags "," }}`)
+		if hasDescription {
+			tab.AddColumn("Description", `{{ truncate 40 .Description }}`)
+		}
 		if len(reasons) > 0 {
 			tab.AddColumn("Reasons", `{{ join .Reasons "\n" }}`)
 		}
@@ -201,12 +208,13 @@ func PrintSnapshots(stdout io.Writer, list restic.Snapshots, reasons []restic.Ke
 	}
 
 	type snapshot struct {
-		ID        string
-		Timestamp string
-		Hostname  string
-		Tags      []string
-		Reasons   []string
-		Paths     []string
+		ID          string
+		Timestamp   string
+		Hostname    string
+		Tags        []string
+		Description string
+		Reasons     []string
+		Paths       []string
 	}
 
 	var multiline bool
@@ -219,6 +227,9 @@ func PrintSnapshots(stdout io.Writer, list restic.Snapshots, reasons []restic.Ke
 			Paths:     sn.Paths,
 		}
 
+		// only show the first line of the description
+		data.Description, _, _ = strings.Cut(sn.Description, "\n")
+
 		if len(reasons) > 0 {
 			id := sn.ID()
 			data.Reasons = keepReasons[*id].Matches
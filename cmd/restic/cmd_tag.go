@@ -13,13 +13,20 @@ import (
 
 var cmdTag = &cobra.Command{
 	Use:   "tag [flags] [snapshot-ID ...]",
-	Short: "Modify tags on snapshots",
+	Short: "Modify tags, labels and descriptions on snapshots",
 	Long: `
 The "tag" command allows you to modify tags on exiting snapshots.
 
 You can either set/replace the entire set of tags on a snapshot, or
 add tags to/remove tags from the existing set.
 
+Labels are modified using "--set-label key=value", which adds the label or
+replaces the value of an existing label with the same key, and
+"--remove-label key".
+
+The description of a snapshot is replaced with "--set-description text" and
+removed with "--remove-description".
+
 When no snapshot-ID is given, all snapshots matching the host, tag and path filter criteria are modified.
 
 EXIT STATUS
@@ -39,6 +46,12 @@ type TagOptions struct {
 	SetTags    restic.TagLists
 	AddTags    restic.TagLists
 	RemoveTags restic.TagLists
+
+	SetLabels    restic.LabelMap
+	RemoveLabels []string
+
+	SetDescription    string
+	RemoveDescription bool
 }
 
 var tagOptions TagOptions
@@ -50,12 +63,20 @@ func init() {
 	tagFlags.Var(&tagOptions.SetTags, "set", "`tags` which will replace the existing tags in the format `tag[,tag,...]` (can be given multiple times)")
 	tagFlags.Var(&tagOptions.AddTags, "add", "`tags` which will be added to the existing tags in the format `tag[,tag,...]` (can be given multiple times)")
 	tagFlags.Var(&tagOptions.RemoveTags, "remove", "`tags` which will be removed from the existing tags in the format `tag[,tag,...]` (can be given multiple times)")
+	tagFlags.Var(&tagOptions.SetLabels, "set-label", "set the label `key=value`, replacing the existing value for key (can be given multiple times)")
+	tagFlags.StringArrayVar(&tagOptions.RemoveLabels, "remove-label", nil, "remove the label with the given `key` (can be given multiple times)")
+	tagFlags.StringVar(&tagOptions.SetDescription, "set-description", "", "replace the description of the snapshots with `text`")
+	tagFlags.BoolVar(&tagOptions.RemoveDescription, "remove-description", false, "remove the description of the snapshots")
 	initMultiSnapshotFilter(tagFlags, &tagOptions.SnapshotFilter, true)
 }
 
-func changeTags(ctx context.Context, repo *repository.Repository, sn *restic.Snapshot, setTags, addTags, removeTags []string) (bool, error) {
+func changeTags(ctx context.Context, repo *repository.Repository, sn *restic.Snapshot, opts TagOptions) (bool, error) {
 	var changed bool
 
+	setTags := opts.SetTags.Flatten()
+	addTags := opts.AddTags.Flatten()
+	removeTags := opts.RemoveTags.Flatten()
+
 	if len(setTags) != 0 {
 		// Setting the tag to an empty string really means no tags.
 		if len(setTags) == 1 && setTags[0] == "" {
@@ -70,38 +91,70 @@ func changeTags(ctx context.Context, repo *repository.Repository, sn *restic.Sna
 		}
 	}
 
-	if changed {
-		// Retain the original snapshot id over all tag changes.
-		if sn.Original == nil {
-			sn.Original = sn.ID()
-		}
+	if sn.SetLabels(opts.SetLabels) {
+		changed = true
+	}
+	if sn.RemoveLabels(opts.RemoveLabels) {
+		changed = true
+	}
 
-		// Save the new snapshot.
-		id, err := restic.SaveSnapshot(ctx, repo, sn)
-		if err != nil {
+	description := sn.Description
+	if opts.SetDescription != "" {
+		description = opts.SetDescription
+	} else if opts.RemoveDescription {
+		description = ""
+	}
+	if description != sn.Description {
+		sn.Description = description
+		changed = true
+	}
+
+	if changed {
+		if err := replaceSnapshot(ctx, repo, sn); err != nil {
 			return false, err
 		}
+	}
+	return changed, nil
+}
 
-		debug.Log("new snapshot saved as %v", id)
+// replaceSnapshot saves the modified snapshot sn and removes the snapshot it
+// was loaded from.
+func replaceSnapshot(ctx context.Context, repo *repository.Repository, sn *restic.Snapshot) error {
+	// Retain the original snapshot id over all tag changes.
+	if sn.Original == nil {
+		sn.Original = sn.ID()
+	}
 
-		// Remove the old snapshot.
-		h := restic.Handle{Type: restic.SnapshotFile, Name: sn.ID().String()}
-		if err = repo.Backend().Remove(ctx, h); err != nil {
-			return false, err
-		}
+	// Save the new snapshot.
+	id, err := restic.SaveSnapshot(ctx, repo, sn)
+	if err != nil {
+		return err
+	}
 
-		debug.Log("old snapshot %v removed", sn.ID())
+	debug.Log("new snapshot saved as %v", id)
+
+	// Remove the old snapshot.
+	h := restic.Handle{Type: restic.SnapshotFile, Name: sn.ID().String()}
+	if err = repo.Backend().Remove(ctx, h); err != nil {
+		return err
 	}
-	return changed, nil
+
+	debug.Log("old snapshot %v removed", sn.ID())
+	return nil
 }
 
 func runTag(ctx context.Context, opts TagOptions, gopts GlobalOptions, args []string) error {
-	if len(opts.SetTags) == 0 && len(opts.AddTags) == 0 && len(opts.RemoveTags) == 0 {
+	if len(opts.SetTags) == 0 && len(opts.AddTags) == 0 && len(opts.RemoveTags) == 0 &&
+		len(opts.SetLabels) == 0 && len(opts.RemoveLabels) == 0 &&
+		opts.SetDescription == "" && !opts.RemoveDescription {
 		return errors.Fatal("nothing to do!")
 	}
 	if len(opts.SetTags) != 0 && (len(opts.AddTags) != 0 || len(opts.RemoveTags) != 0) {
 		return errors.Fatal("--set and --add/--remove cannot be given at the same time")
 	}
+	if opts.SetDescription != "" && opts.RemoveDescription {
+		return errors.Fatal("--set-description and --remove-description cannot be given at the same time")
+	}
 
 	repo, err := OpenRepository(ctx, gopts)
 	if err != nil {
@@ -120,9 +173,9 @@ func runTag(ctx context.Context, opts TagOptions, gopts GlobalOptions, args []st
 
 	changeCnt := 0
 	for sn := range FindFilteredSnapshots(ctx, repo.Backend(), repo, &opts.SnapshotFilter, args) {
-		changed, err := changeTags(ctx, repo, sn, opts.SetTags.Flatten(), opts.AddTags.Flatten(), opts.RemoveTags.Flatten())
+		changed, err := changeTags(ctx, repo, sn, opts)
 		if err != nil {
-			Warnf("unable to modify the tags for snapshot ID %q, ignoring: %v\n", sn.ID(), err)
+			Warnf("unable to modify snapshot ID %q, ignoring: %v\n", sn.ID(), err)
 			continue
 		}
 		if changed {
@@ -132,7 +185,7 @@ func runTag(ctx context.Context, opts TagOptions, gopts GlobalOptions, args []st
 	if changeCnt == 0 {
 		Verbosef("no snapshots were modified\n")
 	} else {
-		Verbosef("modified tags on %v snapshots\n", changeCnt)
+		Verbosef("modified %v snapshots\n", changeCnt)
 	}
 	return nil
 }
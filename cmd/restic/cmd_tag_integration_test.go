@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/restic/restic/internal/restic"
@@ -92,3 +94,71 @@ func TestTag(t *testing.T) {
 	rtest.Assert(t, *newest.Original == originalID,
 		"expected original ID to be set to the first snapshot id")
 }
+
+func TestTagLabels(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	testSetupBackupData(t, env)
+	testRunBackup(t, "", []string{env.testdata}, BackupOptions{Labels: restic.LabelMap{"env": "prod", "job": "42"}}, env.gopts)
+	testRunCheck(t, env.gopts)
+	newest, _ := testRunSnapshots(t, env.gopts)
+	if newest == nil {
+		t.Fatal("expected a new backup, got nil")
+	}
+	rtest.Equals(t, restic.LabelMap{"env": "prod", "job": "42"}, newest.Labels)
+	originalID := *newest.ID
+
+	testRunTag(t, TagOptions{SetLabels: restic.LabelMap{"job": "43", "ticket": "T-1"}, RemoveLabels: []string{"env"}}, env.gopts)
+	testRunCheck(t, env.gopts)
+	newest, _ = testRunSnapshots(t, env.gopts)
+	if newest == nil {
+		t.Fatal("expected a backup, got nil")
+	}
+	rtest.Equals(t, restic.LabelMap{"job": "43", "ticket": "T-1"}, newest.Labels)
+	rtest.Assert(t, newest.Original != nil && *newest.Original == originalID,
+		"expected original ID to be set to the first snapshot id")
+
+	// only snapshots with matching labels are modified
+	testRunTag(t, TagOptions{
+		SnapshotFilter: restic.SnapshotFilter{Labels: restic.LabelMap{"job": "42"}},
+		AddTags:        restic.TagLists{[]string{"NL"}},
+	}, env.gopts)
+	newest, _ = testRunSnapshots(t, env.gopts)
+	rtest.Assert(t, len(newest.Tags) == 0, "expected no tags, got %v", newest.Tags)
+
+	testRunTag(t, TagOptions{
+		SnapshotFilter: restic.SnapshotFilter{Labels: restic.LabelMap{"job": "43"}},
+		AddTags:        restic.TagLists{[]string{"NL"}},
+	}, env.gopts)
+	newest, _ = testRunSnapshots(t, env.gopts)
+	rtest.Equals(t, restic.TagList{"NL"}, restic.TagList(newest.Tags))
+}
+
+func TestTagDescription(t *testing.T) {
+	env, cleanup := withTestEnvironment(t)
+	defer cleanup()
+
+	descriptionFile := filepath.Join(env.base, "description")
+	rtest.OK(t, os.WriteFile(descriptionFile, []byte("pre-upgrade of postgres 15→16\n"), 0o600))
+
+	testSetupBackupData(t, env)
+	testRunBackup(t, "", []string{env.testdata}, BackupOptions{DescriptionFile: descriptionFile}, env.gopts)
+	newest, _ := testRunSnapshots(t, env.gopts)
+	if newest == nil {
+		t.Fatal("expected a new backup, got nil")
+	}
+	rtest.Equals(t, "pre-upgrade of postgres 15→16", newest.Description)
+
+	testRunTag(t, TagOptions{SetDescription: "post-upgrade"}, env.gopts)
+	testRunCheck(t, env.gopts)
+	newest, _ = testRunSnapshots(t, env.gopts)
+	rtest.Equals(t, "post-upgrade", newest.Description)
+
+	testRunTag(t, TagOptions{RemoveDescription: true}, env.gopts)
+	newest, _ = testRunSnapshots(t, env.gopts)
+	rtest.Equals(t, "", newest.Description)
+
+	err := runTag(context.TODO(), TagOptions{SetDescription: "foo", RemoveDescription: true}, env.gopts, []string{})
+	rtest.Assert(t, err != nil, "expected error for --set-description together with --remove-description")
+}
@@ -390,6 +390,11 @@ func parseSizeStr(sizeStr string) (int64, error) {
 		return 0, errors.New("expected size, got empty string")
 	}
 
+	// accept binary unit suffixes like "TiB" as an alias for "T"
+	if len(sizeStr) > 3 && strings.HasSuffix(sizeStr, "iB") {
+		sizeStr = sizeStr[:len(sizeStr)-2]
+	}
+
 	numStr := sizeStr[:len(sizeStr)-1]
 	var unit int64 = 1
 
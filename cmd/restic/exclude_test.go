@@ -204,6 +204,10 @@ func TestParseSizeStr(t *testing.T) {
 		{"10g", 10737418240},
 		{"2T", 2199023255552},
 		{"2t", 2199023255552},
+		{"100KiB", 102400},
+		{"10MiB", 10485760},
+		{"20GiB", 21474836480},
+		{"2TiB", 2199023255552},
 	}
 
 	for _, tt := range sizeStrTests {
@@ -222,6 +226,7 @@ func TestParseInvalidSizeStr(t *testing.T) {
 		" ",
 		"foobar",
 		"zzz",
+		"5iB",
 	}
 
 	for _, s := range invalidSizes {
@@ -22,11 +22,17 @@ var globalLocks struct {
 }
 
 func lockRepo(ctx context.Context, repo restic.Repository, retryLock time.Duration, json bool) (*restic.Lock, context.Context, error) {
-	return lockRepository(ctx, repo, false, retryLock, json)
+	return lockRepository(ctx, repo, restic.NewLock, retryLock, json)
 }
 
 func lockRepoExclusive(ctx context.Context, repo restic.Repository, retryLock time.Duration, json bool) (*restic.Lock, context.Context, error) {
-	return lockRepository(ctx, repo, true, retryLock, json)
+	return lockRepository(ctx, repo, restic.NewExclusiveLock, retryLock, json)
+}
+
+// lockRepoPrune acquires a non-exclusive lock which also prevents other
+// processes from acquiring a prune lock.
+func lockRepoPrune(ctx context.Context, repo restic.Repository, retryLock time.Duration, json bool) (*restic.Lock, context.Context, error) {
+	return lockRepository(ctx, repo, restic.NewPruneLock, retryLock, json)
 }
 
 var (
@@ -43,18 +49,13 @@ func minDuration(a, b time.Duration) time.Duration {
 
 // lockRepository wraps the ctx such that it is cancelled when the repository is unlocked
 // cancelling the original context also stops the lock refresh
-func lockRepository(ctx context.Context, repo restic.Repository, exclusive bool, retryLock time.Duration, json bool) (*restic.Lock, context.Context, error) {
+func lockRepository(ctx context.Context, repo restic.Repository, lockFn func(context.Context, restic.Repository) (*restic.Lock, error), retryLock time.Duration, json bool) (*restic.Lock, context.Context, error) {
 	// make sure that a repository is unlocked properly and after cancel() was
 	// called by the cleanup handler in global.go
 	globalLocks.Do(func() {
 		AddCleanupHandler(unlockAll)
 	})
 
-	lockFn := restic.NewLock
-	if exclusive {
-		lockFn = restic.NewExclusiveLock
-	}
-
 	var lock *restic.Lock
 	var err error
 
@@ -99,7 +100,7 @@ retryLoop:
 	if err != nil {
 		return nil, ctx, errors.Fatalf("unable to create lock in backend: %v", err)
 	}
-	debug.Log("create lock %p (exclusive %v)", lock, exclusive)
+	debug.Log("create lock %p (exclusive %v, prune %v)", lock, lock.Exclusive, lock.Prune)
 
 	ctx, cancel := context.WithCancel(ctx)
 	lockInfo := &lockContext{
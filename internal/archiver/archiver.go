@@ -6,12 +6,15 @@ import (
 	"path"
 	"runtime"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/restic/restic/internal/debug"
 	"github.com/restic/restic/internal/errors"
 	"github.com/restic/restic/internal/fs"
 	"github.com/restic/restic/internal/restic"
+	"github.com/restic/restic/internal/walker"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -56,10 +59,20 @@ type Archiver struct {
 	FS           fs.FS
 	Options      Options
 
+	// SelectUnchanged is called for all items within directories which are
+	// taken from the parent snapshot as Changes reports them as unchanged. If
+	// it rejects an item, the directory is visited instead. As the items are
+	// not visited, it must only depend on the path. If it is nil, the
+	// directories are used as is.
+	SelectUnchanged SelectByNameFunc
+
 	blobSaver *BlobSaver
 	fileSaver *FileSaver
 	treeSaver *TreeSaver
 
+	mu      sync.Mutex
+	summary *restic.SnapshotSummary
+
 	// Error is called for all errors that occur during backup.
 	Error ErrorFunc
 
@@ -88,6 +101,66 @@ type Archiver struct {
 
 	// Flags controlling change detection. See doc/040_backup.rst for details.
 	ChangeIgnoreFlags uint
+
+	// Changes optionally reports which items were modified since the parent
+	// snapshot. Items which were not modified are taken from the parent
+	// snapshot without visiting them.
+	Changes ChangeSource
+
+	// DetectMoves enables looking up files by device ID and inode number in
+	// the parent snapshot, so that the content of files which were moved or
+	// renamed is not read again. This requires loading all trees of the
+	// parent snapshot. It has no effect if ChangeIgnoreInode is set.
+	DetectMoves bool
+	inodes      *InodeTable
+}
+
+// trackItem updates the summary of the current snapshot and passes the item
+// on to CompleteItem.
+func (arch *Archiver) trackItem(item string, previous, current *restic.Node, s ItemStats, d time.Duration) {
+	arch.CompleteItem(item, previous, current, s, d)
+
+	arch.mu.Lock()
+	defer arch.mu.Unlock()
+
+	if arch.summary == nil {
+		return
+	}
+
+	arch.summary.DataBlobs += s.DataBlobs
+	arch.summary.TreeBlobs += s.TreeBlobs
+	arch.summary.DataAdded += s.DataSize + s.TreeSize
+	arch.summary.DataAddedPacked += s.DataSizeInRepo + s.TreeSizeInRepo
+
+	// current is nil for the final item "/" and if an error occurred
+	if current == nil {
+		return
+	}
+
+	switch current.Type {
+	case "dir":
+		switch {
+		case previous == nil:
+			arch.summary.DirsNew++
+		case previous.Equals(*current):
+			arch.summary.DirsUnmodified++
+		default:
+			arch.summary.DirsChanged++
+		}
+
+	case "file":
+		arch.summary.TotalFilesProcessed++
+		arch.summary.TotalBytesProcessed += current.Size
+
+		switch {
+		case previous == nil:
+			arch.summary.FilesNew++
+		case previous.Equals(*current):
+			arch.summary.FilesUnmodified++
+		default:
+			arch.summary.FilesChanged++
+		}
+	}
 }
 
 // Flags for the ChangeIgnoreFlags bitfield.
@@ -324,6 +397,120 @@ func (arch *Archiver) allBlobsPresent(previous *restic.Node) bool {
 	return true
 }
 
+// reuseContent returns a node for the unchanged file at target which
+// references the given content.
+func (arch *Archiver) reuseContent(snPath, target string, fi os.FileInfo, content restic.IDs) (FutureNode, *restic.Node, error) {
+	node, err := arch.nodeFromFileInfo(snPath, target, fi)
+	if err != nil {
+		return FutureNode{}, nil, err
+	}
+
+	// copy list of blobs
+	node.Content = content
+
+	fn := newFutureNodeWithResult(futureNodeResult{
+		snPath: snPath,
+		target: target,
+		node:   node,
+	})
+	return fn, node, nil
+}
+
+// reuseUnchanged returns the node from the parent snapshot for an item which
+// the change source reports as unchanged. It returns false if the data
+// referenced by the node is not available in the repository, or if an item
+// within a directory is rejected by SelectUnchanged.
+func (arch *Archiver) reuseUnchanged(ctx context.Context, snPath, abstarget, target string, previous *restic.Node, start time.Time) (FutureNode, bool, error) {
+	switch previous.Type {
+	case "file":
+		if !arch.allBlobsPresent(previous) {
+			return FutureNode{}, false, nil
+		}
+		arch.trackItem(snPath, previous, previous, ItemStats{}, time.Since(start))
+		arch.CompleteBlob(previous.Size)
+	case "dir":
+		items, ok, err := arch.unchangedSubtree(ctx, abstarget, previous)
+		if err != nil || !ok {
+			return FutureNode{}, false, err
+		}
+
+		// account for all items within the directory as if they were visited
+		for _, item := range items {
+			p := snPath + item.path
+			if item.node.Type == "dir" {
+				p += "/"
+			}
+			arch.trackItem(p, item.node, item.node, ItemStats{}, 0)
+			if item.node.Type == "file" {
+				arch.CompleteBlob(item.node.Size)
+			}
+		}
+		arch.trackItem(snPath+"/", previous, previous, ItemStats{}, time.Since(start))
+	}
+
+	node := *previous
+	return newFutureNodeWithResult(futureNodeResult{
+		snPath: snPath,
+		target: target,
+		node:   &node,
+	}), true, nil
+}
+
+type unchangedItem struct {
+	path string // slash-separated path below the directory
+	node *restic.Node
+}
+
+// unchangedSubtree returns all items within the directory node from the parent
+// snapshot. It returns false if a tree or the content of a file is not
+// available in the repository or if SelectUnchanged rejects an item.
+func (arch *Archiver) unchangedSubtree(ctx context.Context, abstarget string, dir *restic.Node) ([]unchangedItem, bool, error) {
+	if dir.Subtree == nil || !arch.Repo.Index().Has(restic.BlobHandle{ID: *dir.Subtree, Type: restic.TreeBlob}) {
+		return nil, false, nil
+	}
+
+	var items []unchangedItem
+	errReject := errors.New("reject subtree")
+	err := walker.Walk(ctx, arch.Repo, *dir.Subtree, nil, func(_ restic.ID, nodepath string, node *restic.Node, err error) (bool, error) {
+		if err != nil {
+			debug.Log("unable to load tree for %v: %v", nodepath, err)
+			return false, errReject
+		}
+		if node == nil {
+			return false, nil
+		}
+
+		switch node.Type {
+		case "dir":
+			if node.Subtree == nil || !arch.Repo.Index().Has(restic.BlobHandle{ID: *node.Subtree, Type: restic.TreeBlob}) {
+				return false, errReject
+			}
+		case "file":
+			if !arch.allBlobsPresent(node) {
+				return false, errReject
+			}
+		}
+
+		if arch.SelectUnchanged != nil {
+			item := arch.FS.Join(append([]string{abstarget}, strings.Split(nodepath[1:], "/")...)...)
+			if !arch.SelectUnchanged(item) {
+				debug.Log("%v is excluded, visiting %v", item, abstarget)
+				return false, errReject
+			}
+		}
+
+		items = append(items, unchangedItem{path: nodepath, node: node})
+		return false, nil
+	})
+	if err == errReject {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return items, true, nil
+}
+
 // Save saves a target (file or directory) to the repo. If the item is
 // excluded, this function returns a nil node and error, with excluded set to
 // true.
@@ -346,6 +533,17 @@ func (arch *Archiver) Save(ctx context.Context, snPath, target string, previous
 		return FutureNode{}, true, nil
 	}
 
+	if previous != nil && arch.Changes != nil && !arch.Changes.Changed(abstarget) {
+		fn, ok, err := arch.reuseUnchanged(ctx, snPath, abstarget, target, previous, start)
+		if err != nil {
+			return FutureNode{}, false, err
+		}
+		if ok {
+			debug.Log("%v is reported as unchanged, using node from parent snapshot", target)
+			return fn, false, nil
+		}
+	}
+
 	// get file info and run remaining select functions that require file information
 	fi, err := arch.FS.Lstat(target)
 	if err != nil {
@@ -370,22 +568,10 @@ func (arch *Archiver) Save(ctx context.Context, snPath, target string, previous
 		if previous != nil && !fileChanged(fi, previous, arch.ChangeIgnoreFlags) {
 			if arch.allBlobsPresent(previous) {
 				debug.Log("%v hasn't changed, using old list of blobs", target)
-				arch.CompleteItem(snPath, previous, previous, ItemStats{}, time.Since(start))
+				arch.trackItem(snPath, previous, previous, ItemStats{}, time.Since(start))
 				arch.CompleteBlob(previous.Size)
-				node, err := arch.nodeFromFileInfo(snPath, target, fi)
-				if err != nil {
-					return FutureNode{}, false, err
-				}
-
-				// copy list of blobs
-				node.Content = previous.Content
-
-				fn = newFutureNodeWithResult(futureNodeResult{
-					snPath: snPath,
-					target: target,
-					node:   node,
-				})
-				return fn, false, nil
+				fn, _, err = arch.reuseContent(snPath, target, fi, previous.Content)
+				return fn, false, err
 			}
 
 			debug.Log("%v hasn't changed, but contents are missing!", target)
@@ -397,6 +583,21 @@ func (arch *Archiver) Save(ctx context.Context, snPath, target string, previous
 			}
 		}
 
+		// check if the file was moved here from a different path
+		if arch.inodes != nil && fileChanged(fi, previous, arch.ChangeIgnoreFlags) {
+			moved := arch.inodes.Lookup(fi)
+			if moved != nil && !fileChanged(fi, moved, arch.ChangeIgnoreFlags) && arch.allBlobsPresent(moved) {
+				debug.Log("%v was moved, using old list of blobs", target)
+				fn, node, err := arch.reuseContent(snPath, target, fi, moved.Content)
+				if err != nil {
+					return FutureNode{}, false, err
+				}
+				arch.trackItem(snPath, previous, node, ItemStats{}, time.Since(start))
+				arch.CompleteBlob(node.Size)
+				return fn, false, nil
+			}
+		}
+
 		// reopen file and do an fstat() on the open file to check it is still
 		// a file (and has not been exchanged for e.g. a symlink)
 		file, err := arch.FS.OpenFile(target, fs.O_RDONLY|fs.O_NOFOLLOW, 0)
@@ -435,9 +636,9 @@ func (arch *Archiver) Save(ctx context.Context, snPath, target string, previous
 		fn = arch.fileSaver.Save(ctx, snPath, target, file, fi, func() {
 			arch.StartFile(snPath)
 		}, func() {
-			arch.CompleteItem(snPath, nil, nil, ItemStats{}, 0)
+			arch.trackItem(snPath, nil, nil, ItemStats{}, 0)
 		}, func(node *restic.Node, stats ItemStats) {
-			arch.CompleteItem(snPath, previous, node, stats, time.Since(start))
+			arch.trackItem(snPath, previous, node, stats, time.Since(start))
 		})
 
 	case fi.IsDir():
@@ -454,7 +655,7 @@ func (arch *Archiver) Save(ctx context.Context, snPath, target string, previous
 
 		fn, err = arch.SaveDir(ctx, snPath, target, fi, oldSubtree,
 			func(node *restic.Node, stats ItemStats) {
-				arch.CompleteItem(snItem, previous, node, stats, time.Since(start))
+				arch.trackItem(snItem, previous, node, stats, time.Since(start))
 			})
 		if err != nil {
 			debug.Log("SaveDir for %v returned error: %v", snPath, err)
@@ -610,7 +811,7 @@ func (arch *Archiver) SaveTree(ctx context.Context, snPath string, atree *Tree,
 
 		// not a leaf node, archive subtree
 		fn, _, err := arch.SaveTree(ctx, join(snPath, name), &subatree, oldSubtree, func(n *restic.Node, is ItemStats) {
-			arch.CompleteItem(snItem, oldNode, n, is, time.Since(start))
+			arch.trackItem(snItem, oldNode, n, is, time.Since(start))
 		})
 		if err != nil {
 			return FutureNode{}, 0, err
@@ -676,10 +877,17 @@ func resolveRelativeTargets(filesys fs.FS, targets []string) ([]string, error) {
 // SnapshotOptions collect attributes for a new snapshot.
 type SnapshotOptions struct {
 	Tags           restic.TagList
+	Labels         restic.LabelMap
+	Description    string
 	Hostname       string
 	Excludes       []string
 	Time           time.Time
 	ParentSnapshot *restic.Snapshot
+	// Command is the command line of the program which produced the data.
+	Command []string
+	// BackupStart is recorded in the snapshot summary. If unset, the time
+	// Snapshot was called is used.
+	BackupStart time.Time
 }
 
 // loadParentTree loads a tree referenced by snapshot id. If id is null, nil is returned.
@@ -738,6 +946,26 @@ func (arch *Archiver) Snapshot(ctx context.Context, targets []string, opts Snaps
 		return nil, restic.ID{}, err
 	}
 
+	summary := &restic.SnapshotSummary{BackupStart: opts.BackupStart}
+	if summary.BackupStart.IsZero() {
+		summary.BackupStart = time.Now()
+	}
+	arch.mu.Lock()
+	arch.summary = summary
+	arch.mu.Unlock()
+
+	arch.inodes = nil
+	if arch.DetectMoves && arch.ChangeIgnoreFlags&ChangeIgnoreInode == 0 && opts.ParentSnapshot != nil && opts.ParentSnapshot.Tree != nil {
+		debug.Log("load inode table for parent snapshot %v", opts.ParentSnapshot.ID())
+		arch.inodes, err = LoadInodeTable(ctx, arch.Repo, *opts.ParentSnapshot.Tree)
+		if err != nil {
+			err = arch.error("/", errors.Errorf("unable to load files of the parent snapshot: %v", err))
+			if err != nil {
+				return nil, restic.ID{}, err
+			}
+		}
+	}
+
 	var rootTreeID restic.ID
 
 	wgUp, wgUpCtx := errgroup.WithContext(ctx)
@@ -752,7 +980,7 @@ func (arch *Archiver) Snapshot(ctx context.Context, targets []string, opts Snaps
 
 			debug.Log("starting snapshot")
 			fn, nodeCount, err := arch.SaveTree(wgCtx, "/", atree, arch.loadParentTree(wgCtx, opts.ParentSnapshot), func(n *restic.Node, is ItemStats) {
-				arch.CompleteItem("/", nil, nil, is, time.Since(start))
+				arch.trackItem("/", nil, nil, is, time.Since(start))
 			})
 			if err != nil {
 				return err
@@ -797,11 +1025,22 @@ func (arch *Archiver) Snapshot(ctx context.Context, targets []string, opts Snaps
 	}
 
 	sn.Excludes = opts.Excludes
+	sn.Description = opts.Description
+	sn.Command = opts.Command
+	if len(opts.Labels) > 0 {
+		sn.Labels = opts.Labels
+	}
 	if opts.ParentSnapshot != nil {
 		sn.Parent = opts.ParentSnapshot.ID()
 	}
 	sn.Tree = &rootTreeID
 
+	arch.mu.Lock()
+	summary.BackupEnd = time.Now()
+	sn.Summary = summary
+	arch.summary = nil
+	arch.mu.Unlock()
+
 	id, err := restic.SaveSnapshot(ctx, arch.Repo, sn)
 	if err != nil {
 		return nil, restic.ID{}, err
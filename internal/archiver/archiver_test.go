@@ -1,6 +1,7 @@
 package archiver
 
 import (
+	"archive/tar"
 	"bytes"
 	"context"
 	"io"
@@ -347,6 +348,77 @@ func TestArchiverSaveReaderFS(t *testing.T) {
 	}
 }
 
+func TestArchiverSnapshotArchiveFS(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := repository.TestRepository(t)
+	modtime := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range []*tar.Header{
+		{Name: "dir/file", Typeflag: tar.TypeReg, Mode: 0640, Uid: 1000, Uname: "user", Size: 3,
+			PAXRecords: map[string]string{"SCHILY.xattr.user.foo": "bar"}},
+		{Name: "dir/link", Typeflag: tar.TypeLink, Linkname: "dir/file"},
+		{Name: "symlink", Typeflag: tar.TypeSymlink, Linkname: "dir/file", Mode: 0777},
+	} {
+		hdr.ModTime = modtime
+		restictest.OK(t, tw.WriteHeader(hdr))
+		if hdr.Size > 0 {
+			_, err := tw.Write([]byte("foo"))
+			restictest.OK(t, err)
+		}
+	}
+	restictest.OK(t, tw.Close())
+
+	archive, err := fs.NewTarArchive(&buf, "export")
+	restictest.OK(t, err)
+	defer func() {
+		restictest.OK(t, archive.Close())
+	}()
+
+	arch := New(repo, archive, Options{})
+	_, id, err := arch.Snapshot(ctx, []string{archive.Name}, SnapshotOptions{Time: time.Now()})
+	restictest.OK(t, err)
+
+	sn, err := restic.LoadSnapshot(ctx, repo, id)
+	restictest.OK(t, err)
+	restictest.Equals(t, []string{"/export"}, sn.Paths)
+
+	loadSubtree := func(tree *restic.Tree, name string) *restic.Tree {
+		node := tree.Find(name)
+		restictest.Assert(t, node != nil && node.Type == "dir", "dir %v not found", name)
+		subtree, err := restic.LoadTree(ctx, repo, *node.Subtree)
+		restictest.OK(t, err)
+		return subtree
+	}
+
+	root, err := restic.LoadTree(ctx, repo, *sn.Tree)
+	restictest.OK(t, err)
+	export := loadSubtree(root, "export")
+	dir := loadSubtree(export, "dir")
+
+	symlink := export.Find("symlink")
+	restictest.Assert(t, symlink != nil, "symlink not found")
+	restictest.Equals(t, "dir/file", symlink.LinkTarget)
+
+	file, link := dir.Find("file"), dir.Find("link")
+	restictest.Assert(t, file != nil && link != nil, "files not found")
+	for _, node := range []*restic.Node{file, link} {
+		TestEnsureFileContent(ctx, t, repo, node.Name, node, TestFile{Content: "foo"})
+		restictest.Equals(t, uint32(1000), node.UID)
+		restictest.Equals(t, "user", node.User)
+		restictest.Equals(t, os.FileMode(0640), node.Mode)
+		restictest.Assert(t, node.ModTime.Equal(modtime), "wrong modtime %v", node.ModTime)
+		restictest.Equals(t, uint64(2), node.Links)
+		restictest.Equals(t, file.Inode, node.Inode)
+		restictest.Equals(t, []restic.ExtendedAttribute{{Name: "user.foo", Value: []byte("bar")}}, node.ExtendedAttributes)
+	}
+
+	checker.TestCheckRepo(t, repo)
+}
+
 func BenchmarkArchiverSaveFileSmall(b *testing.B) {
 	const fileSize = 4 * 1024
 	d := TestDir{"file": TestFile{
@@ -1708,6 +1780,64 @@ func TestArchiverParent(t *testing.T) {
 	}
 }
 
+func TestArchiverSnapshotSummary(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	src := TestDir{
+		"foo": TestFile{Content: "foo"},
+		"subdir": TestDir{
+			"bar": TestFile{Content: "barbaz"},
+		},
+	}
+
+	tempdir, repo := prepareTempdirRepoSrc(t, src)
+	arch := New(repo, fs.Track{FS: fs.Local{}}, Options{})
+
+	back := restictest.Chdir(t, tempdir)
+	defer back()
+
+	start := time.Now()
+	firstSnapshot, _, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now(), BackupStart: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary := firstSnapshot.Summary
+	if summary == nil {
+		t.Fatal("snapshot has no summary")
+	}
+	restictest.Equals(t, start, summary.BackupStart)
+	restictest.Assert(t, !summary.BackupEnd.Before(summary.BackupStart), "backup end %v is before start %v", summary.BackupEnd, summary.BackupStart)
+	restictest.Equals(t, uint(2), summary.FilesNew)
+	restictest.Equals(t, uint(0), summary.FilesChanged)
+	restictest.Equals(t, uint(0), summary.FilesUnmodified)
+	restictest.Assert(t, summary.DirsNew > 0, "expected new dirs, got %v", summary.DirsNew)
+	restictest.Equals(t, 2, summary.DataBlobs)
+	restictest.Assert(t, summary.TreeBlobs > 0, "expected new tree blobs, got %v", summary.TreeBlobs)
+	restictest.Assert(t, summary.DataAdded > 0, "expected added data, got %v", summary.DataAdded)
+	restictest.Equals(t, uint(2), summary.TotalFilesProcessed)
+	restictest.Equals(t, uint64(9), summary.TotalBytesProcessed)
+
+	secondSnapshot, _, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now(), ParentSnapshot: firstSnapshot})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary = secondSnapshot.Summary
+	if summary == nil {
+		t.Fatal("snapshot has no summary")
+	}
+	restictest.Assert(t, !summary.BackupStart.IsZero(), "backup start is not set")
+	restictest.Equals(t, uint(0), summary.FilesNew)
+	restictest.Equals(t, uint(2), summary.FilesUnmodified)
+	restictest.Equals(t, uint(0), summary.DirsNew)
+	restictest.Equals(t, 0, summary.DataBlobs)
+	restictest.Equals(t, uint64(0), summary.DataAdded)
+	restictest.Equals(t, uint(2), summary.TotalFilesProcessed)
+	restictest.Equals(t, uint64(9), summary.TotalBytesProcessed)
+}
+
 func TestArchiverErrorReporting(t *testing.T) {
 	ignoreErrorForBasename := func(basename string) ErrorFunc {
 		return func(item string, err error) error {
@@ -2244,3 +2374,144 @@ func TestRacyFileSwap(t *testing.T) {
 		t.Errorf("Save() excluded the node, that's unexpected")
 	}
 }
+
+func TestArchiverChangeSource(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	src := TestDir{
+		"dir1": TestDir{
+			"a": TestFile{Content: "aaa"},
+		},
+		"dir2": TestDir{
+			"b": TestFile{Content: "bbb"},
+		},
+		"c": TestFile{Content: "ccc"},
+	}
+
+	tempdir, repo := prepareTempdirRepoSrc(t, src)
+	arch := New(repo, fs.Track{FS: fs.Local{}}, Options{})
+
+	back := restictest.Chdir(t, tempdir)
+	defer back()
+
+	parent, _, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"dir1/a", "dir2/b", "c"} {
+		save(t, filepath.FromSlash(name), []byte("modified"))
+	}
+	save(t, filepath.Join("dir1", "new"), []byte("new"))
+
+	changed, err := filepath.Abs("dir1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only dir1 is reported as modified, so the other items must be taken
+	// from the parent snapshot
+	arch.Changes = NewChangeSet(changed)
+	sn, id, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now(), ParentSnapshot: parent})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	TestEnsureSnapshot(t, repo, id, TestDir{
+		"dir1": TestDir{
+			"a":   TestFile{Content: "modified"},
+			"new": TestFile{Content: "new"},
+		},
+		"dir2": TestDir{
+			"b": TestFile{Content: "bbb"},
+		},
+		"c": TestFile{Content: "ccc"},
+	})
+	restictest.Equals(t, uint(1), sn.Summary.FilesNew)
+	restictest.Equals(t, uint(1), sn.Summary.FilesChanged)
+	// the file within dir2 is counted although dir2 is not visited
+	restictest.Equals(t, uint(2), sn.Summary.FilesUnmodified)
+	restictest.Equals(t, uint(4), sn.Summary.TotalFilesProcessed)
+	restictest.Equals(t, uint(1), sn.Summary.DirsUnmodified)
+
+	// items excluded since the parent snapshot was created must be excluded
+	// even if their directory is reported as unchanged
+	arch.SelectByName = func(item string) bool {
+		return filepath.Base(item) != "b"
+	}
+	arch.SelectUnchanged = arch.SelectByName
+	_, id, err = arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now(), ParentSnapshot: parent})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	TestEnsureSnapshot(t, repo, id, TestDir{
+		"dir1": TestDir{
+			"a":   TestFile{Content: "modified"},
+			"new": TestFile{Content: "new"},
+		},
+		"dir2": TestDir{},
+		"c":    TestFile{Content: "ccc"},
+	})
+
+	checker.TestCheckRepo(t, repo)
+}
+
+func TestArchiverDetectMoves(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	src := TestDir{
+		"dir": TestDir{
+			"a": TestFile{Content: string(restictest.Random(23, 300*1024))},
+			"b": TestFile{Content: "bbb"},
+		},
+		"c": TestFile{Content: "ccc"},
+	}
+
+	tempdir, repo := prepareTempdirRepoSrc(t, src)
+
+	testFS := &MockFS{
+		FS:        fs.Track{FS: fs.Local{}},
+		bytesRead: make(map[string]int),
+	}
+	arch := New(repo, testFS, Options{})
+	arch.DetectMoves = true
+
+	back := restictest.Chdir(t, tempdir)
+	defer back()
+
+	parent, _, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rename(t, "dir", "moved")
+	rename(t, "c", filepath.Join("moved", "c"))
+	save(t, "new", []byte("new"))
+	testFS.bytesRead = make(map[string]int)
+
+	sn, id, err := arch.Snapshot(ctx, []string{"."}, SnapshotOptions{Time: time.Now(), ParentSnapshot: parent})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	TestEnsureSnapshot(t, repo, id, TestDir{
+		"moved": TestDir{
+			"a": src["dir"].(TestDir)["a"],
+			"b": TestFile{Content: "bbb"},
+			"c": TestFile{Content: "ccc"},
+		},
+		"new": TestFile{Content: "new"},
+	})
+
+	// the files in the moved directory must not have been read, renaming c
+	// may have changed its ctime
+	delete(testFS.bytesRead, filepath.Join("moved", "c"))
+	restictest.Equals(t, map[string]int{"new": 3}, testFS.bytesRead)
+	restictest.Equals(t, uint(4), sn.Summary.FilesNew)
+	restictest.Equals(t, 1, sn.Summary.DataBlobs)
+
+	checker.TestCheckRepo(t, repo)
+}
@@ -0,0 +1,258 @@
+package archiver
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/restic/restic/internal/errors"
+)
+
+// ChangeSource reports which items may have been modified since the parent
+// snapshot was created. The archiver only visits items for which Changed
+// returns true and reuses the nodes from the parent snapshot for all others,
+// without calling lstat() or reading the directory.
+type ChangeSource interface {
+	// Changed returns true if the item at the absolute path, or any item
+	// below it, may have been created, modified or removed.
+	Changed(path string) bool
+}
+
+// ChangeSet is a ChangeSource for a list of modified paths.
+type ChangeSet struct {
+	// trees contains the paths for which all items below are modified
+	trees map[string]struct{}
+	// items contains the paths for which only the item itself, for a
+	// directory this includes the list of entries, is modified
+	items map[string]struct{}
+	// parents contains all parent directories of trees and items
+	parents map[string]struct{}
+}
+
+// NewChangeSet returns a ChangeSet containing the given absolute paths, see
+// Add.
+func NewChangeSet(paths ...string) *ChangeSet {
+	s := &ChangeSet{
+		trees:   make(map[string]struct{}),
+		items:   make(map[string]struct{}),
+		parents: make(map[string]struct{}),
+	}
+	for _, p := range paths {
+		s.Add(p)
+	}
+	return s
+}
+
+// Add marks the absolute path p and all items below it as modified.
+func (s *ChangeSet) Add(p string) {
+	p = filepath.Clean(p)
+	s.trees[p] = struct{}{}
+	s.addParents(p)
+}
+
+// AddItem marks only the item at the absolute path p as modified. For a
+// directory, its entries are listed again, but each entry is only visited if
+// it is reported as modified as well.
+func (s *ChangeSet) AddItem(p string) {
+	p = filepath.Clean(p)
+	s.items[p] = struct{}{}
+	s.addParents(p)
+}
+
+func (s *ChangeSet) addParents(p string) {
+	for {
+		dir := filepath.Dir(p)
+		if dir == p {
+			return
+		}
+		if _, ok := s.parents[dir]; ok {
+			return
+		}
+		s.parents[dir] = struct{}{}
+		p = dir
+	}
+}
+
+// Len returns the number of paths in the set.
+func (s *ChangeSet) Len() int {
+	return len(s.trees) + len(s.items)
+}
+
+// Changed returns true if path or an item below it was added to the set, or
+// if path is below a directory added with Add.
+func (s *ChangeSet) Changed(path string) bool {
+	path = filepath.Clean(path)
+	if _, ok := s.parents[path]; ok {
+		return true
+	}
+	if _, ok := s.items[path]; ok {
+		return true
+	}
+
+	for {
+		if _, ok := s.trees[path]; ok {
+			return true
+		}
+		dir := filepath.Dir(path)
+		if dir == path {
+			return false
+		}
+		path = dir
+	}
+}
+
+// changeSourceProviders maps the names accepted by NewChangeSource to the
+// functions constructing the change source from the argument.
+var changeSourceProviders = map[string]func(arg string) (ChangeSource, error){
+	"list":     readChangeList,
+	"zfs-diff": readZFSDiff,
+}
+
+// ChangeSourceProviders returns the names of all available providers.
+func ChangeSourceProviders() []string {
+	var names []string
+	for name := range changeSourceProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewChangeSource returns the change source described by spec, which has the
+// form "provider:argument". Available providers are:
+//
+//	list:file      a file with one modified absolute path per line, as
+//	               written for example by an inotify or fanotify daemon
+//	zfs-diff:file  the output of "zfs diff -H" between the snapshot
+//	               the parent backup was created from and the current one
+//
+// There are no providers which watch the file system or compare Btrfs
+// snapshots, such tools can write a file for the list provider.
+func NewChangeSource(spec string) (ChangeSource, error) {
+	provider, arg, found := strings.Cut(spec, ":")
+	if !found || arg == "" {
+		return nil, errors.Errorf("invalid change source %q, expected provider:argument", spec)
+	}
+
+	fn, ok := changeSourceProviders[provider]
+	if !ok {
+		return nil, errors.Errorf("unknown change source provider %q, available: %v", provider, strings.Join(ChangeSourceProviders(), ", "))
+	}
+	return fn(arg)
+}
+
+// readChangeList reads a file containing one absolute path per line. Empty
+// lines are ignored.
+func readChangeList(filename string) (ChangeSource, error) {
+	s := NewChangeSet()
+	err := readLines(filename, func(line string) error {
+		if line == "" {
+			return nil
+		}
+		if !filepath.IsAbs(line) {
+			return errors.Errorf("path %q is not absolute", line)
+		}
+		s.Add(line)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// readZFSDiff reads the output of "zfs diff -H", optionally with -F. Each
+// line consists of the change type, the file type if -F was used and the
+// path, separated by tabs. Renames list both the old and the new path.
+// Modified directories are listed along with the modified items within, so
+// only created or renamed items need to be visited completely.
+func readZFSDiff(filename string) (ChangeSource, error) {
+	s := NewChangeSet()
+	err := readLines(filename, func(line string) error {
+		if line == "" {
+			return nil
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			return errors.Errorf("invalid zfs diff line %q", line)
+		}
+
+		var paths []string
+		for _, p := range fields[1:] {
+			p, err := unescapeZFSPath(p)
+			if err != nil {
+				return err
+			}
+			if filepath.IsAbs(p) {
+				paths = append(paths, p)
+			}
+		}
+
+		switch {
+		case len(paths) == 1 && fields[0] == "+":
+			s.Add(paths[0])
+		case len(paths) == 1 && (fields[0] == "M" || fields[0] == "-"):
+			s.AddItem(paths[0])
+		case len(paths) == 2 && fields[0] == "R":
+			s.AddItem(paths[0])
+			s.Add(paths[1])
+		default:
+			return errors.Errorf("invalid zfs diff line %q", line)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// unescapeZFSPath decodes the escape sequences "\0ooo" zfs diff uses for
+// special characters in paths.
+func unescapeZFSPath(p string) (string, error) {
+	if !strings.Contains(p, `\`) {
+		return p, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(p); i++ {
+		if p[i] != '\\' {
+			sb.WriteByte(p[i])
+			continue
+		}
+		if i+4 >= len(p) {
+			return "", errors.Errorf("invalid escape sequence in path %q", p)
+		}
+		c, err := strconv.ParseUint(p[i+1:i+5], 8, 8)
+		if err != nil {
+			return "", errors.Errorf("invalid escape sequence in path %q", p)
+		}
+		sb.WriteByte(byte(c))
+		i += 4
+	}
+	return sb.String(), nil
+}
+
+func readLines(filename string, fn func(line string) error) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if err := fn(sc.Text()); err != nil {
+			_ = f.Close()
+			return errors.Wrapf(err, "%v", filename)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		_ = f.Close()
+		return errors.Wrapf(err, "%v", filename)
+	}
+	return errors.WithStack(f.Close())
+}
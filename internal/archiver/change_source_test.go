@@ -0,0 +1,77 @@
+package archiver
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func TestChangeSet(t *testing.T) {
+	s := NewChangeSet("/home/user/dir", "/home/user/file", "/srv/data/")
+	s.AddItem("/var/log")
+
+	for _, test := range []struct {
+		path    string
+		changed bool
+	}{
+		{"/", true},
+		{"/home", true},
+		{"/home/user", true},
+		{"/home/user/dir", true},
+		{"/home/user/dir/sub/file", true},
+		{"/home/user/file", true},
+		{"/home/user/file2", false},
+		{"/home/user/other", false},
+		{"/home/other", false},
+		{"/srv/data", true},
+		{"/srv/data/x", true},
+		{"/srv/database", false},
+		{"/etc", false},
+		{"/var", true},
+		{"/var/log", true},
+		{"/var/log/messages", false},
+	} {
+		rtest.Assert(t, s.Changed(filepath.FromSlash(test.path)) == test.changed,
+			"wrong result for %v, want changed=%v", test.path, test.changed)
+	}
+}
+
+func TestNewChangeSource(t *testing.T) {
+	dir := rtest.TempDir(t)
+
+	list := filepath.Join(dir, "list")
+	rtest.OK(t, os.WriteFile(list, []byte("/data/a\n\n/data/b/c\n"), 0600))
+
+	cs, err := NewChangeSource("list:" + list)
+	rtest.OK(t, err)
+	rtest.Assert(t, cs.Changed("/data/a"), "/data/a not changed")
+	rtest.Assert(t, cs.Changed("/data/b"), "/data/b not changed")
+	rtest.Assert(t, !cs.Changed("/data/d"), "/data/d changed")
+
+	zfsDiff := filepath.Join(dir, "zfs-diff")
+	rtest.OK(t, os.WriteFile(zfsDiff, []byte("M\t/\nM\t/tank/dir\n+\tF\t/tank/dir/new\\0040file\nR\t/tank/old\t/tank/moved/here\n-\t/tank/removed\n"), 0600))
+
+	cs, err = NewChangeSource("zfs-diff:" + zfsDiff)
+	rtest.OK(t, err)
+	for _, p := range []string{"/tank/dir/new file", "/tank/old", "/tank/moved/here", "/tank/removed"} {
+		rtest.Assert(t, cs.Changed(p), "%v not changed", p)
+	}
+	for _, p := range []string{"/tank/other", "/tank/dir/unmodified"} {
+		rtest.Assert(t, !cs.Changed(p), "%v changed", p)
+	}
+
+	rtest.OK(t, os.WriteFile(list, []byte("relative/path\n"), 0600))
+	_, err = NewChangeSource("list:" + list)
+	rtest.Assert(t, err != nil, "relative path not rejected")
+
+	rtest.OK(t, os.WriteFile(zfsDiff, []byte("X\t/tank/dir\n"), 0600))
+	_, err = NewChangeSource("zfs-diff:" + zfsDiff)
+	rtest.Assert(t, err != nil, "invalid change type not rejected")
+
+	for _, spec := range []string{"", "list", "list:", "foo:bar"} {
+		_, err = NewChangeSource(spec)
+		rtest.Assert(t, err != nil, "invalid spec %q not rejected", spec)
+	}
+}
@@ -0,0 +1,78 @@
+package archiver
+
+import (
+	"context"
+	"os"
+
+	"github.com/restic/restic/internal/debug"
+	"github.com/restic/restic/internal/fs"
+	"github.com/restic/restic/internal/restic"
+	"github.com/restic/restic/internal/walker"
+)
+
+type inodeKey struct {
+	deviceID, inode uint64
+}
+
+// InodeTable maps the device IDs and inode numbers of the files in a snapshot
+// to their nodes. It allows finding files which were moved or renamed since
+// the snapshot was created.
+type InodeTable struct {
+	m map[inodeKey]*restic.Node
+}
+
+// LoadInodeTable collects all files in the tree with the given ID. Only the
+// attributes used for change detection and the content are kept for each
+// file. Subtrees which cannot be loaded are skipped.
+func LoadInodeTable(ctx context.Context, repo restic.BlobLoader, root restic.ID) (*InodeTable, error) {
+	t := &InodeTable{m: make(map[inodeKey]*restic.Node)}
+
+	err := walker.Walk(ctx, repo, root, nil, func(_ restic.ID, nodepath string, node *restic.Node, err error) (bool, error) {
+		if err != nil {
+			debug.Log("unable to load tree for %v: %v", nodepath, err)
+			return false, walker.ErrSkipNode
+		}
+		if node == nil || node.Type == "dir" {
+			return false, nil
+		}
+		if node.Type != "file" || node.Inode == 0 {
+			return true, nil
+		}
+
+		key := inodeKey{deviceID: node.DeviceID, inode: node.Inode}
+		if _, ok := t.m[key]; ok {
+			// hard links share the content
+			return true, nil
+		}
+		t.m[key] = &restic.Node{
+			Type:       node.Type,
+			Size:       node.Size,
+			ModTime:    node.ModTime,
+			ChangeTime: node.ChangeTime,
+			Inode:      node.Inode,
+			DeviceID:   node.DeviceID,
+			Content:    node.Content,
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Len returns the number of files in the table.
+func (t *InodeTable) Len() int {
+	return len(t.m)
+}
+
+// Lookup returns the node of the file with the same device ID and inode
+// number as fi, or nil if there is no such file.
+func (t *InodeTable) Lookup(fi os.FileInfo) *restic.Node {
+	extFI := fs.ExtendedStat(fi)
+	if extFI.Inode == 0 {
+		return nil
+	}
+	return t.m[inodeKey{deviceID: extFI.DeviceID, inode: extFI.Inode}]
+}
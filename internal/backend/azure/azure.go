@@ -310,6 +310,9 @@ func (be *Backend) Stat(ctx context.Context, h restic.Handle) (restic.FileInfo,
 		Size: *props.ContentLength,
 		Name: h.Name,
 	}
+	if props.LastModified != nil {
+		fi.ModTime = *props.LastModified
+	}
 	return fi, nil
 }
 
@@ -364,6 +367,9 @@ func (be *Backend) List(ctx context.Context, t restic.FileType, fn func(restic.F
 				Name: path.Base(m),
 				Size: *item.Properties.ContentLength,
 			}
+			if item.Properties.LastModified != nil {
+				fi.ModTime = *item.Properties.LastModified
+			}
 
 			if ctx.Err() != nil {
 				return ctx.Err()
@@ -242,7 +242,7 @@ func (be *b2Backend) Stat(ctx context.Context, h restic.Handle) (bi restic.FileI
 	if err != nil {
 		return restic.FileInfo{}, errors.Wrap(err, "Stat")
 	}
-	return restic.FileInfo{Size: info.Size, Name: h.Name}, nil
+	return restic.FileInfo{Size: info.Size, Name: h.Name, ModTime: info.UploadTimestamp}, nil
 }
 
 // Remove removes the blob with the given name and type.
@@ -298,8 +298,9 @@ func (be *b2Backend) List(ctx context.Context, t restic.FileType, fn func(restic
 		}
 
 		fi := restic.FileInfo{
-			Name: path.Base(obj.Name()),
-			Size: attrs.Size,
+			Name:    path.Base(obj.Name()),
+			Size:    attrs.Size,
+			ModTime: attrs.UploadTimestamp,
 		}
 
 		if err := fn(fi); err != nil {
@@ -281,7 +281,7 @@ func (be *Backend) Stat(ctx context.Context, h restic.Handle) (bi restic.FileInf
 		return restic.FileInfo{}, errors.Wrap(err, "service.Objects.Get")
 	}
 
-	return restic.FileInfo{Size: attr.Size, Name: h.Name}, nil
+	return restic.FileInfo{Size: attr.Size, Name: h.Name, ModTime: attr.Updated}, nil
 }
 
 // Remove removes the blob with the given name and type.
@@ -326,8 +326,9 @@ func (be *Backend) List(ctx context.Context, t restic.FileType, fn func(restic.F
 		}
 
 		fi := restic.FileInfo{
-			Name: path.Base(m),
-			Size: int64(attrs.Size),
+			Name:    path.Base(m),
+			Size:    int64(attrs.Size),
+			ModTime: attrs.Updated,
 		}
 
 		err = fn(fi)
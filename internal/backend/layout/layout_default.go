@@ -21,6 +21,8 @@ var defaultLayoutPaths = map[restic.FileType]string{
 	restic.IndexFile:    "index",
 	restic.LockFile:     "locks",
 	restic.KeyFile:      "keys",
+	restic.PendingFile:  "pending",
+	restic.CheckFile:    "checks",
 }
 
 func (l *DefaultLayout) String() string {
@@ -46,8 +48,8 @@ func (l *DefaultLayout) Dirname(h restic.Handle) string {
 // Filename returns a path to a file, including its name.
 func (l *DefaultLayout) Filename(h restic.Handle) string {
 	name := h.Name
-	if h.Type == restic.ConfigFile {
-		return l.Join(l.Path, "config")
+	if h.Type == restic.ConfigFile || h.Type == restic.PolicyFile {
+		return l.Join(l.Path, h.Type.String())
 	}
 
 	return l.Join(l.Dirname(h), name)
@@ -22,7 +22,7 @@ func (l *RESTLayout) Name() string {
 
 // Dirname returns the directory path for a given file type and name.
 func (l *RESTLayout) Dirname(h restic.Handle) string {
-	if h.Type == restic.ConfigFile {
+	if h.Type == restic.ConfigFile || h.Type == restic.PolicyFile {
 		return l.URL + l.Join(l.Path, "/")
 	}
 
@@ -33,8 +33,8 @@ func (l *RESTLayout) Dirname(h restic.Handle) string {
 func (l *RESTLayout) Filename(h restic.Handle) string {
 	name := h.Name
 
-	if h.Type == restic.ConfigFile {
-		name = "config"
+	if h.Type == restic.ConfigFile || h.Type == restic.PolicyFile {
+		name = h.Type.String()
 	}
 
 	return l.URL + l.Join(l.Path, "/", restLayoutPaths[h.Type], name)
@@ -16,6 +16,8 @@ var s3LayoutPaths = map[restic.FileType]string{
 	restic.IndexFile:    "index",
 	restic.LockFile:     "lock",
 	restic.KeyFile:      "key",
+	restic.PendingFile:  "pending",
+	restic.CheckFile:    "check",
 }
 
 func (l *S3LegacyLayout) String() string {
@@ -45,7 +47,7 @@ func (l *S3LegacyLayout) join(url string, items ...string) string {
 
 // Dirname returns the directory path for a given file type and name.
 func (l *S3LegacyLayout) Dirname(h restic.Handle) string {
-	if h.Type == restic.ConfigFile {
+	if h.Type == restic.ConfigFile || h.Type == restic.PolicyFile {
 		return l.URL + l.Join(l.Path, "/")
 	}
 
@@ -56,8 +58,8 @@ func (l *S3LegacyLayout) Dirname(h restic.Handle) string {
 func (l *S3LegacyLayout) Filename(h restic.Handle) string {
 	name := h.Name
 
-	if h.Type == restic.ConfigFile {
-		name = "config"
+	if h.Type == restic.ConfigFile || h.Type == restic.PolicyFile {
+		name = h.Type.String()
 	}
 
 	return l.join(l.URL, l.Path, s3LayoutPaths[h.Type], name)
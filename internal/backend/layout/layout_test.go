@@ -34,6 +34,12 @@ func TestDefaultLayout(t *testing.T) {
 			restic.Handle{Type: restic.ConfigFile, Name: "CFG"},
 			filepath.Join(tempdir, "config"),
 		},
+		{
+			tempdir,
+			filepath.Join,
+			restic.Handle{Type: restic.PolicyFile},
+			filepath.Join(tempdir, "policy"),
+		},
 		{
 			tempdir,
 			filepath.Join,
@@ -70,6 +76,12 @@ func TestDefaultLayout(t *testing.T) {
 			restic.Handle{Type: restic.ConfigFile, Name: "CFG"},
 			"config",
 		},
+		{
+			"",
+			path.Join,
+			restic.Handle{Type: restic.PolicyFile},
+			"policy",
+		},
 		{
 			"",
 			path.Join,
@@ -110,6 +122,8 @@ func TestDefaultLayout(t *testing.T) {
 			filepath.Join(tempdir, "index"),
 			filepath.Join(tempdir, "locks"),
 			filepath.Join(tempdir, "keys"),
+			filepath.Join(tempdir, "pending"),
+			filepath.Join(tempdir, "checks"),
 		}
 
 		for i := 0; i < 256; i++ {
@@ -186,6 +200,8 @@ func TestRESTLayout(t *testing.T) {
 			filepath.Join(path, "index"),
 			filepath.Join(path, "locks"),
 			filepath.Join(path, "keys"),
+			filepath.Join(path, "pending"),
+			filepath.Join(path, "checks"),
 		}
 
 		sort.Strings(want)
@@ -231,6 +247,12 @@ func TestRESTLayoutURLs(t *testing.T) {
 			"https://hostname.foo:1234/prefix/repo/config",
 			"https://hostname.foo:1234/prefix/repo/",
 		},
+		{
+			&RESTLayout{URL: "https://hostname.foo:1234/prefix/repo", Path: "/", Join: path.Join},
+			restic.Handle{Type: restic.PolicyFile},
+			"https://hostname.foo:1234/prefix/repo/policy",
+			"https://hostname.foo:1234/prefix/repo/",
+		},
 		{
 			&S3LegacyLayout{URL: "https://hostname.foo", Path: "/", Join: path.Join},
 			restic.Handle{Type: restic.PackFile, Name: "foobar"},
@@ -249,6 +271,12 @@ func TestRESTLayoutURLs(t *testing.T) {
 			"https://hostname.foo:1234/prefix/repo/config",
 			"https://hostname.foo:1234/prefix/repo/",
 		},
+		{
+			&S3LegacyLayout{URL: "https://hostname.foo:1234/prefix/repo", Path: "/", Join: path.Join},
+			restic.Handle{Type: restic.PolicyFile},
+			"https://hostname.foo:1234/prefix/repo/policy",
+			"https://hostname.foo:1234/prefix/repo/",
+		},
 		{
 			&S3LegacyLayout{URL: "", Path: "", Join: path.Join},
 			restic.Handle{Type: restic.PackFile, Name: "foobar"},
@@ -331,6 +359,8 @@ func TestS3LegacyLayout(t *testing.T) {
 			filepath.Join(path, "index"),
 			filepath.Join(path, "lock"),
 			filepath.Join(path, "key"),
+			filepath.Join(path, "pending"),
+			filepath.Join(path, "check"),
 		}
 
 		sort.Strings(want)
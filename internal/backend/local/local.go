@@ -228,7 +228,7 @@ func (b *Local) Stat(_ context.Context, h restic.Handle) (restic.FileInfo, error
 		return restic.FileInfo{}, errors.WithStack(err)
 	}
 
-	return restic.FileInfo{Size: fi.Size(), Name: h.Name}, nil
+	return restic.FileInfo{Size: fi.Size(), Name: h.Name, ModTime: fi.ModTime()}, nil
 }
 
 // Remove removes the blob with the given name and type.
@@ -328,8 +328,9 @@ func visitFiles(ctx context.Context, dir string, fn func(restic.FileInfo) error,
 		}
 
 		err := fn(restic.FileInfo{
-			Name: fi.Name(),
-			Size: fi.Size(),
+			Name:    fi.Name(),
+			Size:    fi.Size(),
+			ModTime: fi.ModTime(),
 		})
 		if err != nil {
 			return err
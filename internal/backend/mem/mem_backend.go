@@ -7,6 +7,7 @@ import (
 	"hash"
 	"io"
 	"sync"
+	"time"
 
 	"github.com/cespare/xxhash/v2"
 	"github.com/restic/restic/internal/backend"
@@ -27,14 +28,16 @@ const connectionCount = 2
 // MemoryBackend is a mock backend that uses a map for storing all data in
 // memory. This should only be used for tests.
 type MemoryBackend struct {
-	data memMap
-	m    sync.Mutex
+	data    memMap
+	modTime map[restic.Handle]time.Time
+	m       sync.Mutex
 }
 
 // New returns a new backend that saves all data in a map in memory.
 func New() *MemoryBackend {
 	be := &MemoryBackend{
-		data: make(memMap),
+		data:    make(memMap),
+		modTime: make(map[restic.Handle]time.Time),
 	}
 
 	debug.Log("created new memory backend")
@@ -53,7 +56,7 @@ func (be *MemoryBackend) Save(ctx context.Context, h restic.Handle, rd restic.Re
 	defer be.m.Unlock()
 
 	h.ContainedBlobType = restic.InvalidBlob
-	if h.Type == restic.ConfigFile {
+	if h.Type == restic.ConfigFile || h.Type == restic.PolicyFile {
 		h.Name = ""
 	}
 
@@ -85,6 +88,7 @@ func (be *MemoryBackend) Save(ctx context.Context, h restic.Handle, rd restic.Re
 	}
 
 	be.data[h] = buf
+	be.modTime[h] = time.Now()
 
 	return ctx.Err()
 }
@@ -100,7 +104,7 @@ func (be *MemoryBackend) openReader(ctx context.Context, h restic.Handle, length
 	defer be.m.Unlock()
 
 	h.ContainedBlobType = restic.InvalidBlob
-	if h.Type == restic.ConfigFile {
+	if h.Type == restic.ConfigFile || h.Type == restic.PolicyFile {
 		h.Name = ""
 	}
 
@@ -127,7 +131,7 @@ func (be *MemoryBackend) Stat(ctx context.Context, h restic.Handle) (restic.File
 	defer be.m.Unlock()
 
 	h.ContainedBlobType = restic.InvalidBlob
-	if h.Type == restic.ConfigFile {
+	if h.Type == restic.ConfigFile || h.Type == restic.PolicyFile {
 		h.Name = ""
 	}
 
@@ -136,7 +140,7 @@ func (be *MemoryBackend) Stat(ctx context.Context, h restic.Handle) (restic.File
 		return restic.FileInfo{}, errNotFound
 	}
 
-	return restic.FileInfo{Size: int64(len(e)), Name: h.Name}, ctx.Err()
+	return restic.FileInfo{Size: int64(len(e)), Name: h.Name, ModTime: be.modTime[h]}, ctx.Err()
 }
 
 // Remove deletes a file from the backend.
@@ -150,13 +154,14 @@ func (be *MemoryBackend) Remove(ctx context.Context, h restic.Handle) error {
 	}
 
 	delete(be.data, h)
+	delete(be.modTime, h)
 
 	return ctx.Err()
 }
 
 // List returns a channel which yields entries from the backend.
 func (be *MemoryBackend) List(ctx context.Context, t restic.FileType, fn func(restic.FileInfo) error) error {
-	entries := make(map[string]int64)
+	var entries []restic.FileInfo
 
 	be.m.Lock()
 	for entry, buf := range be.data {
@@ -164,16 +169,15 @@ func (be *MemoryBackend) List(ctx context.Context, t restic.FileType, fn func(re
 			continue
 		}
 
-		entries[entry.Name] = int64(len(buf))
+		entries = append(entries, restic.FileInfo{
+			Name:    entry.Name,
+			Size:    int64(len(buf)),
+			ModTime: be.modTime[entry],
+		})
 	}
 	be.m.Unlock()
 
-	for name, size := range entries {
-		fi := restic.FileInfo{
-			Name: name,
-			Size: size,
-		}
-
+	for _, fi := range entries {
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
@@ -220,6 +224,7 @@ func (be *MemoryBackend) Delete(ctx context.Context) error {
 	}
 
 	be.data = make(memMap)
+	be.modTime = make(map[restic.Handle]time.Time)
 	return nil
 }
 
@@ -267,6 +267,10 @@ func (b *Backend) Stat(ctx context.Context, h restic.Handle) (restic.FileInfo, e
 		Size: resp.ContentLength,
 		Name: h.Name,
 	}
+	// the modification time is optional, not all servers return it
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		bi.ModTime = modTime
+	}
 
 	return bi, nil
 }
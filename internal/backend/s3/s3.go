@@ -339,7 +339,7 @@ func (be *Backend) Stat(ctx context.Context, h restic.Handle) (bi restic.FileInf
 		return restic.FileInfo{}, errors.Wrap(err, "Stat")
 	}
 
-	return restic.FileInfo{Size: fi.Size, Name: h.Name}, nil
+	return restic.FileInfo{Size: fi.Size, Name: h.Name, ModTime: fi.LastModified}, nil
 }
 
 // Remove removes the blob with the given name and type.
@@ -390,8 +390,9 @@ func (be *Backend) List(ctx context.Context, t restic.FileType, fn func(restic.F
 		}
 
 		fi := restic.FileInfo{
-			Name: path.Base(m),
-			Size: obj.Size,
+			Name:    path.Base(m),
+			Size:    obj.Size,
+			ModTime: obj.LastModified,
 		}
 
 		if ctx.Err() != nil {
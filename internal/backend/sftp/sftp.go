@@ -434,7 +434,7 @@ func (r *SFTP) Stat(_ context.Context, h restic.Handle) (restic.FileInfo, error)
 		return restic.FileInfo{}, errors.Wrap(err, "Lstat")
 	}
 
-	return restic.FileInfo{Size: fi.Size(), Name: h.Name}, nil
+	return restic.FileInfo{Size: fi.Size(), Name: h.Name, ModTime: fi.ModTime()}, nil
 }
 
 // Remove removes the content stored at name.
@@ -482,8 +482,9 @@ func (r *SFTP) List(ctx context.Context, t restic.FileType, fn func(restic.FileI
 		debug.Log("send %v\n", path.Base(walker.Path()))
 
 		rfi := restic.FileInfo{
-			Name: path.Base(walker.Path()),
-			Size: fi.Size(),
+			Name:    path.Base(walker.Path()),
+			Size:    fi.Size(),
+			ModTime: fi.ModTime(),
 		}
 
 		if ctx.Err() != nil {
@@ -177,7 +177,7 @@ func (be *beSwift) Stat(ctx context.Context, h restic.Handle) (bi restic.FileInf
 		return restic.FileInfo{}, errors.Wrap(err, "conn.Object")
 	}
 
-	return restic.FileInfo{Size: obj.Bytes, Name: h.Name}, nil
+	return restic.FileInfo{Size: obj.Bytes, Name: h.Name, ModTime: obj.LastModified}, nil
 }
 
 // Remove removes the blob with the given name and type.
@@ -208,8 +208,9 @@ func (be *beSwift) List(ctx context.Context, t restic.FileType, fn func(restic.F
 				}
 
 				fi := restic.FileInfo{
-					Name: m,
-					Size: obj.Bytes,
+					Name:    m,
+					Size:    obj.Bytes,
+					ModTime: obj.LastModified,
 				}
 
 				err := fn(fi)
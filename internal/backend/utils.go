@@ -82,7 +82,9 @@ func DefaultDelete(ctx context.Context, be restic.Backend) error {
 		restic.KeyFile,
 		restic.LockFile,
 		restic.SnapshotFile,
-		restic.IndexFile}
+		restic.IndexFile,
+		restic.PendingFile,
+		restic.CheckFile}
 
 	for _, t := range alltypes {
 		err := be.List(ctx, t, func(fi restic.FileInfo) error {
@@ -38,8 +38,22 @@ type Checker struct {
 	}
 	trackUnused bool
 
+	// packs which were read by ReadPacks without finding errors
+	verified struct {
+		sync.Mutex
+		M restic.IDSet
+	}
+
+	// blobs which are missing or could not be read
+	damaged struct {
+		sync.Mutex
+		M restic.BlobSet
+	}
+
 	masterIndex *index.MasterIndex
 	snapshots   restic.Lister
+	// snapshots by their root tree, filled by Structure
+	treeSnapshots map[restic.ID]restic.IDs
 
 	repo restic.Repository
 }
@@ -54,6 +68,9 @@ func New(repo restic.Repository, trackUnused bool) *Checker {
 	}
 
 	c.blobRefs.M = restic.NewBlobSet()
+	c.verified.M = restic.NewIDSet()
+	c.damaged.M = restic.NewBlobSet()
+	c.treeSnapshots = make(map[restic.ID]restic.IDs)
 
 	return c
 }
@@ -190,9 +207,10 @@ func (c *Checker) LoadIndex(ctx context.Context) (hints []error, errs []error) {
 
 // PackError describes an error with a specific pack.
 type PackError struct {
-	ID       restic.ID
-	Orphaned bool
-	Err      error
+	ID        restic.ID
+	Orphaned  bool
+	Truncated bool
+	Err       error
 }
 
 func (e *PackError) Error() string {
@@ -244,6 +262,7 @@ func (c *Checker) Packs(ctx context.Context, errChan chan<- error) {
 		errChan <- err
 	}
 
+	damagedPacks := restic.NewIDSet()
 	for id, size := range c.packs {
 		reposize, ok := repoPacks[id]
 		// remove from repoPacks so we can find orphaned packs
@@ -251,6 +270,7 @@ func (c *Checker) Packs(ctx context.Context, errChan chan<- error) {
 
 		// missing: present in c.packs but not in the repo
 		if !ok {
+			damagedPacks.Insert(id)
 			select {
 			case <-ctx.Done():
 				return
@@ -261,10 +281,20 @@ func (c *Checker) Packs(ctx context.Context, errChan chan<- error) {
 
 		// size not matching: present in c.packs and in the repo, but sizes do not match
 		if size != reposize {
+			damagedPacks.Insert(id)
 			select {
 			case <-ctx.Done():
 				return
-			case errChan <- &PackError{ID: id, Err: errors.Errorf("unexpected file size: got %d, expected %d", reposize, size)}:
+			case errChan <- &PackError{ID: id, Truncated: true, Err: errors.Errorf("unexpected file size: got %d, expected %d", reposize, size)}:
+			}
+		}
+	}
+
+	// the blobs stored in missing or truncated packs are damaged
+	if len(damagedPacks) > 0 {
+		for pbs := range c.repo.Index().ListPacks(ctx, damagedPacks) {
+			for _, blob := range pbs.Blobs {
+				c.addDamaged(blob.BlobHandle)
 			}
 		}
 	}
@@ -279,9 +309,11 @@ func (c *Checker) Packs(ctx context.Context, errChan chan<- error) {
 	}
 }
 
-// Error is an error that occurred while checking a repository.
+// Error is an error that occurred while checking a repository. BlobID is set
+// if the error concerns a data blob referenced by the tree.
 type Error struct {
 	TreeID restic.ID
+	BlobID restic.ID
 	Err    error
 }
 
@@ -311,6 +343,7 @@ func (c *Checker) checkTreeWorker(ctx context.Context, trees <-chan restic.TreeI
 		var errs []error
 		if job.Error != nil {
 			errs = append(errs, job.Error)
+			c.addDamaged(restic.BlobHandle{ID: job.ID, Type: restic.TreeBlob})
 		} else {
 			errs = c.checkTree(job.ID, job.Tree)
 		}
@@ -328,15 +361,27 @@ func (c *Checker) checkTreeWorker(ctx context.Context, trees <-chan restic.TreeI
 	}
 }
 
-func loadSnapshotTreeIDs(ctx context.Context, lister restic.Lister, repo restic.Repository) (ids restic.IDs, errs []error) {
-	err := restic.ForAllSnapshots(ctx, lister, repo, nil, func(id restic.ID, sn *restic.Snapshot, err error) error {
+// SnapshotError is returned when a snapshot cannot be loaded.
+type SnapshotError struct {
+	ID  restic.ID
+	Err error
+}
+
+func (e *SnapshotError) Error() string {
+	// the error returned by restic.LoadSnapshot already contains the ID
+	return e.Err.Error()
+}
+
+func (c *Checker) loadSnapshotTreeIDs(ctx context.Context) (ids restic.IDs, errs []error) {
+	err := restic.ForAllSnapshots(ctx, c.snapshots, c.repo, nil, func(id restic.ID, sn *restic.Snapshot, err error) error {
 		if err != nil {
-			errs = append(errs, err)
+			errs = append(errs, &SnapshotError{ID: id, Err: err})
 			return nil
 		}
 		treeID := *sn.Tree
 		debug.Log("snapshot %v has tree %v", id, treeID)
 		ids = append(ids, treeID)
+		c.treeSnapshots[treeID] = append(c.treeSnapshots[treeID], id)
 		return nil
 	})
 	if err != nil {
@@ -350,7 +395,7 @@ func loadSnapshotTreeIDs(ctx context.Context, lister restic.Lister, repo restic.
 // subtrees are available in the index. errChan is closed after all trees have
 // been traversed.
 func (c *Checker) Structure(ctx context.Context, p *progress.Counter, errChan chan<- error) {
-	trees, errs := loadSnapshotTreeIDs(ctx, c.snapshots, c.repo)
+	trees, errs := c.loadSnapshotTreeIDs(ctx)
 	p.SetMax(uint64(len(trees)))
 	debug.Log("need to check %d trees from snapshots, %d errs returned", len(trees), len(errs))
 
@@ -392,6 +437,13 @@ func (c *Checker) Structure(ctx context.Context, p *progress.Counter, errChan ch
 	}
 }
 
+// SnapshotsForTree returns the snapshots which use the tree id as their root
+// tree. It must only be called after Structure has sent an error or has
+// returned.
+func (c *Checker) SnapshotsForTree(id restic.ID) restic.IDs {
+	return c.treeSnapshots[id]
+}
+
 func (c *Checker) checkTree(id restic.ID, tree *restic.Tree) (errs []error) {
 	debug.Log("checking tree %v", id)
 
@@ -415,7 +467,8 @@ func (c *Checker) checkTree(id restic.ID, tree *restic.Tree) (errs []error) {
 				_, found := c.repo.LookupBlobSize(blobID, restic.DataBlob)
 				if !found {
 					debug.Log("tree %v references blob %v which isn't contained in index", id, blobID)
-					errs = append(errs, &Error{TreeID: id, Err: errors.Errorf("file %q blob %v not found in index", node.Name, blobID)})
+					c.addDamaged(restic.BlobHandle{ID: blobID, Type: restic.DataBlob})
+					errs = append(errs, &Error{TreeID: id, BlobID: blobID, Err: errors.Errorf("file %q blob %v not found in index", node.Name, blobID)})
 				}
 			}
 
@@ -492,12 +545,24 @@ func (c *Checker) GetPacks() map[restic.ID]int64 {
 	return c.packs
 }
 
+// ErrPackData is returned if errors are discovered while verifying a pack file.
+// Damaged lists the blobs which could not be read.
+type ErrPackData struct {
+	PackID  restic.ID
+	Damaged restic.BlobHandles
+	errs    []error
+}
+
+func (e *ErrPackData) Error() string {
+	return fmt.Sprintf("pack %v contains %v errors: %v", e.PackID, len(e.errs), e.errs)
+}
+
 // checkPack reads a pack and checks the integrity of all blobs.
 func checkPack(ctx context.Context, r restic.Repository, id restic.ID, blobs []restic.Blob, size int64, bufRd *bufio.Reader) error {
 	debug.Log("checking pack %v", id.String())
 
 	if len(blobs) == 0 {
-		return errors.Errorf("pack %v is empty or not indexed", id)
+		return &ErrPackData{PackID: id, errs: []error{errors.New("pack is empty or not indexed")}}
 	}
 
 	// sanity check blobs in index
@@ -561,27 +626,36 @@ func checkPack(ctx context.Context, r restic.Repository, id restic.ID, blobs []r
 		})
 	}
 
+	var damaged restic.BlobHandles
+	readBlobs := restic.NewBlobSet()
 	err := repository.StreamPack(ctx, hashingLoader, r.Key(), id, blobs, func(blob restic.BlobHandle, buf []byte, err error) error {
 		debug.Log("  check blob %v: %v", blob.ID, blob)
+		readBlobs.Insert(blob)
 		if err != nil {
 			debug.Log("  error verifying blob %v: %v", blob.ID, err)
 			errs = append(errs, errors.Errorf("blob %v: %v", blob.ID, err))
+			damaged = append(damaged, blob)
 		}
 		return nil
 	})
 	if err != nil {
 		// failed to load the pack file, return as further checks cannot succeed anyways
 		debug.Log("  error streaming pack: %v", err)
-		return errors.Errorf("pack %v failed to download: %v", id, err)
+		for _, blob := range blobs {
+			if !readBlobs.Has(blob.BlobHandle) {
+				damaged = append(damaged, blob.BlobHandle)
+			}
+		}
+		return &ErrPackData{PackID: id, Damaged: damaged, errs: []error{errors.Errorf("failed to download: %v", err)}}
 	}
 	if !hash.Equal(id) {
 		debug.Log("Pack ID does not match, want %v, got %v", id, hash)
-		return errors.Errorf("Pack ID does not match, want %v, got %v", id, hash)
+		return &ErrPackData{PackID: id, Damaged: damaged, errs: []error{errors.Errorf("pack ID does not match, want %v, got %v", id, hash)}}
 	}
 
 	blobs, hdrSize, err := pack.List(r.Key(), bytes.NewReader(hdrBuf), int64(len(hdrBuf)))
 	if err != nil {
-		return err
+		return &ErrPackData{PackID: id, Damaged: damaged, errs: []error{err}}
 	}
 
 	if uint32(idxHdrSize) != hdrSize {
@@ -606,12 +680,40 @@ func checkPack(ctx context.Context, r restic.Repository, id restic.ID, blobs []r
 	}
 
 	if len(errs) > 0 {
-		return errors.Errorf("pack %v contains %v errors: %v", id, len(errs), errs)
+		return &ErrPackData{PackID: id, Damaged: damaged, errs: errs}
 	}
 
 	return nil
 }
 
+// VerifiedPacks returns the packs which were read by ReadPacks without finding
+// any errors.
+func (c *Checker) VerifiedPacks() restic.IDSet {
+	c.verified.Lock()
+	defer c.verified.Unlock()
+
+	packs := restic.NewIDSet()
+	packs.Merge(c.verified.M)
+	return packs
+}
+
+func (c *Checker) addDamaged(h restic.BlobHandle) {
+	c.damaged.Lock()
+	defer c.damaged.Unlock()
+	c.damaged.M.Insert(h)
+}
+
+// DamagedBlobs returns the blobs which were found to be missing or damaged by
+// Packs, Structure and ReadPacks.
+func (c *Checker) DamagedBlobs() restic.BlobSet {
+	c.damaged.Lock()
+	defer c.damaged.Unlock()
+
+	blobs := restic.NewBlobSet()
+	blobs.Merge(c.damaged.M)
+	return blobs
+}
+
 // ReadData loads all data from the repository and checks the integrity.
 func (c *Checker) ReadData(ctx context.Context, errChan chan<- error) {
 	c.ReadPacks(ctx, c.packs, nil, errChan)
@@ -653,8 +755,17 @@ func (c *Checker) ReadPacks(ctx context.Context, packs map[restic.ID]int64, p *p
 				err := checkPack(ctx, c.repo, ps.id, ps.blobs, ps.size, bufRd)
 				p.Add(1)
 				if err == nil {
+					c.verified.Lock()
+					c.verified.M.Insert(ps.id)
+					c.verified.Unlock()
 					continue
 				}
+				var e *ErrPackData
+				if errors.As(err, &e) {
+					for _, h := range e.Damaged {
+						c.addDamaged(h)
+					}
+				}
 
 				select {
 				case <-ctx.Done():
@@ -369,6 +369,8 @@ func TestCheckerModifiedData(t *testing.T) {
 	for _, err := range checkData(chkr) {
 		t.Logf("data error: %v", err)
 		errFound = true
+		var e *checker.ErrPackData
+		test.Assert(t, errors.As(err, &e), "expected ErrPackData, got %T", err)
 	}
 
 	if !errFound {
@@ -376,6 +378,111 @@ func TestCheckerModifiedData(t *testing.T) {
 	}
 }
 
+func TestCheckerMissingBlob(t *testing.T) {
+	ctx := context.TODO()
+	repo := repository.TestRepository(t)
+
+	missing := restic.NewRandomID()
+	tree := &restic.Tree{
+		Nodes: []*restic.Node{{
+			Name:    "foo",
+			Type:    "file",
+			Mode:    0644,
+			Content: restic.IDs{missing},
+		}},
+	}
+
+	wg, wgCtx := errgroup.WithContext(ctx)
+	repo.StartPackUploader(wgCtx, wg)
+	treeID, err := restic.SaveTree(ctx, repo, tree)
+	test.OK(t, err)
+	test.OK(t, repo.Flush(ctx))
+
+	sn, err := restic.NewSnapshot([]string{"/foo"}, nil, "foo", time.Now())
+	test.OK(t, err)
+	sn.Tree = &treeID
+	snID, err := restic.SaveSnapshot(ctx, repo, sn)
+	test.OK(t, err)
+
+	chkr := checker.New(repo, false)
+	_, errs := chkr.LoadIndex(ctx)
+	test.OKs(t, errs)
+
+	errs = checkStruct(chkr)
+	test.Equals(t, 1, len(errs))
+	treeErr, ok := errs[0].(*checker.TreeError)
+	test.Assert(t, ok, "expected TreeError, got %T", errs[0])
+	test.Equals(t, treeID, treeErr.ID)
+	test.Equals(t, 1, len(treeErr.Errors))
+
+	e, ok := treeErr.Errors[0].(*checker.Error)
+	test.Assert(t, ok, "expected Error, got %T", treeErr.Errors[0])
+	test.Equals(t, missing, e.BlobID)
+	test.Equals(t, restic.IDs{snID}, chkr.SnapshotsForTree(treeID))
+
+	damaged := chkr.DamagedBlobs()
+	test.Equals(t, restic.NewBlobSet(restic.BlobHandle{ID: missing, Type: restic.DataBlob}), damaged)
+	affected, err := chkr.AnalyzeDamage(ctx, damaged)
+	test.OK(t, err)
+	test.Equals(t, 1, len(affected))
+	test.Equals(t, snID, *affected[0].Snapshot.ID())
+	test.Equals(t, []string{"/foo"}, affected[0].Paths)
+}
+
+func TestAnalyzeDamageMultipleSnapshots(t *testing.T) {
+	ctx := context.TODO()
+	repo := repository.TestRepository(t)
+
+	wg, wgCtx := errgroup.WithContext(ctx)
+	repo.StartPackUploader(wgCtx, wg)
+
+	saveTree := func(nodes ...*restic.Node) restic.ID {
+		id, err := restic.SaveTree(ctx, repo, &restic.Tree{Nodes: nodes})
+		test.OK(t, err)
+		return id
+	}
+	dir := func(name string, subtree restic.ID) *restic.Node {
+		return &restic.Node{Name: name, Type: "dir", Mode: 0755, Subtree: &subtree}
+	}
+	file := func(name string) *restic.Node {
+		return &restic.Node{Name: name, Type: "file", Mode: 0644}
+	}
+
+	// x cannot be loaded, y can be loaded but is damaged
+	missing := restic.NewRandomID()
+	damagedTree := saveTree(file("clean"))
+	data := saveTree(file("clean"), dir("x", missing), dir("y", damagedTree))
+
+	var snIDs restic.IDs
+	for i, name := range []string{"first", "second"} {
+		root := saveTree(dir("data", data), file(name))
+		sn, err := restic.NewSnapshot([]string{"/data"}, nil, "foo", time.Unix(int64(i), 0))
+		test.OK(t, err)
+		sn.Tree = &root
+		id, err := restic.SaveSnapshot(ctx, repo, sn)
+		test.OK(t, err)
+		snIDs = append(snIDs, id)
+	}
+	test.OK(t, repo.Flush(ctx))
+
+	chkr := checker.New(repo, false)
+	_, errs := chkr.LoadIndex(ctx)
+	test.OKs(t, errs)
+	test.OK(t, chkr.LoadSnapshots(ctx))
+
+	damaged := restic.NewBlobSet(
+		restic.BlobHandle{ID: missing, Type: restic.TreeBlob},
+		restic.BlobHandle{ID: damagedTree, Type: restic.TreeBlob},
+	)
+	affected, err := chkr.AnalyzeDamage(ctx, damaged)
+	test.OK(t, err)
+	test.Equals(t, 2, len(affected))
+	for i, a := range affected {
+		test.Equals(t, snIDs[i], *a.Snapshot.ID())
+		test.Equals(t, []string{"/data/x", "/data/y"}, a.Paths)
+	}
+}
+
 // loadTreesOnceRepository allows each tree to be loaded only once
 type loadTreesOnceRepository struct {
 	restic.Repository
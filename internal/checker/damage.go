@@ -0,0 +1,119 @@
+package checker
+
+import (
+	"context"
+	"path"
+	"sort"
+
+	"github.com/restic/restic/internal/debug"
+	"github.com/restic/restic/internal/restic"
+)
+
+// AffectedSnapshot lists the files and directories of a snapshot which
+// reference damaged blobs.
+type AffectedSnapshot struct {
+	Snapshot *restic.Snapshot
+	Paths    []string
+}
+
+// AnalyzeDamage walks the trees of all snapshots and returns the snapshots
+// which reference one of the damaged blobs, sorted by time. A file is affected
+// if one of its content blobs is damaged, a directory if its tree is damaged
+// or cannot be loaded.
+func (c *Checker) AnalyzeDamage(ctx context.Context, damaged restic.BlobSet) ([]AffectedSnapshot, error) {
+	var affected []AffectedSnapshot
+	// trees which neither are damaged nor reference damaged blobs, shared
+	// between all snapshots
+	clean := restic.NewIDSet()
+
+	var snapshots []*restic.Snapshot
+	err := restic.ForAllSnapshots(ctx, c.snapshots, c.repo, nil, func(id restic.ID, sn *restic.Snapshot, err error) error {
+		if err != nil {
+			// unreadable snapshots are already reported by Structure
+			debug.Log("skipping snapshot %v: %v", id, err)
+			return nil
+		}
+		snapshots = append(snapshots, sn)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Time.Before(snapshots[j].Time)
+	})
+
+	for _, sn := range snapshots {
+		var paths []string
+		_, err := c.findDamage(ctx, *sn.Tree, "/", damaged, clean, &paths)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(paths) > 0 {
+			affected = append(affected, AffectedSnapshot{Snapshot: sn, Paths: paths})
+		}
+	}
+
+	return affected, nil
+}
+
+// findDamage appends the paths below the tree with the given ID which
+// reference damaged blobs to paths and returns whether the tree is clean.
+// Trees in clean are skipped, trees which turn out to be clean are added to
+// it. A tree which is damaged or which cannot be loaded is never added, such
+// that it is reported for every snapshot containing it.
+func (c *Checker) findDamage(ctx context.Context, id restic.ID, nodepath string, damaged restic.BlobSet, clean restic.IDSet, paths *[]string) (bool, error) {
+	if clean.Has(id) {
+		return true, nil
+	}
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	tree, err := restic.LoadTree(ctx, c.repo, id)
+	if err != nil {
+		debug.Log("unable to load tree %v for %v: %v", id.Str(), nodepath, err)
+		*paths = append(*paths, nodepath)
+		return false, nil
+	}
+
+	isClean := true
+	if damaged.Has(restic.BlobHandle{ID: id, Type: restic.TreeBlob}) {
+		// the tree was loaded successfully but is still damaged
+		*paths = append(*paths, nodepath)
+		isClean = false
+	}
+
+	for _, node := range tree.Nodes {
+		p := path.Join(nodepath, node.Name)
+		switch node.Type {
+		case "dir":
+			if node.Subtree == nil {
+				// reported by Structure
+				continue
+			}
+			subtreeClean, err := c.findDamage(ctx, *node.Subtree, p, damaged, clean, paths)
+			if err != nil {
+				return false, err
+			}
+			if !subtreeClean {
+				isClean = false
+			}
+		case "file":
+			for _, blob := range node.Content {
+				if damaged.Has(restic.BlobHandle{ID: blob, Type: restic.DataBlob}) {
+					*paths = append(*paths, p)
+					isClean = false
+					break
+				}
+			}
+		}
+	}
+
+	if isClean {
+		clean.Insert(id)
+	}
+	return isClean, nil
+}
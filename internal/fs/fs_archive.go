@@ -0,0 +1,484 @@
+package fs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/restic/restic/internal/errors"
+)
+
+// ArchiveHeader describes a member of a tar or zip archive. It is returned by
+// the Sys() method of the os.FileInfo for the items of an Archive.
+type ArchiveHeader struct {
+	tar.Header
+
+	// Inode is unique for each member, except for hard links which share
+	// the number of the file they link to.
+	Inode uint64
+	// Links is the number of hard links to a file.
+	Links uint64
+}
+
+// Archive is a read-only file system which provides the members of a tar or
+// zip archive below the directory Name. Directories which are not contained
+// in the archive are created implicitly.
+type Archive struct {
+	Name string
+
+	entries   map[string]*archiveEntry
+	lastInode uint64
+	spool     *os.File
+}
+
+// statically ensure that Archive implements FS.
+var _ FS = &Archive{}
+
+type archiveEntry struct {
+	hdr      *ArchiveHeader
+	children map[string]struct{}
+	open     func() (io.ReadCloser, error)
+	// implicit is set for directories which are not contained in the archive
+	implicit bool
+}
+
+var zipMagic = []byte("PK\x03\x04")
+
+// OpenArchive reads a tar or zip archive from f and returns a file system
+// containing its members below the directory name. Zip archives are only
+// supported if f is a regular file, as reading them requires random access.
+// The contents of the files in a tar archive are read from f if it is a
+// regular file, otherwise they are copied to a temporary file, see
+// NewTarArchive.
+func OpenArchive(f *os.File, name string) (*Archive, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if fi.Mode().IsRegular() {
+		magic := make([]byte, len(zipMagic))
+		_, err := f.ReadAt(magic, 0)
+		if err == nil && bytes.Equal(magic, zipMagic) {
+			return NewZipArchive(f, fi.Size(), name)
+		}
+		return NewTarArchive(f, name)
+	}
+
+	rd := bufio.NewReader(f)
+	magic, _ := rd.Peek(len(zipMagic))
+	if bytes.Equal(magic, zipMagic) {
+		return nil, errors.New("zip archives can only be read from a file, not from a pipe")
+	}
+	return NewTarArchive(rd, name)
+}
+
+func newArchive(name string) *Archive {
+	a := &Archive{
+		Name:    path.Join("/", name),
+		entries: make(map[string]*archiveEntry),
+	}
+	a.entries["/"] = &archiveEntry{
+		hdr: &ArchiveHeader{
+			Header: tar.Header{Name: "/", Typeflag: tar.TypeDir, Mode: 0755},
+			Inode:  a.nextInode(),
+		},
+		children: make(map[string]struct{}),
+		implicit: true,
+	}
+	return a
+}
+
+// NewTarArchive reads the tar archive from rd. If rd can be read at arbitrary
+// offsets, for example a regular file, the contents of the files are read from
+// rd directly, which must then remain open until the Archive is no longer
+// used. Otherwise the contents are copied to a temporary file in the
+// directory returned by os.TempDir, which thus needs as much free space as the
+// files in the archive are large. The temporary file is removed right after
+// creating it, such that it is not left behind if restic is interrupted.
+func NewTarArchive(rd io.Reader, name string) (*Archive, error) {
+	a := newArchive(name)
+	src := seekableReader(rd)
+
+	var offset int64
+	tr := tar.NewReader(rd)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			_ = a.Close()
+			return nil, errors.Wrap(err, "tar")
+		}
+
+		ahdr := &ArchiveHeader{Header: *hdr}
+		var open func() (io.ReadCloser, error)
+
+		switch hdr.Typeflag {
+		case tar.TypeReg, tar.TypeGNUSparse:
+			ahdr.Typeflag = tar.TypeReg
+			if src != nil && !isSparse(hdr) {
+				// the contents directly follow the header, tar skips them
+				// when reading the next header
+				pos, err := src.Seek(0, io.SeekCurrent)
+				if err != nil {
+					_ = a.Close()
+					return nil, errors.Wrapf(err, "tar: %v", hdr.Name)
+				}
+				open = sectionReader(src, pos, hdr.Size)
+				break
+			}
+
+			if a.spool == nil {
+				a.spool, err = TempFile("", "restic-archive-")
+				if err != nil {
+					return nil, errors.Wrap(err, "unable to create temporary file for the archive contents, set TMPDIR to use a different directory")
+				}
+			}
+			n, err := io.Copy(a.spool, tr)
+			if err != nil {
+				_ = a.Close()
+				return nil, errors.Wrapf(err, "tar: copying %v to temporary file", hdr.Name)
+			}
+			ahdr.Size = n
+			open = sectionReader(a.spool, offset, n)
+			offset += n
+
+		case tar.TypeLink:
+			target, ok := a.entries[a.memberPath(hdr.Linkname)]
+			if !ok || target.open == nil {
+				_ = a.Close()
+				return nil, errors.Errorf("tar: target %v of hard link %v not found", hdr.Linkname, hdr.Name)
+			}
+			// hard links share all metadata with the file they link to
+			*ahdr = *target.hdr
+			open = target.open
+
+		case tar.TypeDir, tar.TypeSymlink, tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+
+		default:
+			// skip pax, GNU long name and other special headers
+			continue
+		}
+
+		a.add(hdr.Name, ahdr, open)
+	}
+
+	a.setImplicitModTimes()
+	a.countLinks()
+	return a, nil
+}
+
+// readSeekerAt is implemented by files which can be read at arbitrary offsets.
+type readSeekerAt interface {
+	io.ReaderAt
+	io.Seeker
+}
+
+// seekableReader returns rd if it can be read at arbitrary offsets, and nil
+// otherwise. An *os.File for a pipe implements Seek, but always fails.
+func seekableReader(rd io.Reader) readSeekerAt {
+	src, ok := rd.(readSeekerAt)
+	if !ok {
+		return nil
+	}
+	if _, err := src.Seek(0, io.SeekCurrent); err != nil {
+		return nil
+	}
+	return src
+}
+
+// isSparse returns true if the contents of the file are not stored as a
+// single block in the tar archive.
+func isSparse(hdr *tar.Header) bool {
+	if hdr.Typeflag == tar.TypeGNUSparse {
+		return true
+	}
+	for key := range hdr.PAXRecords {
+		if strings.HasPrefix(key, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
+
+func sectionReader(rd io.ReaderAt, offset, size int64) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(io.NewSectionReader(rd, offset, size)), nil
+	}
+}
+
+// NewZipArchive reads the zip archive with the given size from rd.
+func NewZipArchive(rd io.ReaderAt, size int64, name string) (*Archive, error) {
+	a := newArchive(name)
+
+	zr, err := zip.NewReader(rd, size)
+	if err != nil {
+		return nil, errors.Wrap(err, "zip")
+	}
+
+	for _, f := range zr.File {
+		f := f
+		fi := f.FileInfo()
+
+		var link string
+		if fi.Mode()&os.ModeSymlink != 0 {
+			// zip stores the target of a symlink as the file content
+			rc, err := f.Open()
+			if err != nil {
+				return nil, errors.Wrapf(err, "zip: reading %v", f.Name)
+			}
+			buf, err := io.ReadAll(io.LimitReader(rc, 4096))
+			_ = rc.Close()
+			if err != nil {
+				return nil, errors.Wrapf(err, "zip: reading %v", f.Name)
+			}
+			link = string(buf)
+		}
+
+		hdr, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return nil, errors.Wrapf(err, "zip: %v", f.Name)
+		}
+
+		var open func() (io.ReadCloser, error)
+		if hdr.Typeflag == tar.TypeReg {
+			open = f.Open
+		}
+		a.add(f.Name, &ArchiveHeader{Header: *hdr}, open)
+	}
+
+	a.setImplicitModTimes()
+	a.countLinks()
+	return a, nil
+}
+
+// memberPath returns the path of the archive member name in the file system.
+func (a *Archive) memberPath(name string) string {
+	return path.Join(a.Name, path.Clean("/"+name))
+}
+
+// add inserts the member with the given name and all parent directories.
+func (a *Archive) add(name string, hdr *ArchiveHeader, open func() (io.ReadCloser, error)) {
+	p := a.memberPath(name)
+	hdr.Name = p
+	if hdr.Inode == 0 {
+		hdr.Inode = a.nextInode()
+	}
+
+	entry, ok := a.entries[p]
+	if ok && entry.children != nil && hdr.Typeflag == tar.TypeDir {
+		// keep the contents of a directory which was created implicitly
+		entry.hdr = hdr
+		entry.implicit = false
+		return
+	}
+
+	entry = &archiveEntry{hdr: hdr, open: open}
+	if hdr.Typeflag == tar.TypeDir {
+		entry.children = make(map[string]struct{})
+	}
+	a.entries[p] = entry
+
+	for p != "/" {
+		dir := path.Dir(p)
+		parent, ok := a.entries[dir]
+		if !ok || parent.children == nil {
+			parent = &archiveEntry{
+				hdr: &ArchiveHeader{
+					Header: tar.Header{Name: dir, Typeflag: tar.TypeDir, Mode: 0755},
+					Inode:  a.nextInode(),
+				},
+				children: make(map[string]struct{}),
+				implicit: true,
+			}
+			a.entries[dir] = parent
+		}
+		parent.children[path.Base(p)] = struct{}{}
+		p = dir
+	}
+}
+
+// setImplicitModTimes sets the modification time of all directories which are
+// not contained in the archive to the newest modification time of the items
+// within. This keeps the directories unchanged between backups of the same
+// archive.
+func (a *Archive) setImplicitModTimes() {
+	var newest func(p string, entry *archiveEntry) time.Time
+	newest = func(p string, entry *archiveEntry) time.Time {
+		var t time.Time
+		for child := range entry.children {
+			cp := path.Join(p, child)
+			ct := newest(cp, a.entries[cp])
+			if ct.After(t) {
+				t = ct
+			}
+		}
+		if entry.implicit {
+			entry.hdr.ModTime = t
+		}
+		return entry.hdr.ModTime
+	}
+	newest("/", a.entries["/"])
+}
+
+func (a *Archive) nextInode() uint64 {
+	a.lastInode++
+	return a.lastInode
+}
+
+// countLinks sets the number of hard links for all files.
+func (a *Archive) countLinks() {
+	links := make(map[uint64]uint64)
+	for _, entry := range a.entries {
+		links[entry.hdr.Inode]++
+	}
+	for _, entry := range a.entries {
+		entry.hdr.Links = links[entry.hdr.Inode]
+	}
+}
+
+// Close closes the temporary file holding the contents of a tar archive.
+func (a *Archive) Close() error {
+	if a.spool == nil {
+		return nil
+	}
+
+	err := a.spool.Close()
+	a.spool = nil
+	return errors.WithStack(err)
+}
+
+func (a *Archive) fi(entry *archiveEntry) os.FileInfo {
+	return archiveFileInfo{
+		FileInfo: entry.hdr.FileInfo(),
+		hdr:      entry.hdr,
+	}
+}
+
+// VolumeName returns leading volume name, for the Archive file system it's
+// always the empty string.
+func (a *Archive) VolumeName(_ string) string {
+	return ""
+}
+
+// Open opens a file for reading.
+func (a *Archive) Open(name string) (File, error) {
+	return a.OpenFile(name, O_RDONLY, 0)
+}
+
+// OpenFile opens the named file or directory for reading. If there is an
+// error, it will be of type *os.PathError.
+func (a *Archive) OpenFile(name string, flag int, _ os.FileMode) (File, error) {
+	if flag & ^(O_RDONLY|O_NOFOLLOW) != 0 {
+		return nil, pathError("open", name,
+			fmt.Errorf("invalid combination of flags 0x%x", flag))
+	}
+
+	entry, ok := a.entries[path.Clean(name)]
+	if !ok {
+		return nil, pathError("open", name, syscall.ENOENT)
+	}
+
+	fi := a.fi(entry)
+	switch {
+	case entry.children != nil:
+		d := fakeDir{fakeFile: fakeFile{name: fi.Name(), FileInfo: fi}}
+		for child := range entry.children {
+			d.entries = append(d.entries, a.fi(a.entries[path.Join(entry.hdr.Name, child)]))
+		}
+		sort.Slice(d.entries, func(i, j int) bool {
+			return d.entries[i].Name() < d.entries[j].Name()
+		})
+		return d, nil
+
+	case entry.open != nil:
+		rd, err := entry.open()
+		if err != nil {
+			return nil, pathError("open", name, err)
+		}
+		return newReaderFile(rd, fi, true), nil
+	}
+
+	return fakeFile{name: fi.Name(), FileInfo: fi}, nil
+}
+
+// Stat returns a FileInfo describing the named file. If there is an error, it
+// will be of type *os.PathError.
+func (a *Archive) Stat(name string) (os.FileInfo, error) {
+	return a.Lstat(name)
+}
+
+// Lstat returns the FileInfo structure describing the named file. The Sys()
+// method of the returned FileInfo returns a *ArchiveHeader. If there is an
+// error, it will be of type *os.PathError.
+func (a *Archive) Lstat(name string) (os.FileInfo, error) {
+	entry, ok := a.entries[path.Clean(name)]
+	if !ok {
+		return nil, pathError("lstat", name, os.ErrNotExist)
+	}
+	return a.fi(entry), nil
+}
+
+// Join joins any number of path elements into a single path, adding a
+// Separator if necessary.
+func (a *Archive) Join(elem ...string) string {
+	return path.Join(elem...)
+}
+
+// Separator returns the OS and FS dependent separator for dirs/subdirs/files.
+func (a *Archive) Separator() string {
+	return "/"
+}
+
+// IsAbs reports whether the path is absolute. For the Archive, this is always
+// the case.
+func (a *Archive) IsAbs(_ string) bool {
+	return true
+}
+
+// Abs returns an absolute representation of path. For the Archive, all paths
+// are absolute.
+func (a *Archive) Abs(p string) (string, error) {
+	return path.Clean(p), nil
+}
+
+// Clean returns the cleaned path. For details, see filepath.Clean.
+func (a *Archive) Clean(p string) string {
+	return path.Clean(p)
+}
+
+// Base returns the last element of p.
+func (a *Archive) Base(p string) string {
+	return path.Base(p)
+}
+
+// Dir returns p without the last element.
+func (a *Archive) Dir(p string) string {
+	return path.Dir(p)
+}
+
+// archiveFileInfo returns the base name of the path in the file system and
+// the ArchiveHeader from Sys().
+type archiveFileInfo struct {
+	os.FileInfo
+	hdr *ArchiveHeader
+}
+
+func (fi archiveFileInfo) Name() string {
+	return path.Base(fi.hdr.Name)
+}
+
+func (fi archiveFileInfo) Sys() interface{} {
+	return fi.hdr
+}
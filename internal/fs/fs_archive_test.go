@@ -0,0 +1,187 @@
+package fs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/restic/restic/internal/test"
+)
+
+func createTestTar(t testing.TB, modtime time.Time) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	for _, item := range []struct {
+		hdr     tar.Header
+		content string
+	}{
+		{hdr: tar.Header{Name: "./", Typeflag: tar.TypeDir, Mode: 0700}},
+		{hdr: tar.Header{Name: "./dir/", Typeflag: tar.TypeDir, Mode: 0750, Uid: 1000, Uname: "user"}},
+		{hdr: tar.Header{Name: "./dir/file", Typeflag: tar.TypeReg, Mode: 0640, Uid: 1000, Gid: 100,
+			PAXRecords: map[string]string{"SCHILY.xattr.user.foo": "bar"}}, content: "file content"},
+		{hdr: tar.Header{Name: "./dir/link", Typeflag: tar.TypeLink, Linkname: "./dir/file", Mode: 0640}},
+		{hdr: tar.Header{Name: "./implicit/symlink", Typeflag: tar.TypeSymlink, Linkname: "../dir/file", Mode: 0777}},
+		{hdr: tar.Header{Name: "../escape", Typeflag: tar.TypeReg, Mode: 0600}, content: "x"},
+	} {
+		hdr := item.hdr
+		hdr.ModTime = modtime
+		hdr.Size = int64(len(item.content))
+		if hdr.Typeflag != tar.TypeReg {
+			hdr.Size = 0
+		}
+		test.OK(t, tw.WriteHeader(&hdr))
+		_, err := tw.Write([]byte(item.content))
+		test.OK(t, err)
+	}
+	test.OK(t, tw.Close())
+
+	return buf.Bytes()
+}
+
+func TestFSArchiveTar(t *testing.T) {
+	modtime := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)
+	buf := createTestTar(t, modtime)
+
+	t.Run("seekable", func(t *testing.T) {
+		// the contents are read from the archive directly
+		a, err := NewTarArchive(bytes.NewReader(buf), "export")
+		test.OK(t, err)
+		test.Assert(t, a.spool == nil, "contents of seekable archive were copied to a temporary file")
+		testFSArchiveTar(t, a, modtime)
+		test.OK(t, a.Close())
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		a, err := NewTarArchive(struct{ io.Reader }{bytes.NewReader(buf)}, "export")
+		test.OK(t, err)
+		test.Assert(t, a.spool != nil, "contents of streamed archive were not copied to a temporary file")
+		if runtime.GOOS != "windows" {
+			// on Windows, the file is removed once it is closed
+			_, err = os.Stat(a.spool.Name())
+			test.Assert(t, os.IsNotExist(err), "temporary file %v was not removed", a.spool.Name())
+		}
+		testFSArchiveTar(t, a, modtime)
+		test.OK(t, a.Close())
+	})
+}
+
+func testFSArchiveTar(t *testing.T, a *Archive, modtime time.Time) {
+
+	test.Equals(t, "/export", a.Name)
+	verifyDirectoryContents(t, a, "/", []string{"export"})
+	verifyDirectoryContents(t, a, "/export", []string{"dir", "escape", "implicit"})
+	verifyDirectoryContents(t, a, "/export/dir", []string{"file", "link"})
+	verifyDirectoryContents(t, a, "/export/implicit", []string{"symlink"})
+
+	verifyFileContentOpen(t, a, "/export/dir/file", []byte("file content"))
+	verifyFileContentOpenFile(t, a, "/export/dir/link", []byte("file content"))
+	verifyFileContentOpen(t, a, "/export/escape", []byte("x"))
+
+	fi, err := a.Lstat("/export")
+	test.OK(t, err)
+	checkFileInfo(t, fi, "/export", modtime, os.ModeDir|0700, true)
+
+	fi, err = a.Lstat("/export/dir/file")
+	test.OK(t, err)
+	checkFileInfo(t, fi, "/export/dir/file", modtime, 0640, false)
+	file := fi.Sys().(*ArchiveHeader)
+	test.Equals(t, 1000, file.Uid)
+	test.Equals(t, "bar", file.PAXRecords["SCHILY.xattr.user.foo"])
+	test.Equals(t, uint64(2), file.Links)
+
+	fi, err = a.Lstat("/export/dir/link")
+	test.OK(t, err)
+	checkFileInfo(t, fi, "/export/dir/link", modtime, 0640, false)
+	test.Equals(t, int64(12), fi.Size())
+	link := fi.Sys().(*ArchiveHeader)
+	test.Equals(t, file.Inode, link.Inode)
+	test.Equals(t, uint64(2), link.Links)
+
+	fi, err = a.Lstat("/export/implicit/symlink")
+	test.OK(t, err)
+	checkFileInfo(t, fi, "/export/implicit/symlink", modtime, os.ModeSymlink|0777, false)
+	test.Equals(t, "../dir/file", fi.Sys().(*ArchiveHeader).Linkname)
+
+	fi, err = a.Lstat("/export/implicit")
+	test.OK(t, err)
+	checkFileInfo(t, fi, "/export/implicit", modtime, os.ModeDir|0755, true)
+
+	fi, err = a.Lstat("/")
+	test.OK(t, err)
+	checkFileInfo(t, fi, "/", modtime, os.ModeDir|0755, true)
+
+	_, err = a.Lstat("/export/missing")
+	test.Assert(t, os.IsNotExist(err), "unexpected error for missing file: %v", err)
+	_, err = a.Open("/export/missing")
+	test.Assert(t, os.IsNotExist(err), "unexpected error for missing file: %v", err)
+}
+
+func TestFSArchiveZip(t *testing.T) {
+	modtime := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, item := range []struct {
+		name    string
+		mode    os.FileMode
+		content string
+	}{
+		{"dir/", os.ModeDir | 0750, ""},
+		{"dir/file", 0640, "file content"},
+		{"implicit/symlink", os.ModeSymlink | 0777, "../dir/file"},
+	} {
+		hdr := &zip.FileHeader{Name: item.name, Modified: modtime}
+		hdr.SetMode(item.mode)
+		w, err := zw.CreateHeader(hdr)
+		test.OK(t, err)
+		_, err = w.Write([]byte(item.content))
+		test.OK(t, err)
+	}
+	test.OK(t, zw.Close())
+
+	a, err := NewZipArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "export")
+	test.OK(t, err)
+
+	verifyDirectoryContents(t, a, "/export", []string{"dir", "implicit"})
+	verifyDirectoryContents(t, a, "/export/dir", []string{"file"})
+	verifyFileContentOpen(t, a, "/export/dir/file", []byte("file content"))
+
+	fi, err := a.Lstat("/export/dir")
+	test.OK(t, err)
+	checkFileInfo(t, fi, "/export/dir", modtime, os.ModeDir|0750, true)
+
+	fi, err = a.Lstat("/export/dir/file")
+	test.OK(t, err)
+	checkFileInfo(t, fi, "/export/dir/file", modtime, 0640, false)
+
+	fi, err = a.Lstat("/export/implicit/symlink")
+	test.OK(t, err)
+	checkFileInfo(t, fi, "/export/implicit/symlink", modtime, os.ModeSymlink|0777, false)
+	test.Equals(t, "../dir/file", fi.Sys().(*ArchiveHeader).Linkname)
+
+	fi, err = a.Lstat("/export/implicit")
+	test.OK(t, err)
+	checkFileInfo(t, fi, "/export/implicit", modtime, os.ModeDir|0755, true)
+}
+
+func TestFSArchiveZipFromPipe(t *testing.T) {
+	rd, wr, err := os.Pipe()
+	test.OK(t, err)
+	defer func() {
+		_ = rd.Close()
+	}()
+
+	go func() {
+		_, _ = wr.Write([]byte("PK\x03\x04 not a real zip file"))
+		_ = wr.Close()
+	}()
+
+	_, err = OpenArchive(rd, "export")
+	test.Assert(t, err != nil, "reading zip from pipe did not fail")
+}
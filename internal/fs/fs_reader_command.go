@@ -0,0 +1,85 @@
+package fs
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/restic/restic/internal/errors"
+)
+
+// CommandReader wraps a command such that its standard output can be read
+// using an io.ReadCloser. Once all output has been read, Read waits for the
+// command to terminate and returns a fatal error if the command failed.
+type CommandReader struct {
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+
+	eof      bool
+	waitOnce sync.Once
+	waitErr  error
+}
+
+// NewCommandReader starts the command with the given arguments. The standard
+// error of the command is passed to stderr.
+func NewCommandReader(ctx context.Context, args []string, stderr io.Writer) (*CommandReader, error) {
+	if len(args) == 0 {
+		return nil, errors.New("no command given")
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, errors.Wrap(err, "StdoutPipe")
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Fatalf("unable to start command %q: %v", strings.Join(args, " "), err)
+	}
+
+	return &CommandReader{cmd: cmd, stdout: stdout}, nil
+}
+
+// Read reads the output of the command. When the output is exhausted, it
+// returns the error of the command instead of io.EOF if the command failed.
+func (r *CommandReader) Read(p []byte) (int, error) {
+	if r.eof {
+		// the pipe is closed once the command has terminated
+		if err := r.wait(); err != nil {
+			return 0, err
+		}
+		return 0, io.EOF
+	}
+
+	n, err := r.stdout.Read(p)
+	if err == io.EOF {
+		r.eof = true
+		if werr := r.wait(); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+func (r *CommandReader) wait() error {
+	r.waitOnce.Do(func() {
+		err := r.cmd.Wait()
+		if err != nil {
+			r.waitErr = errors.Fatalf("command %q failed: %v", strings.Join(r.cmd.Args, " "), err)
+		}
+	})
+	return r.waitErr
+}
+
+// Close waits for the command to terminate and returns its error. If the
+// output was not read completely, the command is killed first.
+func (r *CommandReader) Close() error {
+	if !r.eof {
+		_ = r.cmd.Process.Kill()
+	}
+	return r.wait()
+}
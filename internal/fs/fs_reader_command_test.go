@@ -0,0 +1,50 @@
+package fs_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"runtime"
+	"testing"
+
+	"github.com/restic/restic/internal/errors"
+	"github.com/restic/restic/internal/fs"
+	"github.com/restic/restic/internal/test"
+)
+
+func TestCommandReader(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires sh")
+	}
+
+	var stderr bytes.Buffer
+	rd, err := fs.NewCommandReader(context.TODO(), []string{"sh", "-c", "echo foo; echo bar >&2"}, &stderr)
+	test.OK(t, err)
+
+	buf, err := io.ReadAll(rd)
+	test.OK(t, err)
+	test.Equals(t, "foo\n", string(buf))
+	test.OK(t, rd.Close())
+	test.Equals(t, "bar\n", stderr.String())
+}
+
+func TestCommandReaderFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test requires sh")
+	}
+
+	rd, err := fs.NewCommandReader(context.TODO(), []string{"sh", "-c", "echo foo; exit 1"}, io.Discard)
+	test.OK(t, err)
+
+	buf, err := io.ReadAll(rd)
+	test.Equals(t, "foo\n", string(buf))
+	test.Assert(t, errors.IsFatal(err), "expected fatal error, got %v", err)
+
+	// the error is returned again
+	_, err = rd.Read(make([]byte, 10))
+	test.Assert(t, errors.IsFatal(err), "expected fatal error, got %v", err)
+	test.Assert(t, errors.IsFatal(rd.Close()), "expected fatal error from Close")
+
+	_, err = fs.NewCommandReader(context.TODO(), []string{"/nonexistent/command"}, io.Discard)
+	test.Assert(t, err != nil, "starting nonexistent command did not fail")
+}
@@ -56,31 +56,15 @@ func (*UpgradeRepoV2) Check(_ context.Context, repo restic.Repository) (bool, st
 func (*UpgradeRepoV2) RepoCheck() bool {
 	return true
 }
-func (*UpgradeRepoV2) upgrade(ctx context.Context, repo restic.Repository) error {
-	h := restic.Handle{Type: restic.ConfigFile}
-
-	if !repo.Backend().HasAtomicReplace() {
-		// remove the original file for backends which do not support atomic overwriting
-		err := repo.Backend().Remove(ctx, h)
-		if err != nil {
-			return fmt.Errorf("remove config failed: %w", err)
-		}
-	}
-
-	// upgrade config
-	cfg := repo.Config()
-	cfg.Version = 2
-
-	err := restic.SaveConfig(ctx, repo, cfg)
-	if err != nil {
-		return fmt.Errorf("save new config file failed: %w", err)
-	}
-
-	return nil
+func (*UpgradeRepoV2) Apply(ctx context.Context, repo restic.Repository) error {
+	return upgradeRepoVersion(ctx, repo, 2)
 }
 
-func (m *UpgradeRepoV2) Apply(ctx context.Context, repo restic.Repository) error {
-	tempdir, err := os.MkdirTemp("", "restic-migrate-upgrade-repo-v2-")
+// upgradeRepoVersion sets the version in the config of repo. A copy of the
+// original config is stored in a temporary directory until the new config is
+// saved successfully.
+func upgradeRepoVersion(ctx context.Context, repo restic.Repository, version uint) error {
+	tempdir, err := os.MkdirTemp("", fmt.Sprintf("restic-migrate-upgrade-repo-v%d-", version))
 	if err != nil {
 		return fmt.Errorf("create temp dir failed: %w", err)
 	}
@@ -104,7 +88,7 @@ func (m *UpgradeRepoV2) Apply(ctx context.Context, repo restic.Repository) error
 	}
 
 	// run the upgrade
-	err = m.upgrade(ctx, repo)
+	err = saveConfigVersion(ctx, repo, version)
 	if err != nil {
 
 		// build an error we can return to the caller
@@ -127,3 +111,26 @@ func (m *UpgradeRepoV2) Apply(ctx context.Context, repo restic.Repository) error
 	_ = os.Remove(tempdir)
 	return nil
 }
+
+func saveConfigVersion(ctx context.Context, repo restic.Repository, version uint) error {
+	h := restic.Handle{Type: restic.ConfigFile}
+
+	if !repo.Backend().HasAtomicReplace() {
+		// remove the original file for backends which do not support atomic overwriting
+		err := repo.Backend().Remove(ctx, h)
+		if err != nil {
+			return fmt.Errorf("remove config failed: %w", err)
+		}
+	}
+
+	// upgrade config
+	cfg := repo.Config()
+	cfg.Version = version
+
+	err := restic.SaveConfig(ctx, repo, cfg)
+	if err != nil {
+		return fmt.Errorf("save new config file failed: %w", err)
+	}
+
+	return nil
+}
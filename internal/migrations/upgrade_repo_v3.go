@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/restic/restic/internal/restic"
+)
+
+func init() {
+	register(&UpgradeRepoV3{})
+}
+
+// UpgradeRepoV3 upgrades a repository to version 3, which adds file types for
+// retention policies, pending pack deletions and check records.
+type UpgradeRepoV3 struct{}
+
+func (*UpgradeRepoV3) Name() string {
+	return "upgrade_repo_v3"
+}
+
+func (*UpgradeRepoV3) Desc() string {
+	return "upgrade a repository to version 3"
+}
+
+func (*UpgradeRepoV3) Check(_ context.Context, repo restic.Repository) (bool, string, error) {
+	switch v := repo.Config().Version; {
+	case v < 2:
+		return false, "repository must be upgraded to version 2 first using upgrade_repo_v2", nil
+	case v > 2:
+		return false, fmt.Sprintf("repository is already upgraded to version %v", v), nil
+	}
+	return true, "", nil
+}
+
+func (*UpgradeRepoV3) RepoCheck() bool {
+	return false
+}
+
+func (*UpgradeRepoV3) Apply(ctx context.Context, repo restic.Repository) error {
+	return upgradeRepoVersion(ctx, repo, 3)
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/restic/restic/internal/repository"
+	"github.com/restic/restic/internal/restic"
+	"github.com/restic/restic/internal/test"
+)
+
+func TestUpgradeRepoV3(t *testing.T) {
+	m := &UpgradeRepoV3{}
+
+	ok, _, err := m.Check(context.Background(), repository.TestRepositoryWithVersion(t, 1))
+	test.OK(t, err)
+	test.Assert(t, !ok, "migration check for version 1 returned true")
+
+	repo := repository.TestRepositoryWithVersion(t, 2)
+	ok, _, err = m.Check(context.Background(), repo)
+	test.OK(t, err)
+	test.Assert(t, ok, "migration check for version 2 returned false")
+
+	test.OK(t, m.Apply(context.Background(), repo))
+
+	cfg, err := restic.LoadConfig(context.Background(), repo)
+	test.OK(t, err)
+	test.Equals(t, uint(3), cfg.Version)
+}
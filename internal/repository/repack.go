@@ -26,6 +26,14 @@ type repackBlobSet interface {
 // The map keepBlobs is modified by Repack, it is used to keep track of which
 // blobs have been processed.
 func Repack(ctx context.Context, repo restic.Repository, dstRepo restic.Repository, packs restic.IDSet, keepBlobs repackBlobSet, p *progress.Counter) (obsoletePacks restic.IDSet, err error) {
+	return RepackUntil(ctx, repo, dstRepo, packs, keepBlobs, p, nil)
+}
+
+// RepackUntil works like Repack, but calls stop before loading each pack and
+// does not load any further packs once stop returns true. Packs which are
+// already being loaded are processed completely. Returned are the packs which
+// were repacked. The blobs of all other packs remain in keepBlobs.
+func RepackUntil(ctx context.Context, repo restic.Repository, dstRepo restic.Repository, packs restic.IDSet, keepBlobs repackBlobSet, p *progress.Counter, stop func() bool) (obsoletePacks restic.IDSet, err error) {
 	debug.Log("repacking %d packs while keeping %d blobs", len(packs), keepBlobs.Len())
 
 	if repo == dstRepo && dstRepo.Connections() < 2 {
@@ -37,7 +45,7 @@ func Repack(ctx context.Context, repo restic.Repository, dstRepo restic.Reposito
 	dstRepo.StartPackUploader(wgCtx, wg)
 	wg.Go(func() error {
 		var err error
-		obsoletePacks, err = repack(wgCtx, repo, dstRepo, packs, keepBlobs, p)
+		obsoletePacks, err = repack(wgCtx, repo, dstRepo, packs, keepBlobs, p, stop)
 		return err
 	})
 
@@ -47,14 +55,22 @@ func Repack(ctx context.Context, repo restic.Repository, dstRepo restic.Reposito
 	return obsoletePacks, nil
 }
 
-func repack(ctx context.Context, repo restic.Repository, dstRepo restic.Repository, packs restic.IDSet, keepBlobs repackBlobSet, p *progress.Counter) (obsoletePacks restic.IDSet, err error) {
+func repack(ctx context.Context, repo restic.Repository, dstRepo restic.Repository, packs restic.IDSet, keepBlobs repackBlobSet, p *progress.Counter, stop func() bool) (obsoletePacks restic.IDSet, err error) {
 	wg, wgCtx := errgroup.WithContext(ctx)
 
 	var keepMutex sync.Mutex
+	repacked := restic.NewIDSet()
 	downloadQueue := make(chan restic.PackBlobs)
 	wg.Go(func() error {
 		defer close(downloadQueue)
+		stopped := false
 		for pbs := range repo.Index().ListPacks(wgCtx, packs) {
+			// keep consuming the list such that ListPacks can finish
+			if stopped || (stop != nil && stop()) {
+				stopped = true
+				continue
+			}
+
 			var packBlobs []restic.Blob
 			keepMutex.Lock()
 			// filter out unnecessary blobs
@@ -112,6 +128,9 @@ func repack(ctx context.Context, repo restic.Repository, dstRepo restic.Reposito
 			if err != nil {
 				return err
 			}
+			keepMutex.Lock()
+			repacked.Insert(t.PackID)
+			keepMutex.Unlock()
 			p.Add(1)
 		}
 		return nil
@@ -136,5 +155,8 @@ func repack(ctx context.Context, repo restic.Repository, dstRepo restic.Reposito
 		return nil, err
 	}
 
-	return packs, nil
+	if stop == nil {
+		return packs, nil
+	}
+	return repacked, nil
 }
@@ -288,6 +288,49 @@ func testRepack(t *testing.T, version uint) {
 	}
 }
 
+func TestRepackUntil(t *testing.T) {
+	repository.TestAllVersions(t, testRepackUntil)
+}
+
+func testRepackUntil(t *testing.T, version uint) {
+	repo := repository.TestRepositoryWithVersion(t, version)
+
+	seed := time.Now().UnixNano()
+	rand.Seed(seed)
+	t.Logf("rand seed is %v", seed)
+
+	createRandomBlobs(t, repo, 100, 0.7)
+	flush(t, repo)
+
+	_, keepBlobs := selectBlobs(t, repo, 0.2)
+	packs := findPacksForBlobs(t, repo, keepBlobs)
+	if len(packs) < 2 {
+		t.Skipf("need at least two packs, got %d", len(packs))
+	}
+	remainingBlobs := keepBlobs.Len()
+
+	// only the first pack is loaded, stop is not called again once it returned true
+	calls := 0
+	repacked, err := repository.RepackUntil(context.TODO(), repo, repo, packs, keepBlobs, nil, func() bool {
+		calls++
+		return calls > 1
+	})
+	rtest.OK(t, err)
+	rtest.Equals(t, 1, len(repacked))
+	rtest.Equals(t, 2, calls)
+
+	// the blobs of all other packs are still to be repacked
+	for id := range repacked {
+		rtest.Assert(t, packs.Has(id), "unexpected pack %v", id.Str())
+	}
+	rtest.Assert(t, keepBlobs.Len() < remainingBlobs, "no blobs were repacked")
+	for h := range keepBlobs {
+		for _, pb := range repo.Index().Lookup(h) {
+			rtest.Assert(t, !repacked.Has(pb.PackID), "blob %v of repacked pack %v was not repacked", h, pb.PackID.Str())
+		}
+	}
+}
+
 func TestRepackCopy(t *testing.T) {
 	repository.TestAllVersions(t, testRepackCopy)
 }
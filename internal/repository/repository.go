@@ -174,7 +174,7 @@ func (r *Repository) SetDryRun() {
 func (r *Repository) LoadUnpacked(ctx context.Context, t restic.FileType, id restic.ID) ([]byte, error) {
 	debug.Log("load %v with id %v", t, id)
 
-	if t == restic.ConfigFile {
+	if t == restic.ConfigFile || t == restic.PolicyFile {
 		id = restic.ID{}
 	}
 
@@ -194,7 +194,7 @@ func (r *Repository) LoadUnpacked(ctx context.Context, t restic.FileType, id res
 		}
 
 		buf := wr.Bytes()
-		if t != restic.ConfigFile && !restic.Hash(buf).Equal(id) {
+		if t != restic.ConfigFile && t != restic.PolicyFile && !restic.Hash(buf).Equal(id) {
 			debug.Log("retry loading broken blob %v", h)
 			if !retriedInvalidData {
 				retriedInvalidData = true
@@ -475,6 +475,10 @@ func (r *Repository) decompressUnpacked(p []byte) ([]byte, error) {
 // storage hash.
 func (r *Repository) SaveUnpacked(ctx context.Context, t restic.FileType, p []byte) (id restic.ID, err error) {
 	if t != restic.ConfigFile {
+		if err := r.cfg.Supports(t); err != nil {
+			return restic.ID{}, err
+		}
+
 		p, err = r.compressUnpacked(p)
 		if err != nil {
 			return restic.ID{}, err
@@ -488,7 +492,7 @@ func (r *Repository) SaveUnpacked(ctx context.Context, t restic.FileType, p []by
 
 	ciphertext = r.key.Seal(ciphertext, nonce, p, nil)
 
-	if t == restic.ConfigFile {
+	if t == restic.ConfigFile || t == restic.PolicyFile {
 		id = restic.ID{}
 	} else {
 		id = restic.Hash(ciphertext)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"hash"
 	"io"
+	"time"
 )
 
 // Backend is used to store and access data.
@@ -79,4 +80,8 @@ type BackendUnwrapper interface {
 type FileInfo struct {
 	Size int64
 	Name string
+	// ModTime is the time the file was last modified. It is zero if the
+	// backend cannot determine it, for example List() of the REST backend
+	// does not report it.
+	ModTime time.Time
 }
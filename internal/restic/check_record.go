@@ -0,0 +1,84 @@
+package restic
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CheckRecord lists the pack files which were read completely and found to be
+// intact by check, and when this happened.
+type CheckRecord struct {
+	Time     time.Time       `json:"time"`
+	Runs     uint64          `json:"runs"` // number of check runs which updated the record
+	Verified []VerifiedPacks `json:"verified"`
+}
+
+// VerifiedPacks lists the packs which were verified at Time.
+type VerifiedPacks struct {
+	Time  time.Time `json:"time"`
+	Packs IDs       `json:"packs"`
+}
+
+// NewCheckRecord returns a record for the given packs and the times at which
+// they were verified.
+func NewCheckRecord(runs uint64, verified map[ID]time.Time) *CheckRecord {
+	byTime := make(map[time.Time]IDs)
+	for id, t := range verified {
+		byTime[t] = append(byTime[t], id)
+	}
+
+	r := &CheckRecord{Time: time.Now(), Runs: runs}
+	for t, ids := range byTime {
+		sort.Sort(ids)
+		r.Verified = append(r.Verified, VerifiedPacks{Time: t, Packs: ids})
+	}
+	sort.Slice(r.Verified, func(i, j int) bool {
+		return r.Verified[i].Time.Before(r.Verified[j].Time)
+	})
+	return r
+}
+
+// merge updates packs with the times at which the packs in r were verified.
+func (r *CheckRecord) merge(packs map[ID]time.Time) {
+	for _, v := range r.Verified {
+		for _, id := range v.Packs {
+			if t, ok := packs[id]; !ok || v.Time.After(t) {
+				packs[id] = v.Time
+			}
+		}
+	}
+}
+
+// SaveCheckRecord saves the record r in the repository.
+func SaveCheckRecord(ctx context.Context, repo SaverUnpacked, r *CheckRecord) (ID, error) {
+	return SaveJSONUnpacked(ctx, repo, CheckFile, r)
+}
+
+// LoadCheckRecords loads and merges all check records in the repository. It
+// returns the IDs of the loaded records, the number of check runs and the time
+// at which each pack was last verified.
+func LoadCheckRecords(ctx context.Context, be Lister, loader LoaderUnpacked) (ids IDSet, runs uint64, packs map[ID]time.Time, err error) {
+	var m sync.Mutex
+	ids = NewIDSet()
+	packs = make(map[ID]time.Time)
+
+	err = ParallelList(ctx, be, CheckFile, loader.Connections(), func(ctx context.Context, id ID, size int64) error {
+		r := &CheckRecord{}
+		err := LoadJSONUnpacked(ctx, loader, CheckFile, id, r)
+		if err != nil {
+			return err
+		}
+
+		m.Lock()
+		defer m.Unlock()
+		ids.Insert(id)
+		if r.Runs > runs {
+			runs = r.Runs
+		}
+		r.merge(packs)
+		return nil
+	})
+	return ids, runs, packs, err
+}
@@ -0,0 +1,38 @@
+package restic_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/restic/restic/internal/repository"
+	"github.com/restic/restic/internal/restic"
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func TestCheckRecordSaveLoad(t *testing.T) {
+	repo := repository.TestRepositoryWithVersion(t, 3)
+	ctx := context.TODO()
+
+	first := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	second := first.Add(24 * time.Hour)
+	a, b, c := restic.NewRandomID(), restic.NewRandomID(), restic.NewRandomID()
+
+	r := restic.NewCheckRecord(1, map[restic.ID]time.Time{a: first, b: first})
+	rtest.Equals(t, 1, len(r.Verified))
+	id1, err := restic.SaveCheckRecord(ctx, repo, r)
+	rtest.OK(t, err)
+
+	// records are merged using the latest time of each pack
+	id2, err := restic.SaveCheckRecord(ctx, repo, restic.NewCheckRecord(3, map[restic.ID]time.Time{b: second, c: second}))
+	rtest.OK(t, err)
+
+	ids, runs, packs, err := restic.LoadCheckRecords(ctx, repo.Backend(), repo)
+	rtest.OK(t, err)
+	rtest.Equals(t, restic.NewIDSet(id1, id2), ids)
+	rtest.Equals(t, uint64(3), runs)
+	rtest.Equals(t, 3, len(packs))
+	rtest.Assert(t, packs[a].Equal(first), "wrong time for pack a: %v", packs[a])
+	rtest.Assert(t, packs[b].Equal(second), "wrong time for pack b: %v", packs[b])
+	rtest.Assert(t, packs[c].Equal(second), "wrong time for pack c: %v", packs[c])
+}
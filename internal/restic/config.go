@@ -19,7 +19,7 @@ type Config struct {
 }
 
 const MinRepoVersion = 1
-const MaxRepoVersion = 2
+const MaxRepoVersion = 3
 
 // StableRepoVersion is the version that is written to the config when a repository
 // is newly created with Init().
@@ -99,6 +99,15 @@ func LoadConfig(ctx context.Context, r LoaderUnpacked) (Config, error) {
 	return cfg, nil
 }
 
+// Supports returns an error if a repository with this config cannot contain
+// files of type t.
+func (cfg Config) Supports(t FileType) error {
+	if v := t.RepoVersion(); cfg.Version < v {
+		return errors.Fatalf("storing %v files requires repository version %v or later, the repository has version %v; run \"restic migrate upgrade_repo_v%v\" to upgrade it", t, v, cfg.Version, v)
+	}
+	return nil
+}
+
 func SaveConfig(ctx context.Context, r SaverUnpacked, cfg Config) error {
 	_, err := SaveJSONUnpacked(ctx, r, ConfigFile, cfg)
 	return err
@@ -126,6 +126,16 @@ func (d *Duration) Set(s string) error {
 	return nil
 }
 
+// MarshalText returns the duration in the format accepted by ParseDuration.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText parses a duration in the format accepted by ParseDuration.
+func (d *Duration) UnmarshalText(text []byte) error {
+	return d.Set(string(text))
+}
+
 // Type returns the type of Duration, usable within github.com/spf13/pflag and
 // in help texts.
 func (d Duration) Type() string {
@@ -17,6 +17,9 @@ const (
 	SnapshotFile
 	IndexFile
 	ConfigFile
+	PolicyFile
+	PendingFile
+	CheckFile
 )
 
 func (t FileType) String() string {
@@ -35,10 +38,27 @@ func (t FileType) String() string {
 		s = "index"
 	case ConfigFile:
 		s = "config"
+	case PolicyFile:
+		s = "policy"
+	case PendingFile:
+		s = "pending"
+	case CheckFile:
+		s = "check"
 	}
 	return s
 }
 
+// RepoVersion returns the minimum repository version which can contain files
+// of type t. Files of newer types are not known to older clients and servers,
+// which would neither list nor remove them.
+func (t FileType) RepoVersion() uint {
+	switch t {
+	case PolicyFile, PendingFile, CheckFile:
+		return 3
+	}
+	return MinRepoVersion
+}
+
 // Handle is used to store and access data in a backend.
 type Handle struct {
 	Type              FileType
@@ -63,11 +83,14 @@ func (h Handle) Valid() error {
 	case SnapshotFile:
 	case IndexFile:
 	case ConfigFile:
+	case PolicyFile:
+	case PendingFile:
+	case CheckFile:
 	default:
 		return errors.Errorf("invalid Type %d", h.Type)
 	}
 
-	if h.Type == ConfigFile {
+	if h.Type == ConfigFile || h.Type == PolicyFile {
 		return nil
 	}
 
@@ -0,0 +1,54 @@
+package restic
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/restic/restic/internal/errors"
+)
+
+// LabelMap is a set of key/value labels.
+type LabelMap map[string]string
+
+// parseLabel splits a label in the format "key=value" into key and value.
+func parseLabel(s string) (key, value string, err error) {
+	key, value, found := strings.Cut(s, "=")
+	key = strings.TrimSpace(key)
+	if !found || key == "" {
+		return "", "", errors.Errorf("invalid label %q, expected format key=value", s)
+	}
+	return key, value, nil
+}
+
+func (m LabelMap) String() string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	labels := make([]string, 0, len(keys))
+	for _, k := range keys {
+		labels = append(labels, k+"="+m[k])
+	}
+	return "[" + strings.Join(labels, ", ") + "]"
+}
+
+// Set adds the label in the format "key=value" to the LabelMap.
+func (m *LabelMap) Set(s string) error {
+	key, value, err := parseLabel(s)
+	if err != nil {
+		return err
+	}
+
+	if *m == nil {
+		*m = make(LabelMap)
+	}
+	(*m)[key] = value
+	return nil
+}
+
+// Type returns a description of the type.
+func (LabelMap) Type() string {
+	return "LabelMap"
+}
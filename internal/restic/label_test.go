@@ -0,0 +1,22 @@
+package restic
+
+import (
+	"testing"
+
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func TestLabelMapSet(t *testing.T) {
+	var m LabelMap
+	rtest.OK(t, m.Set("env=prod"))
+	rtest.OK(t, m.Set("lsn=0/16B3748"))
+	rtest.OK(t, m.Set("query=a=b"))
+	rtest.OK(t, m.Set("empty="))
+	rtest.OK(t, m.Set("env=dev"))
+	rtest.Equals(t, LabelMap{"env": "dev", "lsn": "0/16B3748", "query": "a=b", "empty": ""}, m)
+	rtest.Equals(t, "[empty=, env=dev, lsn=0/16B3748, query=a=b]", m.String())
+
+	for _, label := range []string{"", "env", "=prod"} {
+		rtest.Assert(t, m.Set(label) != nil, "expected error for invalid label %q", label)
+	}
+}
@@ -21,7 +21,9 @@ import (
 //
 // There are two types of locks: exclusive and non-exclusive. There may be many
 // different non-exclusive locks, but at most one exclusive lock, which can
-// only be acquired while no non-exclusive lock is held.
+// only be acquired while no non-exclusive lock is held. A non-exclusive lock
+// can additionally be marked as prune lock, at most one prune lock can be held
+// at a time.
 //
 // A lock must be refreshed regularly to not be considered stale, this must be
 // triggered by regularly calling Refresh.
@@ -29,6 +31,7 @@ type Lock struct {
 	lock      sync.Mutex
 	Time      time.Time `json:"time"`
 	Exclusive bool      `json:"exclusive"`
+	Prune     bool      `json:"prune,omitempty"`
 	Hostname  string    `json:"hostname"`
 	Username  string    `json:"username"`
 	PID       int       `json:"pid"`
@@ -49,6 +52,8 @@ func (e *alreadyLockedError) Error() string {
 	s := ""
 	if e.otherLock.Exclusive {
 		s = "exclusively "
+	} else if e.otherLock.Prune {
+		s = "for pruning "
 	}
 	return fmt.Sprintf("repository is already locked %sby %v", s, e.otherLock)
 }
@@ -85,14 +90,22 @@ func IsInvalidLock(err error) bool {
 // exclusive lock is already held by another process, it returns an error
 // that satisfies IsAlreadyLocked.
 func NewLock(ctx context.Context, repo Repository) (*Lock, error) {
-	return newLock(ctx, repo, false)
+	return newLock(ctx, repo, false, false)
 }
 
 // NewExclusiveLock returns a new, exclusive lock for the repository. If
 // another lock (normal and exclusive) is already held by another process,
 // it returns an error that satisfies IsAlreadyLocked.
 func NewExclusiveLock(ctx context.Context, repo Repository) (*Lock, error) {
-	return newLock(ctx, repo, true)
+	return newLock(ctx, repo, true, false)
+}
+
+// NewPruneLock returns a new, non-exclusive lock for the repository which
+// additionally conflicts with other prune locks. If an exclusive lock or a
+// prune lock is already held by another process, it returns an error that
+// satisfies IsAlreadyLocked.
+func NewPruneLock(ctx context.Context, repo Repository) (*Lock, error) {
+	return newLock(ctx, repo, false, true)
 }
 
 var waitBeforeLockCheck = 200 * time.Millisecond
@@ -103,11 +116,12 @@ func TestSetLockTimeout(t testing.TB, d time.Duration) {
 	waitBeforeLockCheck = d
 }
 
-func newLock(ctx context.Context, repo Repository, excl bool) (*Lock, error) {
+func newLock(ctx context.Context, repo Repository, excl bool, prune bool) (*Lock, error) {
 	lock := &Lock{
 		Time:      time.Now(),
 		PID:       os.Getpid(),
 		Exclusive: excl,
+		Prune:     prune,
 		repo:      repo,
 	}
 
@@ -157,7 +171,7 @@ func (l *Lock) fillUserInfo() error {
 // If an exclusive lock is to be created, checkForOtherLocks returns an error
 // if there are any other locks, regardless if exclusive or not. If a
 // non-exclusive lock is to be created, an error is only returned when an
-// exclusive lock is found.
+// exclusive lock is found. A prune lock also conflicts with other prune locks.
 func (l *Lock) checkForOtherLocks(ctx context.Context) error {
 	var err error
 	// retry locking a few times
@@ -178,6 +192,10 @@ func (l *Lock) checkForOtherLocks(ctx context.Context) error {
 				return &alreadyLockedError{otherLock: lock}
 			}
 
+			if l.Prune && lock.Prune {
+				return &alreadyLockedError{otherLock: lock}
+			}
+
 			return nil
 		})
 		// no lock detected
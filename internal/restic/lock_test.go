@@ -113,6 +113,30 @@ func TestExclusiveLockOnLockedRepo(t *testing.T) {
 	rtest.OK(t, elock.Unlock())
 }
 
+func TestPruneLockOnPruneLockedRepo(t *testing.T) {
+	repo := repository.TestRepository(t)
+
+	plock, err := restic.NewPruneLock(context.TODO(), repo)
+	rtest.OK(t, err)
+
+	lock, err := restic.NewLock(context.TODO(), repo)
+	rtest.OK(t, err)
+	rtest.OK(t, lock.Unlock())
+
+	lock, err = restic.NewPruneLock(context.TODO(), repo)
+	rtest.Assert(t, err != nil,
+		"create prune lock with prune locked repo didn't return an error")
+	rtest.Assert(t, restic.IsAlreadyLocked(err),
+		"create prune lock with prune locked repo didn't return the correct error")
+
+	rtest.OK(t, lock.Unlock())
+	rtest.OK(t, plock.Unlock())
+
+	plock, err = restic.NewPruneLock(context.TODO(), repo)
+	rtest.OK(t, err)
+	rtest.OK(t, plock.Unlock())
+}
+
 func createFakeLock(repo restic.Repository, t time.Time, pid int) (restic.ID, error) {
 	hostname, err := os.Hostname()
 	if err != nil {
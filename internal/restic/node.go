@@ -98,6 +98,11 @@ func NodeFromFileInfo(path string, fi os.FileInfo) (*Node, error) {
 		node.Size = uint64(fi.Size())
 	}
 
+	if hdr, ok := fi.Sys().(*fs.ArchiveHeader); ok {
+		node.fillArchiveHeader(hdr)
+		return node, nil
+	}
+
 	err := node.fillExtra(path, fi)
 	return node, err
 }
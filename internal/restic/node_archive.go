@@ -0,0 +1,61 @@
+package restic
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/restic/restic/internal/fs"
+)
+
+// paxXattrPrefix is the prefix of the PAX records used by GNU tar and others
+// to store extended attributes.
+const paxXattrPrefix = "SCHILY.xattr."
+
+// fillArchiveHeader fills the node with the metadata of an archive member.
+func (node *Node) fillArchiveHeader(hdr *fs.ArchiveHeader) {
+	node.Inode = hdr.Inode
+	node.UID = uint32(hdr.Uid)
+	node.GID = uint32(hdr.Gid)
+	node.User = hdr.Uname
+	node.Group = hdr.Gname
+
+	node.AccessTime = hdr.AccessTime
+	if node.AccessTime.IsZero() {
+		node.AccessTime = node.ModTime
+	}
+	node.ChangeTime = hdr.ChangeTime
+	if node.ChangeTime.IsZero() {
+		node.ChangeTime = node.ModTime
+	}
+
+	switch node.Type {
+	case "file":
+		node.Links = hdr.Links
+	case "symlink":
+		node.LinkTarget = hdr.Linkname
+	case "dev", "chardev":
+		node.Device = mkdev(hdr.Devmajor, hdr.Devminor)
+	}
+
+	var names []string
+	for key := range hdr.PAXRecords {
+		if strings.HasPrefix(key, paxXattrPrefix) {
+			names = append(names, strings.TrimPrefix(key, paxXattrPrefix))
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		node.ExtendedAttributes = append(node.ExtendedAttributes, ExtendedAttribute{
+			Name:  name,
+			Value: []byte(hdr.PAXRecords[paxXattrPrefix+name]),
+		})
+	}
+}
+
+// mkdev returns the device number for the major and minor numbers, using the
+// encoding of glibc on Linux.
+func mkdev(major, minor int64) uint64 {
+	ma, mi := uint64(major), uint64(minor)
+	return (ma&0xfffff000)<<32 | (ma&0x00000fff)<<8 |
+		(mi&0xffffff00)<<12 | (mi & 0x000000ff)
+}
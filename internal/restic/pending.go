@@ -0,0 +1,52 @@
+package restic
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// PendingDeletion lists pack files which were removed from the index by a
+// prune run that did not hold an exclusive lock. A backup which started before
+// Time may still reference data stored in these packs, thus they must only be
+// deleted once no such backup can be running anymore.
+type PendingDeletion struct {
+	Time  time.Time `json:"time"`
+	Packs IDs       `json:"packs"`
+}
+
+// NewPendingDeletion returns a record for packs using the current time.
+func NewPendingDeletion(packs IDSet) *PendingDeletion {
+	return &PendingDeletion{
+		Time:  time.Now(),
+		Packs: packs.List(),
+	}
+}
+
+// SavePendingDeletion saves the record p in the repository.
+func SavePendingDeletion(ctx context.Context, repo SaverUnpacked, p *PendingDeletion) (ID, error) {
+	return SaveJSONUnpacked(ctx, repo, PendingFile, p)
+}
+
+// LoadPendingDeletion loads the record with the given ID.
+func LoadPendingDeletion(ctx context.Context, loader LoaderUnpacked, id ID) (*PendingDeletion, error) {
+	p := &PendingDeletion{}
+	err := LoadJSONUnpacked(ctx, loader, PendingFile, id, p)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ForAllPendingDeletions loads all records of pending pack deletions in
+// parallel and calls fn for each of them.
+func ForAllPendingDeletions(ctx context.Context, be Lister, loader LoaderUnpacked, fn func(ID, *PendingDeletion, error) error) error {
+	var m sync.Mutex
+
+	return ParallelList(ctx, be, PendingFile, loader.Connections(), func(ctx context.Context, id ID, size int64) error {
+		p, err := LoadPendingDeletion(ctx, loader, id)
+		m.Lock()
+		defer m.Unlock()
+		return fn(id, p, err)
+	})
+}
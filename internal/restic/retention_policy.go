@@ -0,0 +1,315 @@
+package restic
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/restic/restic/internal/debug"
+	"github.com/restic/restic/internal/errors"
+)
+
+// RetentionPolicy is the retention policy stored in the repository. It
+// assigns an ExpirePolicy to groups of snapshots, such that the same policy is
+// applied regardless of which client runs forget.
+type RetentionPolicy struct {
+	// GroupBy configures how snapshots are grouped before the rules are applied.
+	GroupBy SnapshotGroupByOptions `json:"group_by"`
+	Rules   []RetentionRule        `json:"rules"`
+}
+
+// RetentionRule applies Policy to all snapshot groups which match Hostname,
+// Paths and Tags. An empty value matches all groups.
+type RetentionRule struct {
+	Hostname string       `json:"hostname,omitempty"`
+	Paths    []string     `json:"paths,omitempty"`
+	Tags     []string     `json:"tags,omitempty"`
+	Policy   ExpirePolicy `json:"policy"`
+}
+
+// NewRetentionRule returns a rule for the given group selectors and policy.
+// paths and tags are sorted in the same way as for a SnapshotGroupKey.
+func NewRetentionRule(hostname string, paths, tags []string, policy ExpirePolicy) RetentionRule {
+	r := RetentionRule{
+		Hostname: hostname,
+		Paths:    append([]string(nil), paths...),
+		Tags:     append([]string(nil), tags...),
+		Policy:   policy,
+	}
+	sort.Strings(r.Paths)
+	sort.Strings(r.Tags)
+	if len(r.Paths) == 0 {
+		r.Paths = nil
+	}
+	if len(r.Tags) == 0 {
+		r.Tags = nil
+	}
+	return r
+}
+
+// Groups returns a description of the snapshot groups the rule applies to.
+func (r RetentionRule) Groups() string {
+	var parts []string
+	if r.Hostname != "" {
+		parts = append(parts, fmt.Sprintf("host [%s]", r.Hostname))
+	}
+	if len(r.Paths) > 0 {
+		parts = append(parts, fmt.Sprintf("paths [%s]", strings.Join(r.Paths, ", ")))
+	}
+	if len(r.Tags) > 0 {
+		parts = append(parts, fmt.Sprintf("tags [%s]", strings.Join(r.Tags, ", ")))
+	}
+	if len(parts) == 0 {
+		return "all snapshot groups"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sameGroups returns true if r and other apply to the same snapshot groups.
+func (r RetentionRule) sameGroups(other RetentionRule) bool {
+	return r.Hostname == other.Hostname &&
+		equalStrings(r.Paths, other.Paths) &&
+		equalStrings(r.Tags, other.Tags)
+}
+
+// matches returns true if the rule applies to the snapshot group key.
+func (r RetentionRule) matches(key SnapshotGroupKey) bool {
+	if r.Hostname != "" && r.Hostname != key.Hostname {
+		return false
+	}
+	if len(r.Paths) > 0 && !equalStrings(r.Paths, key.Paths) {
+		return false
+	}
+	if len(r.Tags) > 0 && !equalStrings(r.Tags, key.Tags) {
+		return false
+	}
+	return true
+}
+
+// overlaps returns true if a snapshot group exists which is matched by both r
+// and other.
+func (r RetentionRule) overlaps(other RetentionRule) bool {
+	if r.Hostname != "" && other.Hostname != "" && r.Hostname != other.Hostname {
+		return false
+	}
+	if len(r.Paths) > 0 && len(other.Paths) > 0 && !equalStrings(r.Paths, other.Paths) {
+		return false
+	}
+	if len(r.Tags) > 0 && len(other.Tags) > 0 && !equalStrings(r.Tags, other.Tags) {
+		return false
+	}
+	return true
+}
+
+// intersect returns a rule without policy which selects exactly the snapshot
+// groups matched by both r and other. The rules must overlap.
+func (r RetentionRule) intersect(other RetentionRule) RetentionRule {
+	res := RetentionRule{Hostname: r.Hostname, Paths: r.Paths, Tags: r.Tags}
+	if res.Hostname == "" {
+		res.Hostname = other.Hostname
+	}
+	if len(res.Paths) == 0 {
+		res.Paths = other.Paths
+	}
+	if len(res.Tags) == 0 {
+		res.Tags = other.Tags
+	}
+	return res
+}
+
+// specificity returns the number of selectors set for the rule.
+func (r RetentionRule) specificity() (n int) {
+	if r.Hostname != "" {
+		n++
+	}
+	if len(r.Paths) > 0 {
+		n++
+	}
+	if len(r.Tags) > 0 {
+		n++
+	}
+	return n
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// SetRule adds rule to the policy. An existing rule for the same snapshot
+// groups is replaced.
+func (p *RetentionPolicy) SetRule(rule RetentionRule) {
+	for i, r := range p.Rules {
+		if r.sameGroups(rule) {
+			p.Rules[i] = rule
+			return
+		}
+	}
+	p.Rules = append(p.Rules, rule)
+}
+
+// RemoveRule removes the rule for the same snapshot groups as rule and
+// returns true if such a rule existed.
+func (p *RetentionPolicy) RemoveRule(rule RetentionRule) bool {
+	for i, r := range p.Rules {
+		if r.sameGroups(rule) {
+			p.Rules = append(p.Rules[:i], p.Rules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup returns the ExpirePolicy for the snapshot group with the given key.
+// If several rules match, the one with the most selectors wins, Validate
+// ensures that this rule is unique. ok is false if no rule matches.
+func (p *RetentionPolicy) Lookup(key SnapshotGroupKey) (policy ExpirePolicy, ok bool) {
+	best := -1
+	for _, r := range p.Rules {
+		if !r.matches(key) {
+			continue
+		}
+		if s := r.specificity(); s > best {
+			best = s
+			policy = r.Policy
+			ok = true
+		}
+	}
+	return policy, ok
+}
+
+// Validate returns an error if the rules cannot be applied to the snapshot
+// groups created according to GroupBy.
+func (p *RetentionPolicy) Validate() error {
+	for i, r := range p.Rules {
+		if r.Hostname != "" && !p.GroupBy.Host {
+			return errors.Errorf("rule for %v selects a host, but snapshots are not grouped by host", r.Groups())
+		}
+		if len(r.Paths) > 0 && !p.GroupBy.Path {
+			return errors.Errorf("rule for %v selects paths, but snapshots are not grouped by paths", r.Groups())
+		}
+		if len(r.Tags) > 0 && !p.GroupBy.Tag {
+			return errors.Errorf("rule for %v selects tags, but snapshots are not grouped by tags", r.Groups())
+		}
+		if r.Policy.Empty() {
+			return errors.Errorf("rule for %v has an empty policy", r.Groups())
+		}
+		if err := r.Policy.Buckets.Validate(); err != nil {
+			return errors.Errorf("rule for %v: %v", r.Groups(), err)
+		}
+		for _, other := range p.Rules[:i] {
+			if r.sameGroups(other) {
+				return errors.Errorf("duplicate rule for %v", r.Groups())
+			}
+			if r.specificity() == other.specificity() && r.overlaps(other) && !p.hasRule(r.intersect(other)) {
+				return errors.Errorf("rules for %v and for %v both apply to %v, add a rule for these snapshot groups to decide which policy is used",
+					other.Groups(), r.Groups(), r.intersect(other).Groups())
+			}
+		}
+	}
+	return nil
+}
+
+// hasRule returns true if p contains a rule for the same snapshot groups as
+// rule.
+func (p *RetentionPolicy) hasRule(rule RetentionRule) bool {
+	for _, r := range p.Rules {
+		if r.sameGroups(rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsIndex returns true if any of the rules requires the repository index
+// to be loaded, which is the case for size budgets.
+func (p *RetentionPolicy) NeedsIndex() bool {
+	for _, r := range p.Rules {
+		if r.Policy.SizeBudget > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadRetentionPolicy loads the retention policy stored in the repository. If
+// the repository does not contain a retention policy, nil is returned.
+func LoadRetentionPolicy(ctx context.Context, repo Repository) (*RetentionPolicy, error) {
+	if repo.Config().Supports(PolicyFile) != nil {
+		// older repository versions cannot contain a policy
+		return nil, nil
+	}
+
+	_, err := repo.Backend().Stat(ctx, Handle{Type: PolicyFile})
+	if err != nil {
+		if repo.Backend().IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	p := &RetentionPolicy{}
+	err = LoadJSONUnpacked(ctx, repo, PolicyFile, ID{}, p)
+	if err != nil {
+		return nil, errors.Wrap(err, "load retention policy")
+	}
+
+	return p, nil
+}
+
+// SaveRetentionPolicy stores p in the repository, replacing the existing
+// retention policy. For backends which cannot replace files atomically, the
+// existing policy is removed first and uploaded again if saving p fails.
+func SaveRetentionPolicy(ctx context.Context, repo Repository, p *RetentionPolicy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if err := repo.Config().Supports(PolicyFile); err != nil {
+		return err
+	}
+
+	h := Handle{Type: PolicyFile}
+	be := repo.Backend()
+
+	// keep a copy of the raw policy file in case saving the new one fails
+	var old []byte
+	err := be.Load(ctx, h, 0, 0, func(rd io.Reader) (err error) {
+		old, err = io.ReadAll(rd)
+		return err
+	})
+	if err != nil && !be.IsNotExist(err) {
+		return errors.Wrap(err, "load retention policy")
+	}
+
+	if old != nil && !be.HasAtomicReplace() {
+		if err := be.Remove(ctx, h); err != nil {
+			return errors.Wrap(err, "remove retention policy")
+		}
+	}
+
+	debug.Log("saving retention policy with %d rules", len(p.Rules))
+	_, err = SaveJSONUnpacked(ctx, repo, PolicyFile, p)
+	if err != nil && old != nil && !be.HasAtomicReplace() {
+		// restore the previous policy
+		_ = be.Remove(ctx, h)
+		if rerr := be.Save(ctx, h, NewByteReader(old, be.Hasher())); rerr != nil {
+			return errors.Errorf("save retention policy failed (%v), restoring the previous policy failed as well (%v)", err, rerr)
+		}
+		return errors.Wrap(err, "save retention policy failed, the previous policy was restored")
+	}
+	return err
+}
+
+// RemoveRetentionPolicy removes the retention policy from the repository.
+func RemoveRetentionPolicy(ctx context.Context, repo Repository) error {
+	return repo.Backend().Remove(ctx, Handle{Type: PolicyFile})
+}
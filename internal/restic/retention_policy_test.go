@@ -0,0 +1,209 @@
+package restic_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/restic/restic/internal/backend/mem"
+	"github.com/restic/restic/internal/repository"
+	"github.com/restic/restic/internal/restic"
+	rtest "github.com/restic/restic/internal/test"
+)
+
+func TestRetentionPolicyLookup(t *testing.T) {
+	def := restic.ExpirePolicy{Daily: 7}
+	host := restic.ExpirePolicy{Daily: 14}
+	hostPath := restic.ExpirePolicy{Weekly: 4}
+
+	p := &restic.RetentionPolicy{GroupBy: restic.SnapshotGroupByOptions{Host: true, Path: true}}
+	p.SetRule(restic.NewRetentionRule("", nil, nil, def))
+	p.SetRule(restic.NewRetentionRule("foo", nil, nil, host))
+	p.SetRule(restic.NewRetentionRule("foo", []string{"/srv", "/home"}, nil, hostPath))
+	rtest.OK(t, p.Validate())
+
+	var tests = []struct {
+		key    restic.SnapshotGroupKey
+		policy restic.ExpirePolicy
+	}{
+		{restic.SnapshotGroupKey{Hostname: "bar", Paths: []string{"/home"}}, def},
+		{restic.SnapshotGroupKey{Hostname: "foo", Paths: []string{"/home"}}, host},
+		{restic.SnapshotGroupKey{Hostname: "foo", Paths: []string{"/home", "/srv"}}, hostPath},
+	}
+
+	for _, test := range tests {
+		policy, ok := p.Lookup(test.key)
+		rtest.Assert(t, ok, "no rule found for %v", test.key)
+		rtest.Equals(t, test.policy, policy)
+	}
+
+	// replace the default rule
+	p.SetRule(restic.NewRetentionRule("", nil, nil, restic.ExpirePolicy{Last: 3}))
+	rtest.Equals(t, 3, len(p.Rules))
+	policy, _ := p.Lookup(restic.SnapshotGroupKey{Hostname: "bar"})
+	rtest.Equals(t, restic.ExpirePolicy{Last: 3}, policy)
+
+	rtest.Assert(t, p.RemoveRule(restic.NewRetentionRule("", nil, nil, restic.ExpirePolicy{})), "default rule was not removed")
+	rtest.Assert(t, !p.RemoveRule(restic.NewRetentionRule("bar", nil, nil, restic.ExpirePolicy{})), "nonexistent rule was removed")
+	_, ok := p.Lookup(restic.SnapshotGroupKey{Hostname: "bar"})
+	rtest.Assert(t, !ok, "unexpected rule found for host bar")
+}
+
+func TestRetentionPolicyValidate(t *testing.T) {
+	var tests = []struct {
+		groupBy restic.SnapshotGroupByOptions
+		rule    restic.RetentionRule
+		valid   bool
+	}{
+		{restic.SnapshotGroupByOptions{}, restic.NewRetentionRule("", nil, nil, restic.ExpirePolicy{Last: 1}), true},
+		{restic.SnapshotGroupByOptions{}, restic.NewRetentionRule("", nil, nil, restic.ExpirePolicy{}), false},
+		{restic.SnapshotGroupByOptions{Path: true}, restic.NewRetentionRule("foo", nil, nil, restic.ExpirePolicy{Last: 1}), false},
+		{restic.SnapshotGroupByOptions{Host: true}, restic.NewRetentionRule("foo", []string{"/home"}, nil, restic.ExpirePolicy{Last: 1}), false},
+		{restic.SnapshotGroupByOptions{Host: true}, restic.NewRetentionRule("", nil, []string{"foo"}, restic.ExpirePolicy{Last: 1}), false},
+		{restic.SnapshotGroupByOptions{Host: true, Tag: true}, restic.NewRetentionRule("foo", nil, []string{"foo"}, restic.ExpirePolicy{Last: 1}), true},
+	}
+
+	for _, test := range tests {
+		p := &restic.RetentionPolicy{GroupBy: test.groupBy, Rules: []restic.RetentionRule{test.rule}}
+		err := p.Validate()
+		if test.valid {
+			rtest.OK(t, err)
+		} else {
+			rtest.Assert(t, err != nil, "expected error for rule %v with grouping %v", test.rule.Groups(), test.groupBy)
+		}
+	}
+
+	p := &restic.RetentionPolicy{Rules: []restic.RetentionRule{
+		restic.NewRetentionRule("", nil, nil, restic.ExpirePolicy{Last: 1}),
+		restic.NewRetentionRule("", nil, nil, restic.ExpirePolicy{Last: 2}),
+	}}
+	rtest.Assert(t, p.Validate() != nil, "expected error for duplicate rules")
+}
+
+func TestRetentionPolicyValidateTies(t *testing.T) {
+	p := &restic.RetentionPolicy{GroupBy: restic.SnapshotGroupByOptions{Host: true, Path: true, Tag: true}}
+	p.SetRule(restic.NewRetentionRule("foo", nil, nil, restic.ExpirePolicy{Last: 1}))
+	p.SetRule(restic.NewRetentionRule("bar", nil, nil, restic.ExpirePolicy{Last: 2}))
+	rtest.OK(t, p.Validate())
+
+	// both rules match the group of host foo with path /home
+	p.SetRule(restic.NewRetentionRule("", []string{"/home"}, nil, restic.ExpirePolicy{Last: 3}))
+	rtest.Assert(t, p.Validate() != nil, "expected error for rules with the same specificity")
+
+	// more specific rules for the overlapping groups resolve the ties
+	p.SetRule(restic.NewRetentionRule("foo", []string{"/home"}, nil, restic.ExpirePolicy{Last: 4}))
+	p.SetRule(restic.NewRetentionRule("bar", []string{"/home"}, nil, restic.ExpirePolicy{Last: 5}))
+	rtest.OK(t, p.Validate())
+
+	policy, ok := p.Lookup(restic.SnapshotGroupKey{Hostname: "foo", Paths: []string{"/home"}})
+	rtest.Assert(t, ok, "no rule found")
+	rtest.Equals(t, restic.ExpirePolicy{Last: 4}, policy)
+
+	p.SetRule(restic.NewRetentionRule("", nil, []string{"a"}, restic.ExpirePolicy{Last: 6}))
+	rtest.Assert(t, p.Validate() != nil, "expected error for rules with the same specificity")
+	rtest.Assert(t, p.RemoveRule(restic.NewRetentionRule("", []string{"/home"}, nil, restic.ExpirePolicy{})), "rule was not removed")
+	rtest.Assert(t, p.RemoveRule(restic.NewRetentionRule("foo", nil, nil, restic.ExpirePolicy{})), "rule was not removed")
+	rtest.Assert(t, p.RemoveRule(restic.NewRetentionRule("bar", nil, nil, restic.ExpirePolicy{})), "rule was not removed")
+	// rules selecting different tags never match the same group
+	p.SetRule(restic.NewRetentionRule("", nil, []string{"b"}, restic.ExpirePolicy{Last: 7}))
+	rtest.OK(t, p.Validate())
+}
+
+func TestRetentionPolicyJSON(t *testing.T) {
+	p := &restic.RetentionPolicy{GroupBy: restic.SnapshotGroupByOptions{Host: true, Tag: true}}
+	p.SetRule(restic.NewRetentionRule("foo", nil, []string{"b", "a"}, restic.ExpirePolicy{
+		Daily:        7,
+		WithinWeekly: restic.ParseDurationOrPanic("1y2m"),
+		Tags:         []restic.TagList{{"keep"}},
+		SizeBudget:   1024,
+	}))
+
+	buf, err := json.Marshal(p)
+	rtest.OK(t, err)
+
+	var p2 restic.RetentionPolicy
+	rtest.OK(t, json.Unmarshal(buf, &p2))
+	rtest.Equals(t, *p, p2)
+}
+
+func TestRetentionPolicySaveLoad(t *testing.T) {
+	repo := repository.TestRepositoryWithVersion(t, 3)
+	ctx := context.TODO()
+
+	p, err := restic.LoadRetentionPolicy(ctx, repo)
+	rtest.OK(t, err)
+	rtest.Assert(t, p == nil, "expected no policy, got %v", p)
+
+	p = &restic.RetentionPolicy{GroupBy: restic.SnapshotGroupByOptions{Host: true}}
+	p.SetRule(restic.NewRetentionRule("", nil, nil, restic.ExpirePolicy{Last: 5}))
+	rtest.OK(t, restic.SaveRetentionPolicy(ctx, repo, p))
+
+	// saving again must replace the existing policy
+	p.SetRule(restic.NewRetentionRule("foo", nil, nil, restic.ExpirePolicy{Daily: 3}))
+	rtest.OK(t, restic.SaveRetentionPolicy(ctx, repo, p))
+
+	loaded, err := restic.LoadRetentionPolicy(ctx, repo)
+	rtest.OK(t, err)
+	rtest.Equals(t, p, loaded)
+
+	rtest.OK(t, restic.RemoveRetentionPolicy(ctx, repo))
+	loaded, err = restic.LoadRetentionPolicy(ctx, repo)
+	rtest.OK(t, err)
+	rtest.Assert(t, loaded == nil, "expected no policy after removal, got %v", loaded)
+}
+
+type failPolicySavingBackend struct {
+	restic.Backend
+	fail bool
+}
+
+// failNext returns true once after fail was set.
+func (be *failPolicySavingBackend) failNext() bool {
+	fail := be.fail
+	be.fail = false
+	return fail
+}
+
+func (be *failPolicySavingBackend) Save(ctx context.Context, h restic.Handle, rd restic.RewindReader) error {
+	if h.Type == restic.PolicyFile && rd.Length() > 0 && be.failNext() {
+		return fmt.Errorf("error saving policy")
+	}
+	return be.Backend.Save(ctx, h, rd)
+}
+
+func TestRetentionPolicySaveRestore(t *testing.T) {
+	be := &failPolicySavingBackend{Backend: mem.New()}
+	repo := repository.TestRepositoryWithBackend(t, be, 3)
+	ctx := context.TODO()
+
+	p := &restic.RetentionPolicy{}
+	p.SetRule(restic.NewRetentionRule("", nil, nil, restic.ExpirePolicy{Last: 5}))
+	rtest.OK(t, restic.SaveRetentionPolicy(ctx, repo, p))
+
+	newPolicy := &restic.RetentionPolicy{}
+	newPolicy.SetRule(restic.NewRetentionRule("", nil, nil, restic.ExpirePolicy{Daily: 3}))
+	be.fail = true
+	err := restic.SaveRetentionPolicy(ctx, repo, newPolicy)
+	rtest.Assert(t, err != nil, "saving the policy did not fail")
+
+	// the in-memory backend cannot replace files atomically, thus the
+	// previous policy must have been uploaded again
+	loaded, err := restic.LoadRetentionPolicy(ctx, repo)
+	rtest.OK(t, err)
+	rtest.Equals(t, p, loaded)
+}
+
+func TestRetentionPolicyRepoVersion(t *testing.T) {
+	repo := repository.TestRepositoryWithVersion(t, 2)
+	ctx := context.TODO()
+
+	p := &restic.RetentionPolicy{}
+	p.SetRule(restic.NewRetentionRule("", nil, nil, restic.ExpirePolicy{Last: 5}))
+	err := restic.SaveRetentionPolicy(ctx, repo, p)
+	rtest.Assert(t, err != nil, "saving a policy in a version 2 repository succeeded")
+
+	loaded, err := restic.LoadRetentionPolicy(ctx, repo)
+	rtest.OK(t, err)
+	rtest.Assert(t, loaded == nil, "expected no policy, got %v", loaded)
+}
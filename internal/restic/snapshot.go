@@ -23,11 +23,40 @@ type Snapshot struct {
 	GID      uint32    `json:"gid,omitempty"`
 	Excludes []string  `json:"excludes,omitempty"`
 	Tags     []string  `json:"tags,omitempty"`
+	Labels   LabelMap  `json:"labels,omitempty"`
 	Original *ID       `json:"original,omitempty"`
 
+	Description string           `json:"description,omitempty"`
+	Command     []string         `json:"command,omitempty"` // command which produced the backed up data
+	Summary     *SnapshotSummary `json:"summary,omitempty"`
+
+	// Hold protects the snapshot from being removed by forget and prune.
+	Hold bool `json:"hold,omitempty"`
+
 	id *ID // plaintext ID, used during restore
 }
 
+// SnapshotSummary contains statistics about the backup which created a
+// snapshot. It is only set for snapshots created by the backup command.
+type SnapshotSummary struct {
+	BackupStart time.Time `json:"backup_start"`
+	BackupEnd   time.Time `json:"backup_end"`
+
+	// statistics from the backup json output
+	FilesNew            uint   `json:"files_new"`
+	FilesChanged        uint   `json:"files_changed"`
+	FilesUnmodified     uint   `json:"files_unmodified"`
+	DirsNew             uint   `json:"dirs_new"`
+	DirsChanged         uint   `json:"dirs_changed"`
+	DirsUnmodified      uint   `json:"dirs_unmodified"`
+	DataBlobs           int    `json:"data_blobs"`
+	TreeBlobs           int    `json:"tree_blobs"`
+	DataAdded           uint64 `json:"data_added"`
+	DataAddedPacked     uint64 `json:"data_added_packed"`
+	TotalFilesProcessed uint   `json:"total_files_processed"`
+	TotalBytesProcessed uint64 `json:"total_bytes_processed"`
+}
+
 // NewSnapshot returns an initialized snapshot struct for the current user and
 // time.
 func NewSnapshot(paths []string, tags []string, hostname string, time time.Time) (*Snapshot, error) {
@@ -194,6 +223,49 @@ func (sn *Snapshot) HasTagList(l []TagList) bool {
 	return false
 }
 
+// SetLabels sets the given labels on the snapshot, replacing the values of
+// already existing keys. It returns true if any changes were made.
+func (sn *Snapshot) SetLabels(labels map[string]string) (changed bool) {
+	for key, value := range labels {
+		if old, ok := sn.Labels[key]; ok && old == value {
+			continue
+		}
+		if sn.Labels == nil {
+			sn.Labels = make(LabelMap)
+		}
+		sn.Labels[key] = value
+		changed = true
+	}
+	return changed
+}
+
+// RemoveLabels removes the labels with the given keys from the snapshot and
+// returns true if any changes were made.
+func (sn *Snapshot) RemoveLabels(keys []string) (changed bool) {
+	for _, key := range keys {
+		if _, ok := sn.Labels[key]; ok {
+			delete(sn.Labels, key)
+			changed = true
+		}
+	}
+	if len(sn.Labels) == 0 {
+		sn.Labels = nil
+	}
+	return changed
+}
+
+// HasLabels returns true if the snapshot has all of the labels with the same
+// values.
+func (sn *Snapshot) HasLabels(labels map[string]string) bool {
+	for key, value := range labels {
+		if v, ok := sn.Labels[key]; !ok || v != value {
+			return false
+		}
+	}
+
+	return true
+}
+
 // HasPaths returns true if the snapshot has all of the paths.
 func (sn *Snapshot) HasPaths(paths []string) bool {
 	m := make(map[string]struct{}, len(sn.Paths))
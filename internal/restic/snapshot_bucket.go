@@ -0,0 +1,209 @@
+package restic
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/restic/restic/internal/debug"
+	"github.com/restic/restic/internal/errors"
+)
+
+// BucketOptions configures how snapshots are grouped into calendar periods
+// (hours, days, weeks, months, quarters and years) by an ExpirePolicy. The
+// zero value keeps the newest snapshot per period, starts weeks on Monday and
+// uses the time zone recorded in each snapshot.
+type BucketOptions struct {
+	KeepOldest bool     `json:"keep_oldest,omitempty"` // keep the oldest instead of the newest snapshot per period
+	WeekStart  Weekday  `json:"week_start,omitempty"`  // first day of a week
+	Timezone   Timezone `json:"timezone,omitempty"`    // time zone used to determine the period of a snapshot
+}
+
+func (o BucketOptions) String() string {
+	var parts []string
+	if o.KeepOldest {
+		parts = append(parts, "oldest snapshot per period")
+	}
+	if o.WeekStart != Monday {
+		parts = append(parts, fmt.Sprintf("weeks starting on %v", o.WeekStart))
+	}
+	if o.Timezone != "" {
+		parts = append(parts, fmt.Sprintf("time zone %v", o.Timezone))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Validate returns an error if the time zone cannot be loaded.
+func (o BucketOptions) Validate() error {
+	_, err := o.Timezone.Location()
+	return err
+}
+
+// Weekday is the first day of a week, counted in days after Monday such that
+// the zero value is Monday.
+type Weekday int
+
+// Monday is the default first day of a week.
+const Monday Weekday = 0
+
+func (w Weekday) weekday() time.Weekday {
+	return time.Weekday((int(w) + 1) % 7)
+}
+
+func (w Weekday) String() string {
+	return w.weekday().String()
+}
+
+// Set parses the name of a day, e.g. "sunday" or "sun".
+func (w *Weekday) Set(s string) error {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := d.String()
+		if strings.EqualFold(s, name) || strings.EqualFold(s, name[:3]) {
+			*w = Weekday((int(d) + 6) % 7)
+			return nil
+		}
+	}
+	return errors.Errorf("invalid day %q", s)
+}
+
+func (w Weekday) Type() string {
+	return "day"
+}
+
+func (w Weekday) MarshalText() ([]byte, error) {
+	return []byte(w.String()), nil
+}
+
+func (w *Weekday) UnmarshalText(text []byte) error {
+	return w.Set(string(text))
+}
+
+// Timezone is the name of a time zone in the IANA time zone database, e.g.
+// "Europe/Berlin". An empty value means that the time zone recorded in each
+// snapshot is used.
+type Timezone string
+
+// Location returns the time zone, or nil if tz is empty.
+func (tz Timezone) Location() (*time.Location, error) {
+	if tz == "" {
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(string(tz))
+	if err != nil {
+		return nil, errors.Errorf("invalid time zone %q: %v", string(tz), err)
+	}
+	return loc, nil
+}
+
+func (tz Timezone) String() string {
+	return string(tz)
+}
+
+// Set parses the name of a time zone.
+func (tz *Timezone) Set(s string) error {
+	if _, err := Timezone(s).Location(); err != nil {
+		return err
+	}
+	*tz = Timezone(s)
+	return nil
+}
+
+func (tz Timezone) Type() string {
+	return "timezone"
+}
+
+// period is a calendar period into which snapshots are grouped.
+type period int
+
+const (
+	periodSnapshot period = iota // each snapshot is its own period
+	periodHour
+	periodDay
+	periodWeek
+	periodMonth
+	periodQuarter
+	periodYear
+)
+
+// bucketer groups snapshots into periods according to BucketOptions.
+type bucketer struct {
+	keepOldest bool
+	weekStart  time.Weekday
+	loc        *time.Location
+}
+
+func newBucketer(opts BucketOptions) bucketer {
+	loc, err := opts.Timezone.Location()
+	if err != nil {
+		// fall back to the time zone recorded in the snapshots
+		debug.Log("ignoring time zone: %v", err)
+	}
+	return bucketer{
+		keepOldest: opts.KeepOldest,
+		weekStart:  opts.WeekStart.weekday(),
+		loc:        loc,
+	}
+}
+
+// key returns the period of t, e.g. "2023-05-14" for a day or "2023-Q2" for
+// a quarter. Weeks are identified by their first day. The key is empty for
+// periodSnapshot.
+func (b bucketer) key(p period, t time.Time) string {
+	if b.loc != nil {
+		t = t.In(b.loc)
+	}
+
+	switch p {
+	case periodHour:
+		return t.Format("2006-01-02 15:00")
+	case periodDay:
+		return t.Format("2006-01-02")
+	case periodWeek:
+		offset := (int(t.Weekday()) - int(b.weekStart) + 7) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location()).Format("2006-01-02")
+	case periodMonth:
+		return t.Format("2006-01")
+	case periodQuarter:
+		return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+	case periodYear:
+		return t.Format("2006")
+	default:
+		return ""
+	}
+}
+
+// representatives returns for each snapshot in list, which must be sorted
+// newest first, the position of the snapshot kept for its period. Snapshots
+// for which include returns false are not considered, their position is -1.
+func (b bucketer) representatives(list Snapshots, p period, include func(*Snapshot) bool) []int {
+	rep := make([]int, len(list))
+	first, firstKey := -1, ""
+	for nr, sn := range list {
+		rep[nr] = -1
+		if include != nil && !include(sn) {
+			continue
+		}
+
+		key := b.key(p, sn.Time)
+		if first == -1 || key != firstKey || p == periodSnapshot {
+			first, firstKey = nr, key
+		}
+		rep[nr] = first
+	}
+
+	if b.keepOldest {
+		// the last snapshot of each period is the oldest one
+		first, oldest := -1, -1
+		for nr := len(rep) - 1; nr >= 0; nr-- {
+			if rep[nr] == -1 {
+				continue
+			}
+			if rep[nr] != first {
+				first, oldest = rep[nr], nr
+			}
+			rep[nr] = oldest
+		}
+	}
+
+	return rep
+}
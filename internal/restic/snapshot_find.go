@@ -12,23 +12,24 @@ import (
 // ErrNoSnapshotFound is returned when no snapshot for the given criteria could be found.
 var ErrNoSnapshotFound = errors.New("no snapshot found")
 
-// A SnapshotFilter denotes a set of snapshots based on hosts, tags and paths.
+// A SnapshotFilter denotes a set of snapshots based on hosts, tags, labels and paths.
 type SnapshotFilter struct {
 	_ struct{} // Force naming fields in literals.
 
-	Hosts []string
-	Tags  TagLists
-	Paths []string
+	Hosts  []string
+	Tags   TagLists
+	Labels LabelMap
+	Paths  []string
 	// Match snapshots from before this timestamp. Zero for no limit.
 	TimestampLimit time.Time
 }
 
 func (f *SnapshotFilter) empty() bool {
-	return len(f.Hosts)+len(f.Tags)+len(f.Paths) == 0
+	return len(f.Hosts)+len(f.Tags)+len(f.Labels)+len(f.Paths) == 0
 }
 
 func (f *SnapshotFilter) matches(sn *Snapshot) bool {
-	return sn.HasHostname(f.Hosts) && sn.HasTagList(f.Tags) && sn.HasPaths(f.Paths)
+	return sn.HasHostname(f.Hosts) && sn.HasTagList(f.Tags) && sn.HasLabels(f.Labels) && sn.HasPaths(f.Paths)
 }
 
 // findLatest finds the latest snapshot with optional target/directory,
@@ -103,8 +104,8 @@ func (f *SnapshotFilter) FindLatest(ctx context.Context, be Lister, loader Loade
 	if snapshotID == "latest" {
 		sn, err := f.findLatest(ctx, be, loader)
 		if err == ErrNoSnapshotFound {
-			err = fmt.Errorf("snapshot filter (Paths:%v Tags:%v Hosts:%v): %w",
-				f.Paths, f.Tags, f.Hosts, err)
+			err = fmt.Errorf("snapshot filter (Paths:%v Tags:%v Labels:%v Hosts:%v): %w",
+				f.Paths, f.Tags, f.Labels, f.Hosts, err)
 		}
 		return sn, err
 	}
@@ -132,8 +133,8 @@ func (f *SnapshotFilter) FindAll(ctx context.Context, be Lister, loader LoaderUn
 
 				sn, err = f.findLatest(ctx, be, loader)
 				if err == ErrNoSnapshotFound {
-					err = errors.Errorf("no snapshot matched given filter (Paths:%v Tags:%v Hosts:%v)",
-						f.Paths, f.Tags, f.Hosts)
+					err = errors.Errorf("no snapshot matched given filter (Paths:%v Tags:%v Labels:%v Hosts:%v)",
+						f.Paths, f.Tags, f.Labels, f.Hosts)
 				}
 				if sn != nil {
 					ids.Insert(*sn.ID())
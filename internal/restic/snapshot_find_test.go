@@ -43,3 +43,25 @@ func TestFindLatestSnapshotWithMaxTimestamp(t *testing.T) {
 		t.Errorf("FindLatest returned wrong snapshot ID: %v", *sn.ID())
 	}
 }
+
+func TestFindLatestSnapshotWithLabels(t *testing.T) {
+	repo := repository.TestRepository(t)
+	desiredSnapshot := restic.TestCreateSnapshot(t, repo, parseTimeUTC("2017-07-07 07:07:07"), 1, 0)
+	desiredSnapshot.Labels = restic.LabelMap{"env": "prod"}
+	id, err := restic.SaveSnapshot(context.TODO(), repo, desiredSnapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restic.TestCreateSnapshot(t, repo, parseTimeUTC("2019-09-09 09:09:09"), 1, 0)
+
+	sn, err := (&restic.SnapshotFilter{
+		Labels: restic.LabelMap{"env": "prod"},
+	}).FindLatest(context.TODO(), repo.Backend(), repo, "latest")
+	if err != nil {
+		t.Fatalf("FindLatest returned error: %v", err)
+	}
+
+	if *sn.ID() != id {
+		t.Errorf("FindLatest returned wrong snapshot ID: %v", *sn.ID())
+	}
+}
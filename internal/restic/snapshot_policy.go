@@ -8,23 +8,28 @@ import (
 	"time"
 
 	"github.com/restic/restic/internal/debug"
+	"github.com/restic/restic/internal/ui"
 )
 
 // ExpirePolicy configures which snapshots should be automatically removed.
 type ExpirePolicy struct {
-	Last          int       // keep the last n snapshots
-	Hourly        int       // keep the last n hourly snapshots
-	Daily         int       // keep the last n daily snapshots
-	Weekly        int       // keep the last n weekly snapshots
-	Monthly       int       // keep the last n monthly snapshots
-	Yearly        int       // keep the last n yearly snapshots
-	Within        Duration  // keep snapshots made within this duration
-	WithinHourly  Duration  // keep hourly snapshots made within this duration
-	WithinDaily   Duration  // keep daily snapshots made within this duration
-	WithinWeekly  Duration  // keep weekly snapshots made within this duration
-	WithinMonthly Duration  // keep monthly snapshots made within this duration
-	WithinYearly  Duration  // keep yearly snapshots made within this duration
-	Tags          []TagList // keep all snapshots that include at least one of the tag lists.
+	Last            int           `json:"last,omitempty"`        // keep the last n snapshots
+	Hourly          int           `json:"hourly,omitempty"`      // keep the last n hourly snapshots
+	Daily           int           `json:"daily,omitempty"`       // keep the last n daily snapshots
+	Weekly          int           `json:"weekly,omitempty"`      // keep the last n weekly snapshots
+	Monthly         int           `json:"monthly,omitempty"`     // keep the last n monthly snapshots
+	Quarterly       int           `json:"quarterly,omitempty"`   // keep the last n quarterly snapshots
+	Yearly          int           `json:"yearly,omitempty"`      // keep the last n yearly snapshots
+	Within          Duration      `json:"within"`                // keep snapshots made within this duration
+	WithinHourly    Duration      `json:"within_hourly"`         // keep hourly snapshots made within this duration
+	WithinDaily     Duration      `json:"within_daily"`          // keep daily snapshots made within this duration
+	WithinWeekly    Duration      `json:"within_weekly"`         // keep weekly snapshots made within this duration
+	WithinMonthly   Duration      `json:"within_monthly"`        // keep monthly snapshots made within this duration
+	WithinQuarterly Duration      `json:"within_quarterly"`      // keep quarterly snapshots made within this duration
+	WithinYearly    Duration      `json:"within_yearly"`         // keep yearly snapshots made within this duration
+	Tags            []TagList     `json:"tags,omitempty"`        // keep all snapshots that include at least one of the tag lists.
+	SizeBudget      uint64        `json:"size_budget,omitempty"` // only keep the newest snapshots until their unique data reaches this size in bytes
+	Buckets         BucketOptions `json:"buckets"`               // how snapshots are grouped into hours, days, weeks etc.
 }
 
 func (e ExpirePolicy) String() (s string) {
@@ -40,6 +45,7 @@ func (e ExpirePolicy) String() (s string) {
 		{e.Daily, "daily"},
 		{e.Weekly, "weekly"},
 		{e.Monthly, "monthly"},
+		{e.Quarterly, "quarterly"},
 		{e.Yearly, "yearly"},
 	} {
 		if opt.count > 0 {
@@ -65,6 +71,10 @@ func (e ExpirePolicy) String() (s string) {
 		keepw = append(keepw, fmt.Sprintf("monthly snapshots within %v", e.WithinMonthly))
 	}
 
+	if !e.WithinQuarterly.Zero() {
+		keepw = append(keepw, fmt.Sprintf("quarterly snapshots within %v", e.WithinQuarterly))
+	}
+
 	if !e.WithinYearly.Zero() {
 		keepw = append(keepw, fmt.Sprintf("yearly snapshots within %v", e.WithinYearly))
 	}
@@ -94,8 +104,19 @@ func (e ExpirePolicy) String() (s string) {
 		s += fmt.Sprintf("all snapshots within %s of the newest", e.Within)
 	}
 
+	if e.SizeBudget > 0 {
+		if s != "" {
+			s += ", "
+		}
+		s += fmt.Sprintf("newest snapshots within a size budget of %s", ui.FormatBytes(e.SizeBudget))
+	}
+
 	s = "keep " + s
 
+	if b := e.Buckets.String(); b != "" {
+		s += fmt.Sprintf(" (%s)", b)
+	}
+
 	return s
 }
 
@@ -105,41 +126,10 @@ func (e ExpirePolicy) Empty() bool {
 		return false
 	}
 
-	empty := ExpirePolicy{Tags: e.Tags}
+	empty := ExpirePolicy{Tags: e.Tags, Buckets: e.Buckets}
 	return reflect.DeepEqual(e, empty)
 }
 
-// ymdh returns an integer in the form YYYYMMDDHH.
-func ymdh(d time.Time, _ int) int {
-	return d.Year()*1000000 + int(d.Month())*10000 + d.Day()*100 + d.Hour()
-}
-
-// ymd returns an integer in the form YYYYMMDD.
-func ymd(d time.Time, _ int) int {
-	return d.Year()*10000 + int(d.Month())*100 + d.Day()
-}
-
-// yw returns an integer in the form YYYYWW, where WW is the week number.
-func yw(d time.Time, _ int) int {
-	year, week := d.ISOWeek()
-	return year*100 + week
-}
-
-// ym returns an integer in the form YYYYMM.
-func ym(d time.Time, _ int) int {
-	return d.Year()*100 + int(d.Month())
-}
-
-// y returns the year of d.
-func y(d time.Time, _ int) int {
-	return d.Year()
-}
-
-// always returns a unique number for d.
-func always(_ time.Time, nr int) int {
-	return nr
-}
-
 // findLatestTimestamp returns the time stamp for the latest (newest) snapshot,
 // for use with policies based on time relative to latest.
 func findLatestTimestamp(list Snapshots) time.Time {
@@ -168,140 +158,368 @@ type KeepReason struct {
 	// description text which criteria match, e.g. "daily", "monthly"
 	Matches []string `json:"matches"`
 
+	// the rules which keep the snapshot
+	Rules []KeepRule `json:"rules,omitempty"`
+
 	// the counters after evaluating the current snapshot
 	Counters struct {
-		Last    int `json:"last,omitempty"`
-		Hourly  int `json:"hourly,omitempty"`
-		Daily   int `json:"daily,omitempty"`
-		Weekly  int `json:"weekly,omitempty"`
-		Monthly int `json:"monthly,omitempty"`
-		Yearly  int `json:"yearly,omitempty"`
+		Last      int `json:"last,omitempty"`
+		Hourly    int `json:"hourly,omitempty"`
+		Daily     int `json:"daily,omitempty"`
+		Weekly    int `json:"weekly,omitempty"`
+		Monthly   int `json:"monthly,omitempty"`
+		Quarterly int `json:"quarterly,omitempty"`
+		Yearly    int `json:"yearly,omitempty"`
 	} `json:"counters"`
 }
 
+// KeepRule identifies a rule of a policy which keeps a snapshot.
+type KeepRule struct {
+	// Rule is the name of the rule, e.g. "daily", "within_weekly", "tags" or
+	// "hold".
+	Rule string `json:"rule"`
+
+	// Bucket is the period the snapshot is kept for, e.g. "2023-05-14" for a
+	// daily or "2023-Q2" for a quarterly snapshot. Weeks are identified by
+	// their first day. For the tags rule, Bucket is the matching tag list.
+	Bucket string `json:"bucket,omitempty"`
+
+	// Position is the position of the period among the periods kept by a
+	// counted rule such as "daily", starting with 1 for the newest period.
+	Position int `json:"position,omitempty"`
+}
+
+// RuleDecision explains whether a rule of a policy keeps a snapshot.
+type RuleDecision struct {
+	KeepRule
+	Keep   bool   `json:"keep"`
+	Reason string `json:"reason"`
+}
+
 // ApplyPolicy returns the snapshots from list that are to be kept and removed
 // according to the policy p. list is sorted in the process. reasons contains
 // the reasons to keep each snapshot, it is in the same order as keep.
-func ApplyPolicy(list Snapshots, p ExpirePolicy) (keep, remove Snapshots, reasons []KeepReason) {
+// Snapshots on hold are always kept. p.Buckets configures which snapshot is kept
+// for each hour, day, week, month, quarter or year.
+//
+// If p.SizeBudget is set, sizer must not be nil. The snapshots selected by the
+// other rules (or all snapshots if there are no other rules) are then only
+// kept, starting with the newest one, until the data they reference reaches
+// the size budget. The newest snapshot is always kept. The snapshots removed
+// only because of the size budget are also returned in overBudget.
+func ApplyPolicy(list Snapshots, p ExpirePolicy, sizer SnapshotSizer) (keep, remove Snapshots, reasons []KeepReason, overBudget Snapshots) {
+	res := applyPolicy(list, p, sizer, nil)
+	return res.keep, res.remove, res.reasons, res.overBudget
+}
+
+// ExplainPolicy applies the policy p to list like ApplyPolicy and returns
+// whether sn, which must be contained in list, is kept. decisions lists for
+// every rule of p whether it keeps sn and why.
+func ExplainPolicy(list Snapshots, p ExpirePolicy, sizer SnapshotSizer, sn *Snapshot) (kept bool, decisions []RuleDecision) {
+	res := applyPolicy(list, p, sizer, sn)
+	for _, k := range res.keep {
+		if k == sn {
+			kept = true
+		}
+	}
+	return kept, res.decisions
+}
+
+type policyResult struct {
+	keep, remove Snapshots
+	reasons      []KeepReason
+	overBudget   Snapshots
+
+	// decisions for the snapshot passed to applyPolicy as explain
+	decisions []RuleDecision
+}
+
+// periodRule is a rule of a policy which keeps one snapshot per period.
+type periodRule struct {
+	name   string // name of the rule, e.g. "daily" or "within_daily"
+	reason string // legacy description of the rule
+	noun   string // name of the periods, e.g. "days"
+
+	count  int      // number of periods left to keep for counted rules
+	limit  int      // number of periods to keep for counted rules
+	within Duration // duration to keep periods for within rules
+
+	period   period
+	rep      []int // position of the snapshot kept for the period of each snapshot
+	position int   // number of periods kept so far
+}
+
+func applyPolicy(list Snapshots, p ExpirePolicy, sizer SnapshotSizer, explain *Snapshot) (res policyResult) {
 	sort.Stable(list)
 
+	rules := p
+	rules.SizeBudget = 0
+
+	if rules.Empty() && p.SizeBudget > 0 && len(list) > 0 {
+		var reasons []KeepReason
+		for _, sn := range list {
+			kr := KeepReason{Snapshot: sn}
+			if sn.Hold {
+				kr.Matches = []string{"hold"}
+				kr.Rules = []KeepRule{{Rule: "hold"}}
+				if sn == explain {
+					res.decisions = append(res.decisions, RuleDecision{KeepRule{Rule: "hold"}, true, "snapshot is on hold"})
+				}
+			}
+			reasons = append(reasons, kr)
+		}
+		res.keep, res.remove, res.reasons = applySizeBudget(list, reasons, p.SizeBudget, sizer)
+		// without other rules, all snapshots are removed because of the budget
+		res.overBudget = res.remove
+		res.decisions = append(res.decisions, explainSizeBudget(list, res.reasons, p.SizeBudget, explain)...)
+		return res
+	}
+
 	if p.Empty() {
 		for _, sn := range list {
-			reasons = append(reasons, KeepReason{
+			res.reasons = append(res.reasons, KeepReason{
 				Snapshot: sn,
 				Matches:  []string{"policy is empty"},
+				Rules:    []KeepRule{{Rule: "empty_policy"}},
 			})
 		}
-		return list, remove, reasons
+		if explain != nil {
+			res.decisions = []RuleDecision{{KeepRule{Rule: "empty_policy"}, true, "the policy is empty, all snapshots are kept"}}
+		}
+		res.keep = list
+		return res
 	}
 
 	if len(list) == 0 {
-		return list, nil, nil
+		res.keep = list
+		return res
+	}
+
+	latest := findLatestTimestamp(list)
+	bucketer := newBucketer(p.Buckets)
+	order := "newest"
+	if bucketer.keepOldest {
+		order = "oldest"
+	}
+	cutoff := func(d Duration) time.Time {
+		return latest.AddDate(-d.Years, -d.Months, -d.Days).Add(time.Hour * time.Duration(-d.Hours))
 	}
 
 	// These buckets are for keeping last n snapshots of given type
-	var buckets = [6]struct {
-		Count  int
-		bucker func(d time.Time, nr int) int
-		Last   int
-		reason string
-	}{
-		{p.Last, always, -1, "last snapshot"},
-		{p.Hourly, ymdh, -1, "hourly snapshot"},
-		{p.Daily, ymd, -1, "daily snapshot"},
-		{p.Weekly, yw, -1, "weekly snapshot"},
-		{p.Monthly, ym, -1, "monthly snapshot"},
-		{p.Yearly, y, -1, "yearly snapshot"},
+	var buckets = [7]periodRule{
+		{name: "last", reason: "last snapshot", noun: "snapshots", count: p.Last, period: periodSnapshot},
+		{name: "hourly", reason: "hourly snapshot", noun: "hours", count: p.Hourly, period: periodHour},
+		{name: "daily", reason: "daily snapshot", noun: "days", count: p.Daily, period: periodDay},
+		{name: "weekly", reason: "weekly snapshot", noun: "weeks", count: p.Weekly, period: periodWeek},
+		{name: "monthly", reason: "monthly snapshot", noun: "months", count: p.Monthly, period: periodMonth},
+		{name: "quarterly", reason: "quarterly snapshot", noun: "quarters", count: p.Quarterly, period: periodQuarter},
+		{name: "yearly", reason: "yearly snapshot", noun: "years", count: p.Yearly, period: periodYear},
+	}
+	for i, b := range buckets {
+		buckets[i].limit = b.count
+		if b.count > 0 || b.count == -1 {
+			buckets[i].rep = bucketer.representatives(list, b.period, nil)
+		}
 	}
 
 	// These buckets are for keeping snapshots of given type within duration
-	var bucketsWithin = [5]struct {
-		Within Duration
-		bucker func(d time.Time, nr int) int
-		Last   int
-		reason string
-	}{
-		{p.WithinHourly, ymdh, -1, "hourly within"},
-		{p.WithinDaily, ymd, -1, "daily within"},
-		{p.WithinWeekly, yw, -1, "weekly within"},
-		{p.WithinMonthly, ym, -1, "monthly within"},
-		{p.WithinYearly, y, -1, "yearly within"},
+	var bucketsWithin = [6]periodRule{
+		{name: "within_hourly", reason: "hourly within", noun: "hours", within: p.WithinHourly, period: periodHour},
+		{name: "within_daily", reason: "daily within", noun: "days", within: p.WithinDaily, period: periodDay},
+		{name: "within_weekly", reason: "weekly within", noun: "weeks", within: p.WithinWeekly, period: periodWeek},
+		{name: "within_monthly", reason: "monthly within", noun: "months", within: p.WithinMonthly, period: periodMonth},
+		{name: "within_quarterly", reason: "quarterly within", noun: "quarters", within: p.WithinQuarterly, period: periodQuarter},
+		{name: "within_yearly", reason: "yearly within", noun: "years", within: p.WithinYearly, period: periodYear},
+	}
+	for i, b := range bucketsWithin {
+		if !b.within.Zero() {
+			t := cutoff(b.within)
+			bucketsWithin[i].rep = bucketer.representatives(list, b.period, func(sn *Snapshot) bool {
+				return sn.Time.After(t)
+			})
+		}
 	}
-
-	latest := findLatestTimestamp(list)
 
 	for nr, cur := range list {
 		var keepSnap bool
 		var keepSnapReasons []string
+		var keepRules []KeepRule
+
+		decide := func(rule KeepRule, keep bool, format string, args ...interface{}) {
+			if keep {
+				keepSnap = true
+				keepRules = append(keepRules, rule)
+			}
+			if cur == explain {
+				res.decisions = append(res.decisions, RuleDecision{rule, keep, fmt.Sprintf(format, args...)})
+			}
+		}
+
+		// Snapshots on hold are always kept.
+		if cur.Hold {
+			keepSnapReasons = append(keepSnapReasons, "hold")
+			decide(KeepRule{Rule: "hold"}, true, "snapshot is on hold")
+		}
 
 		// Tags are handled specially as they are not counted.
 		for _, l := range p.Tags {
 			if cur.HasTags(l) {
-				keepSnap = true
 				keepSnapReasons = append(keepSnapReasons, fmt.Sprintf("has tags %v", l))
+				decide(KeepRule{Rule: "tags", Bucket: l.String()}, true, "snapshot has tags %v", l)
+			} else {
+				decide(KeepRule{Rule: "tags", Bucket: l.String()}, false, "snapshot does not have tags %v", l)
 			}
 		}
 
 		// If the timestamp of the snapshot is within the range, then keep it.
 		if !p.Within.Zero() {
-			t := latest.AddDate(-p.Within.Years, -p.Within.Months, -p.Within.Days).Add(time.Hour * time.Duration(-p.Within.Hours))
-			if cur.Time.After(t) {
-				keepSnap = true
+			if cur.Time.After(cutoff(p.Within)) {
 				keepSnapReasons = append(keepSnapReasons, fmt.Sprintf("within %v", p.Within))
+				decide(KeepRule{Rule: "within"}, true, "snapshot was made within %v of the latest snapshot", p.Within)
+			} else {
+				decide(KeepRule{Rule: "within"}, false, "snapshot is older than %v before the latest snapshot", p.Within)
 			}
 		}
 
 		// Now update the other buckets and see if they have some counts left.
-		for i, b := range buckets {
+		for i := range buckets {
+			b := &buckets[i]
+			if b.limit == 0 {
+				continue
+			}
+			rule := KeepRule{Rule: b.name, Bucket: bucketer.key(b.period, cur.Time)}
+
+			switch {
+			case b.rep[nr] != nr:
+				decide(rule, false, "snapshot %v is the %s snapshot of %s", list[b.rep[nr]].ID().Str(), order, rule.Bucket)
 			// -1 means "keep all"
-			if b.Count > 0 || b.Count == -1 {
-				val := b.bucker(cur.Time, nr)
-				if val != b.Last {
-					debug.Log("keep %v %v, bucker %v, val %v\n", cur.Time, cur.id.Str(), i, val)
-					keepSnap = true
-					buckets[i].Last = val
-					if buckets[i].Count > 0 {
-						buckets[i].Count--
-					}
-					keepSnapReasons = append(keepSnapReasons, b.reason)
+			case b.count > 0 || b.count == -1:
+				debug.Log("keep %v %v, bucket %v\n", cur.Time, cur.id.Str(), i)
+				b.position++
+				if b.count > 0 {
+					b.count--
+				}
+				rule.Position = b.position
+				keepSnapReasons = append(keepSnapReasons, b.reason)
+				if b.limit == -1 {
+					decide(rule, true, "%s %d, all %s are kept", b.name, b.position, b.noun)
+				} else {
+					decide(rule, true, "%s %d of %d", b.name, b.position, b.limit)
 				}
+			default:
+				decide(rule, false, "only the last %d %s are kept", b.limit, b.noun)
 			}
 		}
 
-		// If the timestamp is within range, and the snapshot is an hourly/daily/weekly/monthly/yearly snapshot, then keep it
+		// If the timestamp is within range, and the snapshot is an hourly/daily/weekly/monthly/quarterly/yearly snapshot, then keep it
 		for i, b := range bucketsWithin {
-			if !b.Within.Zero() {
-				t := latest.AddDate(-b.Within.Years, -b.Within.Months, -b.Within.Days).Add(time.Hour * time.Duration(-b.Within.Hours))
-
-				if cur.Time.After(t) {
-					val := b.bucker(cur.Time, nr)
-					if val != b.Last {
-						debug.Log("keep %v, time %v, ID %v, bucker %v, val %v %v\n", b.reason, cur.Time, cur.id.Str(), i, val, b.Last)
-						keepSnap = true
-						bucketsWithin[i].Last = val
-						keepSnapReasons = append(keepSnapReasons, fmt.Sprintf("%v %v", b.reason, b.Within))
-					}
-				}
+			if b.rep == nil {
+				continue
+			}
+			rule := KeepRule{Rule: b.name, Bucket: bucketer.key(b.period, cur.Time)}
+
+			switch b.rep[nr] {
+			case -1:
+				decide(rule, false, "snapshot is older than %v before the latest snapshot", b.within)
+			case nr:
+				debug.Log("keep %v, time %v, ID %v, bucket %v\n", b.reason, cur.Time, cur.id.Str(), i)
+				keepSnapReasons = append(keepSnapReasons, fmt.Sprintf("%v %v", b.reason, b.within))
+				decide(rule, true, "snapshot was made within %v of the latest snapshot", b.within)
+			default:
+				decide(rule, false, "snapshot %v is the %s snapshot of %s", list[b.rep[nr]].ID().Str(), order, rule.Bucket)
 			}
 		}
 
 		if keepSnap {
-			keep = append(keep, cur)
+			res.keep = append(res.keep, cur)
 			kr := KeepReason{
 				Snapshot: cur,
 				Matches:  keepSnapReasons,
+				Rules:    keepRules,
 			}
-			kr.Counters.Last = buckets[0].Count
-			kr.Counters.Hourly = buckets[1].Count
-			kr.Counters.Daily = buckets[2].Count
-			kr.Counters.Weekly = buckets[3].Count
-			kr.Counters.Monthly = buckets[4].Count
-			kr.Counters.Yearly = buckets[5].Count
-			reasons = append(reasons, kr)
+			kr.Counters.Last = buckets[0].count
+			kr.Counters.Hourly = buckets[1].count
+			kr.Counters.Daily = buckets[2].count
+			kr.Counters.Weekly = buckets[3].count
+			kr.Counters.Monthly = buckets[4].count
+			kr.Counters.Quarterly = buckets[5].count
+			kr.Counters.Yearly = buckets[6].count
+			res.reasons = append(res.reasons, kr)
 		} else {
-			remove = append(remove, cur)
+			res.remove = append(res.remove, cur)
 		}
 	}
 
-	return keep, remove, reasons
+	if p.SizeBudget > 0 && len(res.keep) > 0 {
+		considered := res.keep
+		res.keep, res.overBudget, res.reasons = applySizeBudget(res.keep, res.reasons, p.SizeBudget, sizer)
+		res.decisions = append(res.decisions, explainSizeBudget(considered, res.reasons, p.SizeBudget, explain)...)
+		res.remove = append(res.remove, res.overBudget...)
+		sort.Stable(res.remove)
+	}
+
+	return res
+}
+
+// applySizeBudget keeps the snapshots from list, which must be sorted newest
+// first, until the data referenced by them reaches budget. The newest snapshot
+// and snapshots on hold are always kept. reasons must be in the same order as
+// list.
+func applySizeBudget(list Snapshots, reasons []KeepReason, budget uint64, sizer SnapshotSizer) (keep, remove Snapshots, keepReasons []KeepReason) {
+	var used uint64
+	exhausted := false
+	for i, sn := range list {
+		if !exhausted {
+			size := sizer.UniqueSize(sn)
+			if i > 0 && used+size > budget {
+				debug.Log("size budget %d exhausted at snapshot %v, used %d", budget, sn.id.Str(), used)
+				exhausted = true
+			} else {
+				used += size
+			}
+		}
+
+		if exhausted && !sn.Hold {
+			remove = append(remove, sn)
+			continue
+		}
+
+		keep = append(keep, sn)
+		kr := reasons[i]
+		if !exhausted {
+			kr.Matches = append(kr.Matches, "within size budget")
+			kr.Rules = append(kr.Rules, KeepRule{Rule: "size_budget"})
+		}
+		keepReasons = append(keepReasons, kr)
+	}
+
+	return keep, remove, keepReasons
+}
+
+// explainSizeBudget returns the decision of the size budget for the snapshot
+// explain if it was considered for the budget, that is if it is contained in
+// list. reasons are the reasons returned by applySizeBudget.
+func explainSizeBudget(list Snapshots, reasons []KeepReason, budget uint64, explain *Snapshot) []RuleDecision {
+	if explain == nil {
+		return nil
+	}
+
+	rule := KeepRule{Rule: "size_budget"}
+	for _, sn := range list {
+		if sn != explain {
+			continue
+		}
+		for _, kr := range reasons {
+			if kr.Snapshot != explain {
+				continue
+			}
+			for _, r := range kr.Rules {
+				if r.Rule == rule.Rule {
+					return []RuleDecision{{rule, true, fmt.Sprintf("snapshot is within the size budget of %s", ui.FormatBytes(budget))}}
+				}
+			}
+		}
+		return []RuleDecision{{rule, false, fmt.Sprintf("snapshot exceeds the size budget of %s", ui.FormatBytes(budget))}}
+	}
+	return nil
 }
@@ -25,11 +25,11 @@ func parseTimeUTC(s string) time.Time {
 // Returns the maximum number of snapshots to be kept according to this policy.
 // If any of the counts is -1 it will return 0.
 func policySum(e *restic.ExpirePolicy) int {
-	if e.Last == -1 || e.Hourly == -1 || e.Daily == -1 || e.Weekly == -1 || e.Monthly == -1 || e.Yearly == -1 {
+	if e.Last == -1 || e.Hourly == -1 || e.Daily == -1 || e.Weekly == -1 || e.Monthly == -1 || e.Quarterly == -1 || e.Yearly == -1 {
 		return 0
 	}
 
-	return e.Last + e.Hourly + e.Daily + e.Weekly + e.Monthly + e.Yearly
+	return e.Last + e.Hourly + e.Daily + e.Weekly + e.Monthly + e.Quarterly + e.Yearly
 }
 
 func TestExpireSnapshotOps(t *testing.T) {
@@ -41,6 +41,8 @@ func TestExpireSnapshotOps(t *testing.T) {
 		{true, 0, &restic.ExpirePolicy{}},
 		{true, 0, &restic.ExpirePolicy{Tags: []restic.TagList{}}},
 		{false, 22, &restic.ExpirePolicy{Daily: 7, Weekly: 2, Monthly: 3, Yearly: 10}},
+		{false, 4, &restic.ExpirePolicy{Quarterly: 4}},
+		{true, 0, &restic.ExpirePolicy{Buckets: restic.BucketOptions{KeepOldest: true, Timezone: "UTC"}}},
 	}
 	for i, d := range data {
 		isEmpty := d.p.Empty()
@@ -249,7 +251,7 @@ func TestApplyPolicy(t *testing.T) {
 	for i, p := range tests {
 		t.Run("", func(t *testing.T) {
 
-			keep, remove, reasons := restic.ApplyPolicy(testExpireSnapshots, p)
+			keep, remove, reasons, _ := restic.ApplyPolicy(testExpireSnapshots, p, nil)
 
 			if len(keep)+len(remove) != len(testExpireSnapshots) {
 				t.Errorf("len(keep)+len(remove) = %d != len(testExpireSnapshots) = %d",
@@ -285,3 +287,266 @@ func TestApplyPolicy(t *testing.T) {
 		})
 	}
 }
+
+// mapSizer is a SnapshotSizer returning a fixed size per snapshot.
+type mapSizer map[time.Time]uint64
+
+func (m mapSizer) UniqueSize(sn *restic.Snapshot) uint64 {
+	return m[sn.Time]
+}
+
+func TestApplyPolicySizeBudget(t *testing.T) {
+	var snapshots = restic.Snapshots{
+		{Time: parseTimeUTC("2014-09-01 10:20:30")},
+		{Time: parseTimeUTC("2014-09-02 10:20:30")},
+		{Time: parseTimeUTC("2014-09-03 10:20:30")},
+		{Time: parseTimeUTC("2014-09-04 10:20:30")},
+		{Time: parseTimeUTC("2014-09-05 10:20:30")},
+	}
+	sizer := mapSizer{
+		parseTimeUTC("2014-09-01 10:20:30"): 10,
+		parseTimeUTC("2014-09-02 10:20:30"): 10,
+		parseTimeUTC("2014-09-03 10:20:30"): 40,
+		parseTimeUTC("2014-09-04 10:20:30"): 20,
+		parseTimeUTC("2014-09-05 10:20:30"): 100,
+	}
+
+	var tests = []struct {
+		p    restic.ExpirePolicy
+		keep []string
+	}{
+		// the newest snapshot is always kept
+		{restic.ExpirePolicy{SizeBudget: 50}, []string{"2014-09-05 10:20:30"}},
+		{restic.ExpirePolicy{SizeBudget: 120}, []string{"2014-09-05 10:20:30", "2014-09-04 10:20:30"}},
+		// the budget is exhausted by the snapshot from 09-03, older snapshots are removed as well
+		{restic.ExpirePolicy{SizeBudget: 150}, []string{"2014-09-05 10:20:30", "2014-09-04 10:20:30"}},
+		{restic.ExpirePolicy{SizeBudget: 1000}, []string{"2014-09-05 10:20:30", "2014-09-04 10:20:30",
+			"2014-09-03 10:20:30", "2014-09-02 10:20:30", "2014-09-01 10:20:30"}},
+		// only snapshots kept by the other rules are considered
+		{restic.ExpirePolicy{Daily: 2, SizeBudget: 1000}, []string{"2014-09-05 10:20:30", "2014-09-04 10:20:30"}},
+		{restic.ExpirePolicy{Daily: 3, SizeBudget: 150}, []string{"2014-09-05 10:20:30", "2014-09-04 10:20:30"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.p.String(), func(t *testing.T) {
+			keep, remove, reasons, overBudget := restic.ApplyPolicy(snapshots, test.p, sizer)
+
+			var kept []string
+			for _, sn := range keep {
+				kept = append(kept, sn.Time.Format("2006-01-02 15:04:05"))
+			}
+			if !cmp.Equal(test.keep, kept) {
+				t.Error(cmp.Diff(test.keep, kept))
+			}
+
+			if len(keep)+len(remove) != len(snapshots) {
+				t.Errorf("len(keep)+len(remove) = %d != len(snapshots) = %d", len(keep)+len(remove), len(snapshots))
+			}
+			if len(keep) != len(reasons) {
+				t.Errorf("got %d keep reasons for %d snapshots to keep, these must be equal", len(reasons), len(keep))
+			}
+
+			for i := 1; i < len(remove); i++ {
+				if remove[i].Time.After(remove[i-1].Time) {
+					t.Errorf("snapshots to remove are not sorted: %v", remove)
+				}
+			}
+
+			// without the budget all snapshots in overBudget would have been kept
+			rules := test.p
+			rules.SizeBudget = 0
+			keepWithoutBudget, _, _, _ := restic.ApplyPolicy(snapshots, rules, nil)
+			if len(keep)+len(overBudget) != len(keepWithoutBudget) {
+				t.Errorf("got %d snapshots over budget, want %d", len(overBudget), len(keepWithoutBudget)-len(keep))
+			}
+		})
+	}
+}
+
+func TestApplyPolicyHold(t *testing.T) {
+	var snapshots = restic.Snapshots{
+		{Time: parseTimeUTC("2014-09-01 10:20:30"), Hold: true},
+		{Time: parseTimeUTC("2014-09-02 10:20:30")},
+		{Time: parseTimeUTC("2014-09-03 10:20:30")},
+	}
+	sizer := mapSizer{
+		parseTimeUTC("2014-09-01 10:20:30"): 10,
+		parseTimeUTC("2014-09-02 10:20:30"): 10,
+		parseTimeUTC("2014-09-03 10:20:30"): 10,
+	}
+
+	for _, p := range []restic.ExpirePolicy{
+		{Last: 1},
+		{Tags: []restic.TagList{{"foo"}}},
+		{SizeBudget: 5},
+		{Last: 2, SizeBudget: 5},
+	} {
+		t.Run(p.String(), func(t *testing.T) {
+			keep, remove, reasons, _ := restic.ApplyPolicy(snapshots, p, sizer)
+
+			if len(keep) != len(reasons) {
+				t.Errorf("got %d keep reasons for %d snapshots to keep, these must be equal", len(reasons), len(keep))
+			}
+			for _, sn := range remove {
+				if sn.Hold {
+					t.Errorf("snapshot on hold %v was removed", sn.Time)
+				}
+			}
+
+			var found bool
+			for _, r := range reasons {
+				if r.Snapshot.Hold {
+					found = true
+					if len(r.Matches) == 0 || r.Matches[0] != "hold" {
+						t.Errorf("wrong reasons for snapshot on hold: %v", r.Matches)
+					}
+				}
+			}
+			if !found {
+				t.Errorf("snapshot on hold was not kept")
+			}
+		})
+	}
+}
+
+func TestApplyPolicyBuckets(t *testing.T) {
+	var snapshots = restic.Snapshots{
+		{Time: parseTimeUTC("2014-01-05 10:00:00")}, // Sunday
+		{Time: parseTimeUTC("2014-01-06 10:00:00")}, // Monday
+		{Time: parseTimeUTC("2014-01-11 10:00:00")}, // Saturday
+		{Time: parseTimeUTC("2014-01-12 23:30:00")}, // Sunday
+		{Time: parseTimeUTC("2014-03-31 10:00:00")},
+		{Time: parseTimeUTC("2014-04-01 10:00:00")},
+		{Time: parseTimeUTC("2014-06-30 10:00:00")},
+	}
+
+	var tests = []struct {
+		p    restic.ExpirePolicy
+		keep []string
+	}{
+		{restic.ExpirePolicy{Quarterly: 2}, []string{"2014-06-30 10:00:00", "2014-03-31 10:00:00"}},
+		{restic.ExpirePolicy{Quarterly: -1, Buckets: restic.BucketOptions{KeepOldest: true}},
+			[]string{"2014-04-01 10:00:00", "2014-01-05 10:00:00"}},
+		{restic.ExpirePolicy{WithinQuarterly: restic.ParseDurationOrPanic("3m")},
+			[]string{"2014-06-30 10:00:00", "2014-03-31 10:00:00"}},
+		// only snapshots within the duration are considered when looking for the oldest one
+		{restic.ExpirePolicy{WithinQuarterly: restic.ParseDurationOrPanic("3m"), Buckets: restic.BucketOptions{KeepOldest: true}},
+			[]string{"2014-04-01 10:00:00", "2014-03-31 10:00:00"}},
+		// weeks start on Monday by default
+		{restic.ExpirePolicy{Weekly: 3}, []string{"2014-06-30 10:00:00", "2014-04-01 10:00:00", "2014-01-12 23:30:00"}},
+		{restic.ExpirePolicy{Weekly: 5, Buckets: restic.BucketOptions{KeepOldest: true}},
+			[]string{"2014-06-30 10:00:00", "2014-03-31 10:00:00", "2014-01-06 10:00:00", "2014-01-05 10:00:00"}},
+		{restic.ExpirePolicy{Weekly: 5, Buckets: restic.BucketOptions{KeepOldest: true, WeekStart: 6}},
+			[]string{"2014-06-30 10:00:00", "2014-03-31 10:00:00", "2014-01-12 23:30:00", "2014-01-05 10:00:00"}},
+		// in UTC+1 the snapshot from Sunday evening was made on Monday
+		{restic.ExpirePolicy{Daily: -1, Buckets: restic.BucketOptions{Timezone: "Europe/Berlin"}},
+			[]string{"2014-06-30 10:00:00", "2014-04-01 10:00:00", "2014-03-31 10:00:00", "2014-01-12 23:30:00",
+				"2014-01-11 10:00:00", "2014-01-06 10:00:00", "2014-01-05 10:00:00"}},
+		{restic.ExpirePolicy{Weekly: 5, Buckets: restic.BucketOptions{Timezone: "Europe/Berlin"}},
+			[]string{"2014-06-30 10:00:00", "2014-04-01 10:00:00", "2014-01-12 23:30:00", "2014-01-11 10:00:00",
+				"2014-01-05 10:00:00"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.p.String(), func(t *testing.T) {
+			keep, remove, reasons, _ := restic.ApplyPolicy(snapshots, test.p, nil)
+
+			var kept []string
+			for _, sn := range keep {
+				kept = append(kept, sn.Time.UTC().Format("2006-01-02 15:04:05"))
+			}
+			if !cmp.Equal(test.keep, kept) {
+				t.Error(cmp.Diff(test.keep, kept))
+			}
+
+			if len(keep)+len(remove) != len(snapshots) {
+				t.Errorf("len(keep)+len(remove) = %d != len(snapshots) = %d", len(keep)+len(remove), len(snapshots))
+			}
+			if len(keep) != len(reasons) {
+				t.Errorf("got %d keep reasons for %d snapshots to keep, these must be equal", len(reasons), len(keep))
+			}
+		})
+	}
+}
+
+func TestWeekday(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		want  string
+		err   bool
+	}{
+		{"monday", "Monday", false},
+		{"Sun", "Sunday", false},
+		{"SATURDAY", "Saturday", false},
+		{"wed", "Wednesday", false},
+		{"someday", "", true},
+	} {
+		var w restic.Weekday
+		err := w.Set(test.input)
+		if test.err {
+			if err == nil {
+				t.Errorf("expected error for %q", test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", test.input, err)
+			continue
+		}
+		if w.String() != test.want {
+			t.Errorf("wrong day for %q, want %v, got %v", test.input, test.want, w)
+		}
+	}
+}
+
+func TestExplainPolicy(t *testing.T) {
+	var snapshots = restic.Snapshots{
+		{Time: parseTimeUTC("2014-09-01 10:20:30")},
+		{Time: parseTimeUTC("2014-09-02 10:20:30")},
+		{Time: parseTimeUTC("2014-09-02 12:20:30"), Tags: []string{"foo"}},
+		{Time: parseTimeUTC("2014-09-03 10:20:30")},
+		{Time: parseTimeUTC("2014-09-04 10:20:30")},
+	}
+	p := restic.ExpirePolicy{Daily: 2, Monthly: 1, Tags: []restic.TagList{{"foo"}}}
+
+	var tests = []struct {
+		sn        *restic.Snapshot
+		kept      bool
+		decisions []restic.RuleDecision
+	}{
+		{snapshots[4], true, []restic.RuleDecision{
+			{KeepRule: restic.KeepRule{Rule: "tags", Bucket: "[foo]"}, Keep: false},
+			{KeepRule: restic.KeepRule{Rule: "daily", Bucket: "2014-09-04", Position: 1}, Keep: true},
+			{KeepRule: restic.KeepRule{Rule: "monthly", Bucket: "2014-09", Position: 1}, Keep: true},
+		}},
+		{snapshots[2], true, []restic.RuleDecision{
+			{KeepRule: restic.KeepRule{Rule: "tags", Bucket: "[foo]"}, Keep: true},
+			{KeepRule: restic.KeepRule{Rule: "daily", Bucket: "2014-09-02"}, Keep: false},
+			{KeepRule: restic.KeepRule{Rule: "monthly", Bucket: "2014-09"}, Keep: false},
+		}},
+		{snapshots[1], false, []restic.RuleDecision{
+			{KeepRule: restic.KeepRule{Rule: "tags", Bucket: "[foo]"}, Keep: false},
+			{KeepRule: restic.KeepRule{Rule: "daily", Bucket: "2014-09-02"}, Keep: false},
+			{KeepRule: restic.KeepRule{Rule: "monthly", Bucket: "2014-09"}, Keep: false},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.sn.Time.String(), func(t *testing.T) {
+			kept, decisions := restic.ExplainPolicy(snapshots, p, nil, test.sn)
+			if kept != test.kept {
+				t.Errorf("wrong result, want kept=%v, got %v", test.kept, kept)
+			}
+
+			cmpOpts := cmpopts.IgnoreFields(restic.RuleDecision{}, "Reason")
+			if !cmp.Equal(test.decisions, decisions, cmpOpts) {
+				t.Error(cmp.Diff(test.decisions, decisions, cmpOpts))
+			}
+			for _, d := range decisions {
+				if d.Reason == "" {
+					t.Errorf("missing reason for rule %v", d.Rule)
+				}
+			}
+		})
+	}
+}
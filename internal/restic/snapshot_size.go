@@ -0,0 +1,82 @@
+package restic
+
+import (
+	"context"
+
+	"github.com/restic/restic/internal/errors"
+)
+
+// SnapshotSizer returns the size of the data referenced by snapshots. It is
+// used by ApplyPolicy to enforce a size budget.
+type SnapshotSizer interface {
+	// UniqueSize returns the size of the data referenced by sn which is not
+	// referenced by any of the snapshots passed to UniqueSize before.
+	UniqueSize(sn *Snapshot) uint64
+}
+
+// RepoSnapshotSizer implements SnapshotSizer by traversing the trees of the
+// snapshots and looking up the blobs in the repository index. The size of a
+// blob is the space used in the repository, that is after compression and
+// encryption.
+type RepoSnapshotSizer struct {
+	ctx  context.Context
+	repo Repository
+	seen *sizedBlobSet
+	err  error
+}
+
+// NewRepoSnapshotSizer returns a new RepoSnapshotSizer for repo.
+func NewRepoSnapshotSizer(ctx context.Context, repo Repository) *RepoSnapshotSizer {
+	return &RepoSnapshotSizer{
+		ctx:  ctx,
+		repo: repo,
+		seen: &sizedBlobSet{BlobSet: NewBlobSet(), idx: repo.Index()},
+	}
+}
+
+// UniqueSize returns the size of the data referenced by sn which is not
+// referenced by any of the snapshots passed to UniqueSize before. Once an
+// error occurred, UniqueSize returns zero and the error is available via Err.
+func (s *RepoSnapshotSizer) UniqueSize(sn *Snapshot) uint64 {
+	if s.err != nil {
+		return 0
+	}
+
+	if sn.Tree == nil {
+		s.err = errors.Errorf("snapshot %v has no tree", sn.ID().Str())
+		return 0
+	}
+
+	before := s.seen.size
+	err := FindUsedBlobs(s.ctx, s.repo, IDs{*sn.Tree}, s.seen, nil)
+	if err != nil {
+		s.err = errors.Errorf("unable to determine size of snapshot %v: %v", sn.ID().Str(), err)
+		return 0
+	}
+
+	return s.seen.size - before
+}
+
+// Err returns the first error which occurred while determining the size of a
+// snapshot.
+func (s *RepoSnapshotSizer) Err() error {
+	return s.err
+}
+
+// sizedBlobSet is a BlobSet which sums up the size of all inserted blobs.
+type sizedBlobSet struct {
+	BlobSet
+	idx  MasterIndex
+	size uint64
+}
+
+func (s *sizedBlobSet) Insert(h BlobHandle) {
+	if s.Has(h) {
+		return
+	}
+	s.BlobSet.Insert(h)
+
+	if blobs := s.idx.Lookup(h); len(blobs) > 0 {
+		s.size += uint64(blobs[0].Length)
+	}
+}
@@ -49,3 +49,38 @@ func testLoadJSONUnpacked(t *testing.T, version uint) {
 	rtest.Equals(t, sn.Hostname, sn2.Hostname)
 	rtest.Equals(t, sn.Username, sn2.Username)
 }
+
+func TestSnapshotLabels(t *testing.T) {
+	sn, _ := restic.NewSnapshot([]string{"/home/foobar"}, nil, "foo", time.Now())
+
+	rtest.Assert(t, sn.HasLabels(nil), "snapshot without labels must match empty labels")
+	rtest.Assert(t, !sn.HasLabels(map[string]string{"env": "prod"}), "snapshot without labels must not match label")
+
+	rtest.Assert(t, sn.SetLabels(map[string]string{"env": "prod", "job": "42"}), "expected labels to be changed")
+	rtest.Assert(t, !sn.SetLabels(map[string]string{"env": "prod"}), "setting an existing label must not change the snapshot")
+	rtest.Assert(t, sn.HasLabels(map[string]string{"env": "prod"}), "snapshot should match label env=prod")
+	rtest.Assert(t, sn.HasLabels(map[string]string{"env": "prod", "job": "42"}), "snapshot should match all labels")
+	rtest.Assert(t, !sn.HasLabels(map[string]string{"env": "prod", "job": "23"}), "snapshot must not match label job=23")
+
+	rtest.Assert(t, sn.SetLabels(map[string]string{"job": "23"}), "expected label value to be changed")
+	rtest.Equals(t, restic.LabelMap{"env": "prod", "job": "23"}, sn.Labels)
+
+	rtest.Assert(t, !sn.RemoveLabels([]string{"missing"}), "removing a missing label must not change the snapshot")
+	rtest.Assert(t, sn.RemoveLabels([]string{"env", "job"}), "expected labels to be removed")
+	rtest.Assert(t, sn.Labels == nil, "expected no labels, got %v", sn.Labels)
+}
+
+func TestRepoSnapshotSizer(t *testing.T) {
+	repo := repository.TestRepository(t)
+	sn1 := restic.TestCreateSnapshot(t, repo, parseTimeUTC("2015-05-05 05:05:05"), 1, 0)
+	sn2 := restic.TestCreateSnapshot(t, repo, parseTimeUTC("2016-06-06 06:06:06"), 1, 0)
+
+	sizer := restic.NewRepoSnapshotSizer(context.TODO(), repo)
+	size1 := sizer.UniqueSize(sn1)
+	rtest.Assert(t, size1 > 0, "expected size of first snapshot to be greater than zero")
+	rtest.Equals(t, uint64(0), sizer.UniqueSize(sn1))
+
+	size2 := sizer.UniqueSize(sn2)
+	rtest.Assert(t, size2 > 0, "expected size of second snapshot to be greater than zero")
+	rtest.OK(t, sizer.Err())
+}
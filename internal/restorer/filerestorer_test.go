@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/restic/restic/internal/crypto"
@@ -316,3 +317,42 @@ func testPartialDownloadError(t *testing.T, part int) {
 	rtest.OK(t, err)
 	verifyRestore(t, r, repo)
 }
+
+func TestFileRestorerUpdateInPlace(t *testing.T) {
+	tempdir := rtest.TempDir(t)
+	content := []TestFile{
+		{
+			name: "file1",
+			blobs: []TestBlob{
+				{"data1-1", "pack1"},
+				{"data1-2", "pack2"},
+				{"data1-3", "pack3"},
+			},
+		}}
+
+	repo := newTestRepo(content)
+
+	// only the second blob differs from the existing file
+	rtest.OK(t, os.WriteFile(filepath.Join(tempdir, "file1"), []byte("data1-1XXXXXXXdata1-3"), 0600))
+	repo.files[0].state = &fileState{
+		blobMatches: []bool{true, false, true},
+		sizeMatches: true,
+	}
+
+	loadedPacks := make(map[string]int)
+	loader := repo.loader
+	repo.loader = func(ctx context.Context, h restic.Handle, length int, offset int64, fn func(rd io.Reader) error) error {
+		packID, err := restic.ParseID(h.Name)
+		rtest.OK(t, err)
+		loadedPacks[repo.packsIDToName[packID]]++
+		return loader(ctx, h, length, offset, fn)
+	}
+
+	r := newFileRestorer(tempdir, repo.loader, repo.key, repo.Lookup, 1, false, nil)
+	r.files = repo.files
+
+	err := r.restoreFiles(context.TODO())
+	rtest.OK(t, err)
+	verifyRestore(t, r, repo)
+	rtest.Equals(t, map[string]int{"pack2": 1}, loadedPacks)
+}
@@ -38,7 +38,7 @@ func newFilesWriter(count int) *filesWriter {
 	}
 }
 
-func (w *filesWriter) writeToFile(path string, blob []byte, offset int64, createSize int64, sparse bool) error {
+func (w *filesWriter) writeToFile(path string, blob []byte, offset int64, createSize int64, fileInfo *fileInfo) error {
 	bucket := &w.buckets[uint(xxhash.Sum64String(path))%uint(len(w.buckets))]
 
 	acquireWriter := func() (*partialFile, error) {
@@ -51,9 +51,11 @@ func (w *filesWriter) writeToFile(path string, blob []byte, offset int64, create
 		}
 
 		var flags int
-		if createSize >= 0 {
+		if createSize >= 0 && fileInfo.state == nil {
 			flags = os.O_CREATE | os.O_TRUNC | os.O_WRONLY
 		} else {
+			// existing files are either partially written already or
+			// updated in place
 			flags = os.O_WRONLY
 		}
 
@@ -62,11 +64,18 @@ func (w *filesWriter) writeToFile(path string, blob []byte, offset int64, create
 			return nil, err
 		}
 
-		wr := &partialFile{File: f, users: 1, sparse: sparse}
+		wr := &partialFile{File: f, users: 1, sparse: fileInfo.sparse}
 		bucket.files[path] = wr
 
-		if createSize >= 0 {
-			if sparse {
+		if createSize >= 0 && fileInfo.state != nil {
+			if !fileInfo.state.sizeMatches {
+				err = f.Truncate(createSize)
+				if err != nil {
+					return nil, err
+				}
+			}
+		} else if createSize >= 0 {
+			if fileInfo.sparse {
 				err = truncateSparse(f, createSize)
 				if err != nil {
 					return nil, err
@@ -14,16 +14,16 @@ func TestFilesWriterBasic(t *testing.T) {
 	f1 := dir + "/f1"
 	f2 := dir + "/f2"
 
-	rtest.OK(t, w.writeToFile(f1, []byte{1}, 0, 2, false))
+	rtest.OK(t, w.writeToFile(f1, []byte{1}, 0, 2, &fileInfo{}))
 	rtest.Equals(t, 0, len(w.buckets[0].files))
 
-	rtest.OK(t, w.writeToFile(f2, []byte{2}, 0, 2, false))
+	rtest.OK(t, w.writeToFile(f2, []byte{2}, 0, 2, &fileInfo{}))
 	rtest.Equals(t, 0, len(w.buckets[0].files))
 
-	rtest.OK(t, w.writeToFile(f1, []byte{1}, 1, -1, false))
+	rtest.OK(t, w.writeToFile(f1, []byte{1}, 1, -1, &fileInfo{}))
 	rtest.Equals(t, 0, len(w.buckets[0].files))
 
-	rtest.OK(t, w.writeToFile(f2, []byte{2}, 1, -1, false))
+	rtest.OK(t, w.writeToFile(f2, []byte{2}, 1, -1, &fileInfo{}))
 	rtest.Equals(t, 0, len(w.buckets[0].files))
 
 	buf, err := os.ReadFile(f1)
@@ -34,3 +34,19 @@ func TestFilesWriterBasic(t *testing.T) {
 	rtest.OK(t, err)
 	rtest.Equals(t, []byte{2, 2}, buf)
 }
+
+func TestFilesWriterUpdateInPlace(t *testing.T) {
+	dir := rtest.TempDir(t)
+	w := newFilesWriter(1)
+
+	f1 := dir + "/f1"
+	rtest.OK(t, os.WriteFile(f1, []byte{1, 2, 3, 4}, 0600))
+
+	file := &fileInfo{state: &fileState{blobMatches: []bool{true, false}}}
+	rtest.OK(t, w.writeToFile(f1, []byte{5}, 1, 2, file))
+	rtest.Equals(t, 0, len(w.buckets[0].files))
+
+	buf, err := os.ReadFile(f1)
+	rtest.OK(t, err)
+	rtest.Equals(t, []byte{1, 5}, buf)
+}
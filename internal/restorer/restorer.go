@@ -2,6 +2,8 @@ package restorer
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sync/atomic"
@@ -17,27 +19,108 @@ import (
 
 // Restorer is used to restore a snapshot to a directory.
 type Restorer struct {
-	repo   restic.Repository
-	sn     *restic.Snapshot
-	sparse bool
+	repo      restic.Repository
+	sn        *restic.Snapshot
+	sparse    bool
+	overwrite OverwriteBehavior
+	delete    bool
+	dryRun    bool
 
 	progress *restoreui.Progress
 
+	// skipped contains the locations of all files which were not
+	// restored because they already exist in the target.
+	skipped map[string]struct{}
+
 	Error        func(location string, err error) error
 	SelectFilter func(item string, dstpath string, node *restic.Node) (selectedForRestore bool, childMayBeSelected bool)
 }
 
 var restorerAbortOnAllErrors = func(location string, err error) error { return err }
 
+// Options configures a Restorer.
+type Options struct {
+	Sparse    bool
+	Progress  *restoreui.Progress
+	Overwrite OverwriteBehavior
+	// Delete removes files from the target which are not contained in the snapshot.
+	Delete bool
+	// DryRun only reports what would be restored, without modifying the
+	// target or downloading any file contents.
+	DryRun bool
+}
+
+// OverwriteBehavior configures how existing files in the target are handled.
+type OverwriteBehavior int
+
+// Constants for different overwrite behavior
+const (
+	// OverwriteAlways replaces existing files with the content from the snapshot.
+	OverwriteAlways OverwriteBehavior = 0
+	// OverwriteIfChanged skips files whose size and modification time match
+	// the snapshot. For all other files only those parts are rewritten
+	// whose content differs from the snapshot, this allows continuing an
+	// interrupted restore.
+	OverwriteIfChanged OverwriteBehavior = 1
+	// OverwriteIfNewer only replaces files which are older than the file
+	// in the snapshot.
+	OverwriteIfNewer OverwriteBehavior = 2
+	// OverwriteNever never modifies existing files.
+	OverwriteNever OverwriteBehavior = 3
+	// OverwriteInvalid is returned for an unknown overwrite behavior.
+	OverwriteInvalid OverwriteBehavior = 4
+)
+
+// Set implements the method needed for pflag command flag parsing.
+func (c *OverwriteBehavior) Set(s string) error {
+	switch s {
+	case "always":
+		*c = OverwriteAlways
+	case "if-changed":
+		*c = OverwriteIfChanged
+	case "if-newer":
+		*c = OverwriteIfNewer
+	case "never":
+		*c = OverwriteNever
+	default:
+		*c = OverwriteInvalid
+		return fmt.Errorf("invalid overwrite behavior %q, must be one of (always|if-changed|if-newer|never)", s)
+	}
+
+	return nil
+}
+
+func (c *OverwriteBehavior) String() string {
+	switch *c {
+	case OverwriteAlways:
+		return "always"
+	case OverwriteIfChanged:
+		return "if-changed"
+	case OverwriteIfNewer:
+		return "if-newer"
+	case OverwriteNever:
+		return "never"
+	default:
+		return "invalid"
+	}
+}
+
+func (c *OverwriteBehavior) Type() string {
+	return "behavior"
+}
+
 // NewRestorer creates a restorer preloaded with the content from the snapshot id.
-func NewRestorer(repo restic.Repository, sn *restic.Snapshot, sparse bool,
-	progress *restoreui.Progress) *Restorer {
+func NewRestorer(repo restic.Repository, sn *restic.Snapshot, opts Options) *Restorer {
 	r := &Restorer{
 		repo:         repo,
-		sparse:       sparse,
+		sparse:       opts.Sparse,
+		overwrite:    opts.Overwrite,
+		delete:       opts.Delete,
+		dryRun:       opts.DryRun,
 		Error:        restorerAbortOnAllErrors,
 		SelectFilter: func(string, string, *restic.Node) (bool, bool) { return true, true },
-		progress:     progress,
+		progress:     opts.Progress,
+		skipped:      make(map[string]struct{}),
 		sn:           sn,
 	}
 
@@ -48,6 +131,9 @@ type treeVisitor struct {
 	enterDir  func(node *restic.Node, target, location string) error
 	visitNode func(node *restic.Node, target, location string) error
 	leaveDir  func(node *restic.Node, target, location string) error
+	// leaveTree is called after all nodes of a tree have been visited, it
+	// receives the names of all nodes contained in the tree.
+	leaveTree func(target, location string, filenames []string) error
 }
 
 // traverseTree traverses a tree from the repo and calls treeVisitor.
@@ -60,7 +146,9 @@ func (res *Restorer) traverseTree(ctx context.Context, target, location string,
 		return hasRestored, res.Error(location, err)
 	}
 
+	filenames := make([]string, 0, len(tree.Nodes))
 	for _, node := range tree.Nodes {
+		filenames = append(filenames, node.Name)
 
 		// ensure that the node name does not contain anything that refers to a
 		// top-level directory.
@@ -157,6 +245,18 @@ func (res *Restorer) traverseTree(ctx context.Context, target, location string,
 		}
 	}
 
+	if visitor.leaveTree != nil {
+		err = visitor.leaveTree(target, location, filenames)
+		switch err {
+		case nil, context.Canceled, context.DeadlineExceeded:
+		default:
+			err = res.Error(location, err)
+		}
+		if err != nil {
+			return hasRestored, err
+		}
+	}
+
 	return hasRestored, nil
 }
 
@@ -244,6 +344,9 @@ func (res *Restorer) RestoreTo(ctx context.Context, dst string) error {
 			if res.progress != nil {
 				res.progress.AddFile(0)
 			}
+			if res.dryRun {
+				return nil
+			}
 			// create dir with default permissions
 			// #leaveDir restores dir metadata after visiting all children
 			return fs.MkdirAll(target, 0700)
@@ -251,20 +354,41 @@ func (res *Restorer) RestoreTo(ctx context.Context, dst string) error {
 
 		visitNode: func(node *restic.Node, target, location string) error {
 			debug.Log("first pass, visitNode: mkdir %q, leaveDir on second pass should restore metadata", location)
-			// create parent dir with default permissions
-			// second pass #leaveDir restores dir metadata after visiting/restoring all children
-			err := fs.MkdirAll(filepath.Dir(target), 0700)
-			if err != nil {
-				return err
+			if !res.dryRun {
+				// create parent dir with default permissions
+				// second pass #leaveDir restores dir metadata after visiting/restoring all children
+				err := fs.MkdirAll(filepath.Dir(target), 0700)
+				if err != nil {
+					return err
+				}
 			}
 
 			if node.Type != "file" {
+				action := restoreui.ActionCreated
+				if _, err := fs.Lstat(target); err == nil {
+					action = restoreui.ActionOverwritten
+				}
+				res.reportAction(action, location, 0)
 				if res.progress != nil {
 					res.progress.AddFile(0)
 				}
 				return nil
 			}
 
+			action, state, err := res.checkExistingFile(target, node)
+			if err != nil {
+				return err
+			}
+			res.reportAction(action, location, node.Size)
+			if action == restoreui.ActionSkipped {
+				debug.Log("first pass, visitNode: skipping existing file %q", location)
+				res.skipped[location] = struct{}{}
+				if res.progress != nil {
+					res.progress.AddSkippedFile(node.Size)
+				}
+				return nil
+			}
+
 			if node.Size == 0 {
 				if res.progress != nil {
 					res.progress.AddFile(node.Size)
@@ -287,15 +411,54 @@ func (res *Restorer) RestoreTo(ctx context.Context, dst string) error {
 				res.progress.AddFile(node.Size)
 			}
 
-			filerestorer.addFile(location, node.Content, int64(node.Size))
+			if res.dryRun {
+				return nil
+			}
+
+			if state != nil && state.matchesAll() {
+				// the file content is already correct, the metadata is
+				// restored in the second pass
+				if !state.sizeMatches {
+					debug.Log("first pass, visitNode: truncating %q to %d", location, node.Size)
+					err = os.Truncate(target, int64(node.Size))
+					if err != nil {
+						return err
+					}
+				}
+				if res.progress != nil {
+					res.progress.AddProgress(location, node.Size, node.Size)
+				}
+				return nil
+			}
+
+			if state != nil && res.progress != nil {
+				// report the already matching parts of the file as restored
+				res.progress.AddProgress(location, state.matchingBytes, node.Size)
+			}
+
+			filerestorer.addFile(location, node.Content, int64(node.Size), state)
 
 			return nil
 		},
+
+		leaveTree: func(target, location string, filenames []string) error {
+			if !res.delete {
+				return nil
+			}
+			debug.Log("first pass, leaveTree: remove unexpected files in %q", location)
+			_, err := res.removeUnexpectedFiles(target, location, filenames)
+			return err
+		},
 	})
 	if err != nil {
 		return err
 	}
 
+	if res.dryRun {
+		// neither download file contents nor restore any metadata
+		return nil
+	}
+
 	err = filerestorer.restoreFiles(ctx)
 	if err != nil {
 		return err
@@ -307,6 +470,10 @@ func (res *Restorer) RestoreTo(ctx context.Context, dst string) error {
 	_, err = res.traverseTree(ctx, dst, string(filepath.Separator), *res.sn.Tree, treeVisitor{
 		visitNode: func(node *restic.Node, target, location string) error {
 			debug.Log("second pass, visitNode: restore node %q", location)
+			if _, ok := res.skipped[location]; ok {
+				return nil
+			}
+
 			if node.Type != "file" {
 				return res.restoreNodeTo(ctx, node, target, location)
 			}
@@ -336,6 +503,241 @@ func (res *Restorer) RestoreTo(ctx context.Context, dst string) error {
 	return err
 }
 
+// fileState describes which parts of an existing file in the target already
+// contain the expected content.
+type fileState struct {
+	blobMatches   []bool
+	sizeMatches   bool
+	matchingBytes uint64
+}
+
+// hasMatchingBlob returns whether the blob at index i of the file content is
+// already present in the existing file.
+func (s *fileState) hasMatchingBlob(i int) bool {
+	if s == nil || i >= len(s.blobMatches) {
+		return false
+	}
+	return s.blobMatches[i]
+}
+
+// matchesAll returns whether all blobs of the file content are already present
+// in the existing file.
+func (s *fileState) matchesAll() bool {
+	for _, match := range s.blobMatches {
+		if !match {
+			return false
+		}
+	}
+	return true
+}
+
+// checkExistingFile decides what to do with the file target which should be
+// restored from node, according to the configured overwrite behavior. For
+// restoreui.ActionUpdated and restoreui.ActionUnchanged the returned state
+// describes which parts of the existing file must be rewritten.
+func (res *Restorer) checkExistingFile(target string, node *restic.Node) (restoreui.ItemAction, *fileState, error) {
+	fi, err := fs.Lstat(target)
+	if os.IsNotExist(err) {
+		return restoreui.ActionCreated, nil, nil
+	}
+	if err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+
+	switch res.overwrite {
+	case OverwriteAlways:
+		return restoreui.ActionOverwritten, nil, nil
+	case OverwriteNever:
+		return restoreui.ActionSkipped, nil, nil
+	case OverwriteIfNewer:
+		// skip the file unless the version in the snapshot is newer
+		if !node.ModTime.After(fi.ModTime()) {
+			return restoreui.ActionSkipped, nil, nil
+		}
+		return restoreui.ActionOverwritten, nil, nil
+	case OverwriteIfChanged:
+		if !fi.Mode().IsRegular() {
+			return restoreui.ActionOverwritten, nil, nil
+		}
+		if fi.Size() == int64(node.Size) && fi.ModTime().Equal(node.ModTime) {
+			return restoreui.ActionSkipped, nil, nil
+		}
+
+		// Verify the content of the existing file, it may already be
+		// (partially) restored by an interrupted restore run.
+		state, err := res.compareExistingFile(target, node)
+		if err != nil {
+			// the file is rewritten from scratch if its content cannot be checked
+			debug.Log("unable to compare existing file %v: %v", target, err)
+			return restoreui.ActionOverwritten, nil, nil
+		}
+		switch {
+		case state.matchesAll() && state.sizeMatches:
+			return restoreui.ActionUnchanged, state, nil
+		case state.matchingBytes == 0 && len(node.Content) > 0:
+			// nothing to reuse, rewrite the file from scratch
+			return restoreui.ActionOverwritten, nil, nil
+		default:
+			return restoreui.ActionUpdated, state, nil
+		}
+	default:
+		return "", nil, errors.Errorf("unknown overwrite behavior %d", res.overwrite)
+	}
+}
+
+// compareExistingFile checks which blobs of node.Content are already present
+// at the expected offset in the file target.
+func (res *Restorer) compareExistingFile(target string, node *restic.Node) (*fileState, error) {
+	f, err := os.Open(target)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	state := &fileState{
+		blobMatches: make([]bool, len(node.Content)),
+		sizeMatches: fi.Size() == int64(node.Size),
+	}
+
+	var buf []byte
+	var offset int64
+	for i, blobID := range node.Content {
+		var match bool
+		var length uint
+		match, length, buf, err = res.checkBlobAt(f, blobID, offset, buf)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// the existing file is too short
+			match, err = false, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		state.blobMatches[i] = match
+		if match {
+			state.matchingBytes += uint64(length)
+		}
+		offset += int64(length)
+	}
+
+	return state, nil
+}
+
+func (res *Restorer) reportAction(action restoreui.ItemAction, location string, size uint64) {
+	if res.progress != nil {
+		res.progress.ReportAction(action, location, size)
+	}
+}
+
+// removeUnexpectedFiles removes all entries of the directory target that are
+// not contained in expectedFilenames and that are selected by SelectFilter.
+// Directories are only removed if all of their content could be removed. It
+// returns whether all entries of the directory were removed.
+func (res *Restorer) removeUnexpectedFiles(target, location string, expectedFilenames []string) (allRemoved bool, err error) {
+	entries, err := readdirnames(target)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	keep := make(map[string]struct{}, len(expectedFilenames))
+	for _, name := range expectedFilenames {
+		keep[name] = struct{}{}
+	}
+
+	allRemoved = true
+	for _, name := range entries {
+		if _, ok := keep[name]; ok {
+			allRemoved = false
+			continue
+		}
+
+		nodeTarget := filepath.Join(target, name)
+		nodeLocation := filepath.Join(location, name)
+
+		fi, err := fs.Lstat(nodeTarget)
+		if err != nil {
+			allRemoved = false
+			if err = res.Error(nodeLocation, err); err != nil {
+				return false, err
+			}
+			continue
+		}
+
+		// the filters only look at the node type
+		node := &restic.Node{Name: name, Type: "file"}
+		if fi.IsDir() {
+			node.Type = "dir"
+		}
+
+		selectedForRestore, childMayBeSelected := res.SelectFilter(nodeLocation, nodeTarget, node)
+		debug.Log("SelectFilter returned %v %v for unexpected %q", selectedForRestore, childMayBeSelected, nodeLocation)
+
+		removable := selectedForRestore
+		if node.Type == "dir" && (selectedForRestore || childMayBeSelected) {
+			// the filter may exclude some of the directory content
+			childrenRemoved, err := res.removeUnexpectedFiles(nodeTarget, nodeLocation, nil)
+			if err != nil {
+				return false, err
+			}
+			removable = removable && childrenRemoved
+		}
+
+		if !removable {
+			allRemoved = false
+			continue
+		}
+
+		var size uint64
+		if fi.Mode().IsRegular() {
+			size = uint64(fi.Size())
+		}
+
+		if res.dryRun {
+			res.reportAction(restoreui.ActionDeleted, nodeLocation, size)
+			continue
+		}
+
+		debug.Log("removing unexpected %q", nodeLocation)
+		err = fs.Remove(nodeTarget)
+		if err != nil {
+			allRemoved = false
+			if err = res.Error(nodeLocation, errors.WithStack(err)); err != nil {
+				return false, err
+			}
+			continue
+		}
+		res.reportAction(restoreui.ActionDeleted, nodeLocation, size)
+	}
+
+	return allRemoved, nil
+}
+
+// readdirnames returns the names of all entries in the directory dir.
+func readdirnames(dir string) ([]string, error) {
+	f, err := fs.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := f.Readdirnames(-1)
+	if err != nil {
+		_ = f.Close()
+		return nil, errors.WithStack(err)
+	}
+
+	return entries, f.Close()
+}
+
 // Snapshot returns the snapshot this restorer is configured to use.
 func (res *Restorer) Snapshot() *restic.Snapshot {
 	return res.sn
@@ -370,6 +772,10 @@ func (res *Restorer) VerifyFiles(ctx context.Context, dst string) (int, error) {
 				if node.Type != "file" {
 					return nil
 				}
+				if _, ok := res.skipped[location]; ok {
+					// existing files which were not touched may differ from the snapshot
+					return nil
+				}
 				select {
 				case <-ctx.Done():
 					return ctx.Err()
@@ -426,21 +832,13 @@ func (res *Restorer) verifyFile(target string, node *restic.Node, buf []byte) ([
 
 	var offset int64
 	for _, blobID := range node.Content {
-		length, found := res.repo.LookupBlobSize(blobID, restic.DataBlob)
-		if !found {
-			return buf, errors.Errorf("Unable to fetch blob %s", blobID)
-		}
-
-		if length > uint(cap(buf)) {
-			buf = make([]byte, 2*length)
-		}
-		buf = buf[:length]
-
-		_, err = f.ReadAt(buf, offset)
+		var match bool
+		var length uint
+		match, length, buf, err = res.checkBlobAt(f, blobID, offset, buf)
 		if err != nil {
 			return buf, err
 		}
-		if !blobID.Equal(restic.Hash(buf)) {
+		if !match {
 			return buf, errors.Errorf(
 				"Unexpected content in %s, starting at offset %d",
 				target, offset)
@@ -450,3 +848,25 @@ func (res *Restorer) verifyFile(target string, node *restic.Node, buf []byte) ([
 
 	return buf, nil
 }
+
+// checkBlobAt reads the blob blobID from f at offset and reports whether the
+// data matches and the length of the blob.
+//
+// buf is scratch space, it is returned for reuse.
+func (res *Restorer) checkBlobAt(f *os.File, blobID restic.ID, offset int64, buf []byte) (bool, uint, []byte, error) {
+	length, found := res.repo.LookupBlobSize(blobID, restic.DataBlob)
+	if !found {
+		return false, 0, buf, errors.Errorf("Unable to fetch blob %s", blobID)
+	}
+
+	if length > uint(cap(buf)) {
+		buf = make([]byte, 2*length)
+	}
+	buf = buf[:length]
+
+	_, err := f.ReadAt(buf, offset)
+	if err != nil {
+		return false, length, buf, err
+	}
+	return blobID.Equal(restic.Hash(buf)), length, buf, nil
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -17,6 +18,7 @@ import (
 	"github.com/restic/restic/internal/repository"
 	"github.com/restic/restic/internal/restic"
 	rtest "github.com/restic/restic/internal/test"
+	restoreui "github.com/restic/restic/internal/ui/restore"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -40,6 +42,26 @@ type Dir struct {
 	ModTime time.Time
 }
 
+type printerMock struct {
+	s       restoreui.State
+	actions map[string]restoreui.ItemAction
+}
+
+func (p *printerMock) Update(_ restoreui.State, _ time.Duration) {
+}
+func (p *printerMock) Error(_ string, _ error) error {
+	return nil
+}
+func (p *printerMock) ReportAction(action restoreui.ItemAction, item string, _ uint64) {
+	if p.actions == nil {
+		p.actions = make(map[string]restoreui.ItemAction)
+	}
+	p.actions[filepath.ToSlash(item)] = action
+}
+func (p *printerMock) Finish(s restoreui.State, _ time.Duration) {
+	p.s = s
+}
+
 func saveFile(t testing.TB, repo restic.Repository, node File) restic.ID {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -325,7 +347,7 @@ func TestRestorer(t *testing.T) {
 			sn, id := saveSnapshot(t, repo, test.Snapshot)
 			t.Logf("snapshot saved as %v", id.Str())
 
-			res := NewRestorer(repo, sn, false, nil)
+			res := NewRestorer(repo, sn, Options{})
 
 			tempdir := rtest.TempDir(t)
 			// make sure we're creating a new subdir of the tempdir
@@ -442,7 +464,7 @@ func TestRestorerRelative(t *testing.T) {
 			sn, id := saveSnapshot(t, repo, test.Snapshot)
 			t.Logf("snapshot saved as %v", id.Str())
 
-			res := NewRestorer(repo, sn, false, nil)
+			res := NewRestorer(repo, sn, Options{})
 
 			tempdir := rtest.TempDir(t)
 			cleanup := rtest.Chdir(t, tempdir)
@@ -671,7 +693,7 @@ func TestRestorerTraverseTree(t *testing.T) {
 			repo := repository.TestRepository(t)
 			sn, _ := saveSnapshot(t, repo, test.Snapshot)
 
-			res := NewRestorer(repo, sn, false, nil)
+			res := NewRestorer(repo, sn, Options{})
 
 			res.SelectFilter = test.Select
 
@@ -747,7 +769,7 @@ func TestRestorerConsistentTimestampsAndPermissions(t *testing.T) {
 		},
 	})
 
-	res := NewRestorer(repo, sn, false, nil)
+	res := NewRestorer(repo, sn, Options{})
 
 	res.SelectFilter = func(item string, dstpath string, node *restic.Node) (selectedForRestore bool, childMayBeSelected bool) {
 		switch filepath.ToSlash(item) {
@@ -802,7 +824,7 @@ func TestVerifyCancel(t *testing.T) {
 	repo := repository.TestRepository(t)
 	sn, _ := saveSnapshot(t, repo, snapshot)
 
-	res := NewRestorer(repo, sn, false, nil)
+	res := NewRestorer(repo, sn, Options{})
 
 	tempdir := rtest.TempDir(t)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -844,7 +866,7 @@ func TestRestorerSparseFiles(t *testing.T) {
 		archiver.SnapshotOptions{})
 	rtest.OK(t, err)
 
-	res := NewRestorer(repo, sn, true, nil)
+	res := NewRestorer(repo, sn, Options{Sparse: true})
 
 	tempdir := rtest.TempDir(t)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -875,3 +897,337 @@ func TestRestorerSparseFiles(t *testing.T) {
 	t.Logf("wrote %d zeros as %d blocks, %.1f%% sparse",
 		len(zeros), blocks, 100*sparsity)
 }
+
+func saveSnapshotsAndOverwrite(t *testing.T, baseSnapshot Snapshot, overwriteSnapshot Snapshot, overwriteOptions Options) string {
+	repo := repository.TestRepository(t)
+	tempdir := filepath.Join(rtest.TempDir(t), "target")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// base snapshot
+	sn, id := saveSnapshot(t, repo, baseSnapshot)
+	t.Logf("base snapshot saved as %v", id.Str())
+
+	res := NewRestorer(repo, sn, Options{})
+	rtest.OK(t, res.RestoreTo(ctx, tempdir))
+
+	// overwrite snapshot
+	sn, id = saveSnapshot(t, repo, overwriteSnapshot)
+	t.Logf("overwrite snapshot saved as %v", id.Str())
+	res = NewRestorer(repo, sn, overwriteOptions)
+	rtest.OK(t, res.RestoreTo(ctx, tempdir))
+
+	_, err := res.VerifyFiles(ctx, tempdir)
+	rtest.OK(t, err)
+
+	return tempdir
+}
+
+func TestRestorerOverwriteBehavior(t *testing.T) {
+	baseTime := time.Now()
+	baseSnapshot := Snapshot{
+		Nodes: map[string]Node{
+			"foo": File{Data: "content: foo\n", ModTime: baseTime},
+			"dirtest": Dir{
+				Nodes: map[string]Node{
+					"file": File{Data: "content: file\n", ModTime: baseTime},
+				},
+				ModTime: baseTime,
+			},
+		},
+	}
+	overwriteSnapshot := Snapshot{
+		Nodes: map[string]Node{
+			"foo": File{Data: "content: new\n", ModTime: baseTime.Add(time.Second)},
+			"dirtest": Dir{
+				Nodes: map[string]Node{
+					"file": File{Data: "content: file2\n", ModTime: baseTime.Add(-time.Second)},
+				},
+			},
+		},
+	}
+
+	var tests = []struct {
+		Overwrite OverwriteBehavior
+		Files     map[string]string
+	}{
+		{
+			Overwrite: OverwriteAlways,
+			Files: map[string]string{
+				"foo":          "content: new\n",
+				"dirtest/file": "content: file2\n",
+			},
+		},
+		{
+			Overwrite: OverwriteIfChanged,
+			Files: map[string]string{
+				"foo":          "content: new\n",
+				"dirtest/file": "content: file2\n",
+			},
+		},
+		{
+			Overwrite: OverwriteIfNewer,
+			Files: map[string]string{
+				"foo":          "content: new\n",
+				"dirtest/file": "content: file\n",
+			},
+		},
+		{
+			Overwrite: OverwriteNever,
+			Files: map[string]string{
+				"foo":          "content: foo\n",
+				"dirtest/file": "content: file\n",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			tempdir := saveSnapshotsAndOverwrite(t, baseSnapshot, overwriteSnapshot, Options{Overwrite: test.Overwrite})
+
+			for filename, content := range test.Files {
+				data, err := os.ReadFile(filepath.Join(tempdir, filepath.FromSlash(filename)))
+				if err != nil {
+					t.Errorf("unable to read file %v: %v", filename, err)
+					continue
+				}
+
+				if !bytes.Equal(data, []byte(content)) {
+					t.Errorf("file %v has wrong content: want %q, got %q", filename, content, data)
+				}
+			}
+		})
+	}
+}
+
+func TestRestorerOverwriteIfChanged(t *testing.T) {
+	baseTime := time.Now()
+	snapshot := Snapshot{
+		Nodes: map[string]Node{
+			"unchanged": File{Data: "content: unchanged\n", ModTime: baseTime},
+			"touched":   File{Data: "content: touched\n", ModTime: baseTime},
+			"modified":  File{Data: "content: modified\n", ModTime: baseTime},
+			"truncated": File{Data: "content: truncated\n", ModTime: baseTime},
+		},
+	}
+
+	repo := repository.TestRepository(t)
+	tempdir := rtest.TempDir(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sn, _ := saveSnapshot(t, repo, snapshot)
+	rtest.OK(t, NewRestorer(repo, sn, Options{}).RestoreTo(ctx, tempdir))
+
+	// only change the modification time
+	rtest.OK(t, os.Chtimes(filepath.Join(tempdir, "touched"), baseTime, baseTime.Add(time.Hour)))
+	// same size, different content
+	rtest.OK(t, os.WriteFile(filepath.Join(tempdir, "modified"), []byte("content: MODIFIED\n"), 0644))
+	// longer file with matching prefix
+	rtest.OK(t, os.WriteFile(filepath.Join(tempdir, "truncated"), []byte("content: truncated\nsuffix\n"), 0644))
+
+	mock := &printerMock{}
+	progress := restoreui.NewProgress(mock, 0)
+	res := NewRestorer(repo, sn, Options{Overwrite: OverwriteIfChanged, Progress: progress})
+	rtest.OK(t, res.RestoreTo(ctx, tempdir))
+	progress.Finish()
+
+	nverified, err := res.VerifyFiles(ctx, tempdir)
+	rtest.OK(t, err)
+	rtest.Equals(t, 3, nverified)
+	rtest.Equals(t, uint64(1), mock.s.FilesSkipped)
+	rtest.Equals(t, uint64(3), mock.s.FilesFinished)
+
+	for _, name := range []string{"touched", "modified", "truncated"} {
+		fi, err := os.Stat(filepath.Join(tempdir, name))
+		rtest.OK(t, err)
+		rtest.Assert(t, fi.ModTime().Equal(baseTime), "unexpected modification time %v for %v", fi.ModTime(), name)
+	}
+}
+
+func TestRestorerDelete(t *testing.T) {
+	snapshot := Snapshot{
+		Nodes: map[string]Node{
+			"foo": File{Data: "content: foo\n"},
+			"dirtest": Dir{
+				Nodes: map[string]Node{
+					"file": File{Data: "content: file\n"},
+				},
+			},
+		},
+	}
+
+	var tests = []struct {
+		Select   func(item string, dstpath string, node *restic.Node) (selectedForRestore bool, childMayBeSelected bool)
+		Existing []string
+	}{
+		{
+			Existing: []string{"foo", "dirtest", "dirtest/file"},
+		},
+		{
+			// keep files excluded from the restore
+			Select: func(item string, dstpath string, node *restic.Node) (selectedForRestore bool, childMayBeSelected bool) {
+				selectedForRestore = filepath.Ext(item) != ".keep"
+				return selectedForRestore, selectedForRestore && node.Type == "dir"
+			},
+			Existing: []string{"foo", "dirtest", "dirtest/file", "extra.keep", "extradir", "extradir/sub", "extradir/sub/file.keep"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run("", func(t *testing.T) {
+			repo := repository.TestRepository(t)
+			sn, _ := saveSnapshot(t, repo, snapshot)
+			tempdir := rtest.TempDir(t)
+
+			for _, dir := range []string{"dirtest", "extradir/sub"} {
+				rtest.OK(t, os.MkdirAll(filepath.Join(tempdir, filepath.FromSlash(dir)), 0700))
+			}
+			for _, name := range []string{"extra", "extra.keep", "dirtest/extra", "extradir/sub/file", "extradir/sub/file.keep"} {
+				rtest.OK(t, os.WriteFile(filepath.Join(tempdir, filepath.FromSlash(name)), []byte(name), 0600))
+			}
+
+			res := NewRestorer(repo, sn, Options{Delete: true})
+			if test.Select != nil {
+				res.SelectFilter = test.Select
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			rtest.OK(t, res.RestoreTo(ctx, tempdir))
+
+			var existing []string
+			rtest.OK(t, filepath.Walk(tempdir, func(path string, _ os.FileInfo, err error) error {
+				if err != nil || path == tempdir {
+					return err
+				}
+				rel, err := filepath.Rel(tempdir, path)
+				existing = append(existing, filepath.ToSlash(rel))
+				return err
+			}))
+			sort.Strings(existing)
+			sort.Strings(test.Existing)
+			rtest.Equals(t, test.Existing, existing)
+		})
+	}
+}
+
+func TestRestorerDryRun(t *testing.T) {
+	baseTime := time.Now()
+	snapshot := Snapshot{
+		Nodes: map[string]Node{
+			"foo":      File{Data: "content: foo\n", ModTime: baseTime},
+			"existing": File{Data: "content: existing\n", ModTime: baseTime},
+			"dirtest": Dir{
+				Nodes: map[string]Node{
+					"file": File{Data: "content: file\n", ModTime: baseTime},
+				},
+			},
+		},
+	}
+
+	repo := repository.TestRepository(t)
+	sn, _ := saveSnapshot(t, repo, snapshot)
+	tempdir := rtest.TempDir(t)
+	rtest.OK(t, os.WriteFile(filepath.Join(tempdir, "existing"), []byte("old content"), 0600))
+	rtest.OK(t, os.WriteFile(filepath.Join(tempdir, "extra"), []byte("extra"), 0600))
+
+	mock := &printerMock{}
+	progress := restoreui.NewProgress(mock, 0)
+	res := NewRestorer(repo, sn, Options{DryRun: true, Delete: true, Progress: progress})
+
+	// the dry run must not load any file content
+	res.repo = &repoNoPackLoads{Repository: repo, t: t}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rtest.OK(t, res.RestoreTo(ctx, tempdir))
+	progress.Finish()
+
+	rtest.Equals(t, map[string]restoreui.ItemAction{
+		"/foo":          restoreui.ActionCreated,
+		"/existing":     restoreui.ActionOverwritten,
+		"/dirtest/file": restoreui.ActionCreated,
+		"/extra":        restoreui.ActionDeleted,
+	}, mock.actions)
+	rtest.Equals(t, uint64(1), mock.s.FilesDeleted)
+	rtest.Equals(t, uint64(0), mock.s.AllBytesWritten)
+
+	// nothing must have been modified
+	entries, err := readdirnames(tempdir)
+	rtest.OK(t, err)
+	sort.Strings(entries)
+	rtest.Equals(t, []string{"existing", "extra"}, entries)
+	data, err := os.ReadFile(filepath.Join(tempdir, "existing"))
+	rtest.OK(t, err)
+	rtest.Equals(t, "old content", string(data))
+}
+
+type repoNoPackLoads struct {
+	restic.Repository
+	t testing.TB
+}
+
+func (r *repoNoPackLoads) Backend() restic.Backend {
+	return &backendNoPackLoads{Backend: r.Repository.Backend(), t: r.t}
+}
+
+type backendNoPackLoads struct {
+	restic.Backend
+	t testing.TB
+}
+
+func (b *backendNoPackLoads) Load(ctx context.Context, h restic.Handle, length int, offset int64, fn func(rd io.Reader) error) error {
+	if h.Type == restic.PackFile {
+		b.t.Errorf("unexpected load of pack %v", h)
+	}
+	return b.Backend.Load(ctx, h, length, offset, fn)
+}
+
+func TestRestorerResume(t *testing.T) {
+	snapshot := Snapshot{
+		Nodes: map[string]Node{
+			"foo": File{Data: "content: foo\n"},
+			"dirtest": Dir{
+				Nodes: map[string]Node{
+					"file":  File{Data: "content: file\n"},
+					"empty": File{Data: ""},
+				},
+			},
+		},
+	}
+
+	repo := repository.TestRepository(t)
+	sn, _ := saveSnapshot(t, repo, snapshot)
+	tempdir := rtest.TempDir(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rtest.OK(t, NewRestorer(repo, sn, Options{}).RestoreTo(ctx, tempdir))
+
+	// an interrupted restore has not yet restored the modification time
+	now := time.Now()
+	for _, name := range []string{"foo", filepath.Join("dirtest", "file")} {
+		rtest.OK(t, os.Chtimes(filepath.Join(tempdir, name), now, now))
+	}
+
+	// a second restore must not download already restored files again
+	mock := &printerMock{}
+	progress := restoreui.NewProgress(mock, 0)
+	res := NewRestorer(repo, sn, Options{Progress: progress, Overwrite: OverwriteIfChanged})
+	res.repo = &repoNoPackLoads{Repository: repo, t: t}
+	rtest.OK(t, res.RestoreTo(ctx, tempdir))
+	progress.Finish()
+
+	rtest.Equals(t, restoreui.ActionUnchanged, mock.actions["/foo"])
+	rtest.Equals(t, restoreui.ActionUnchanged, mock.actions["/dirtest/file"])
+	rtest.Equals(t, mock.s.AllBytesTotal, mock.s.AllBytesWritten)
+	rtest.Equals(t, mock.s.FilesTotal, mock.s.FilesFinished)
+
+	// simulate an interrupted restore which did not finish writing a file
+	rtest.OK(t, os.WriteFile(filepath.Join(tempdir, "foo"), make([]byte, len("content: foo\n")), 0644))
+	res = NewRestorer(repo, sn, Options{Overwrite: OverwriteIfChanged})
+	rtest.OK(t, res.RestoreTo(ctx, tempdir))
+	_, err := res.VerifyFiles(ctx, tempdir)
+	rtest.OK(t, err)
+}
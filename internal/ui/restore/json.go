@@ -0,0 +1,152 @@
+package restore
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+type jsonPrinter struct {
+	terminal  term
+	verbosity uint
+	dryRun    bool
+}
+
+// NewJSONProgressPrinter returns a printer which reports the progress, errors,
+// the actions for each item and the final summary as JSON lines.
+func NewJSONProgressPrinter(terminal term, verbosity uint, dryRun bool) ProgressPrinter {
+	return &jsonPrinter{
+		terminal:  terminal,
+		verbosity: verbosity,
+		dryRun:    dryRun,
+	}
+}
+
+func toJSONString(status interface{}) string {
+	buf := new(bytes.Buffer)
+	err := json.NewEncoder(buf).Encode(status)
+	if err != nil {
+		panic(err)
+	}
+	return buf.String()
+}
+
+func (t *jsonPrinter) print(status interface{}) {
+	t.terminal.Print(toJSONString(status))
+}
+
+func (t *jsonPrinter) error(status interface{}) {
+	t.terminal.Error(toJSONString(status))
+}
+
+func (t *jsonPrinter) Update(p State, duration time.Duration) {
+	if t.dryRun {
+		// nothing is written during a dry run
+		return
+	}
+
+	status := statusUpdate{
+		MessageType:    "status",
+		SecondsElapsed: uint64(duration / time.Second),
+		TotalFiles:     p.FilesTotal,
+		FilesRestored:  p.FilesFinished,
+		FilesSkipped:   p.FilesSkipped,
+		TotalBytes:     p.AllBytesTotal,
+		BytesRestored:  p.AllBytesWritten,
+		BytesSkipped:   p.AllBytesSkipped,
+	}
+
+	if p.AllBytesTotal > 0 {
+		status.PercentDone = float64(p.AllBytesWritten) / float64(p.AllBytesTotal)
+	}
+	if p.AllBytesWritten > 0 && p.AllBytesTotal > p.AllBytesWritten {
+		secs := float64(duration / time.Second)
+		todo := float64(p.AllBytesTotal - p.AllBytesWritten)
+		status.SecondsRemaining = uint64(secs / float64(p.AllBytesWritten) * todo)
+	}
+
+	t.print(status)
+}
+
+func (t *jsonPrinter) Error(item string, err error) error {
+	t.error(errorUpdate{
+		MessageType: "error",
+		Error:       errorObject{err.Error()},
+		During:      "restore",
+		Item:        item,
+	})
+	return nil
+}
+
+func (t *jsonPrinter) ReportAction(action ItemAction, item string, size uint64) {
+	if !t.dryRun && t.verbosity < 3 {
+		return
+	}
+
+	t.print(verboseUpdate{
+		MessageType: "verbose_status",
+		Action:      string(action),
+		Item:        item,
+		Size:        size,
+	})
+}
+
+func (t *jsonPrinter) Finish(p State, duration time.Duration) {
+	status := summaryOutput{
+		MessageType:    "summary",
+		SecondsElapsed: uint64(duration / time.Second),
+		TotalFiles:     p.FilesTotal,
+		FilesRestored:  p.FilesFinished,
+		FilesSkipped:   p.FilesSkipped,
+		FilesDeleted:   p.FilesDeleted,
+		TotalBytes:     p.AllBytesTotal,
+		BytesRestored:  p.AllBytesWritten,
+		BytesSkipped:   p.AllBytesSkipped,
+		DryRun:         t.dryRun,
+	}
+	t.print(status)
+}
+
+type statusUpdate struct {
+	MessageType      string  `json:"message_type"` // "status"
+	SecondsElapsed   uint64  `json:"seconds_elapsed,omitempty"`
+	SecondsRemaining uint64  `json:"seconds_remaining,omitempty"`
+	PercentDone      float64 `json:"percent_done"`
+	TotalFiles       uint64  `json:"total_files,omitempty"`
+	FilesRestored    uint64  `json:"files_restored,omitempty"`
+	FilesSkipped     uint64  `json:"files_skipped,omitempty"`
+	TotalBytes       uint64  `json:"total_bytes,omitempty"`
+	BytesRestored    uint64  `json:"bytes_restored,omitempty"`
+	BytesSkipped     uint64  `json:"bytes_skipped,omitempty"`
+}
+
+type errorObject struct {
+	Message string `json:"message"`
+}
+
+type errorUpdate struct {
+	MessageType string      `json:"message_type"` // "error"
+	Error       errorObject `json:"error"`
+	During      string      `json:"during"`
+	Item        string      `json:"item"`
+}
+
+type verboseUpdate struct {
+	MessageType string `json:"message_type"` // "verbose_status"
+	Action      string `json:"action"`
+	Item        string `json:"item"`
+	Size        uint64 `json:"size"`
+}
+
+type summaryOutput struct {
+	MessageType    string `json:"message_type"` // "summary"
+	SecondsElapsed uint64 `json:"seconds_elapsed,omitempty"`
+	TotalFiles     uint64 `json:"total_files"`
+	FilesRestored  uint64 `json:"files_restored"`
+	FilesSkipped   uint64 `json:"files_skipped"`
+	FilesDeleted   uint64 `json:"files_deleted"`
+	TotalBytes     uint64 `json:"total_bytes"`
+	BytesRestored  uint64 `json:"bytes_restored"`
+	BytesSkipped   uint64 `json:"bytes_skipped"`
+	DryRun         bool   `json:"dry_run,omitempty"`
+}
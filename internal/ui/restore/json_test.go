@@ -0,0 +1,48 @@
+package restore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/restic/restic/internal/test"
+)
+
+func TestJSONPrintUpdate(t *testing.T) {
+	term := &mockTerm{}
+	printer := NewJSONProgressPrinter(term, 1, false)
+	printer.Update(State{3, 11, 0, 29, 47, 0, 0}, 5*time.Second)
+	test.Equals(t, []string{"{\"message_type\":\"status\",\"seconds_elapsed\":5,\"seconds_remaining\":3,\"percent_done\":0.6170212765957447,\"total_files\":11,\"files_restored\":3,\"total_bytes\":47,\"bytes_restored\":29}\n"}, term.output)
+}
+
+func TestJSONPrintSummaryOnSuccess(t *testing.T) {
+	term := &mockTerm{}
+	printer := NewJSONProgressPrinter(term, 1, false)
+	printer.Finish(State{11, 11, 0, 47, 47, 0, 0}, 5*time.Second)
+	test.Equals(t, []string{"{\"message_type\":\"summary\",\"seconds_elapsed\":5,\"total_files\":11,\"files_restored\":11,\"files_skipped\":0,\"files_deleted\":0,\"total_bytes\":47,\"bytes_restored\":47,\"bytes_skipped\":0}\n"}, term.output)
+}
+
+func TestJSONPrintError(t *testing.T) {
+	term := &mockTerm{}
+	printer := NewJSONProgressPrinter(term, 1, false)
+	test.OK(t, printer.Error("/path", errors.New("error \"message\"")))
+	test.Equals(t, []string{"{\"message_type\":\"error\",\"error\":{\"message\":\"error \\\"message\\\"\"},\"during\":\"restore\",\"item\":\"/path\"}\n"}, term.output)
+}
+
+func TestJSONPrintReportAction(t *testing.T) {
+	term := &mockTerm{}
+	printer := NewJSONProgressPrinter(term, 1, false)
+	printer.ReportAction(ActionCreated, "/foo", 47)
+	test.Equals(t, []string(nil), term.output)
+
+	printer = NewJSONProgressPrinter(term, 1, true)
+	printer.ReportAction(ActionOverwritten, "/foo", 47)
+	test.Equals(t, []string{"{\"message_type\":\"verbose_status\",\"action\":\"overwritten\",\"item\":\"/foo\",\"size\":47}\n"}, term.output)
+}
+
+func TestJSONPrintSummaryDryRun(t *testing.T) {
+	term := &mockTerm{}
+	printer := NewJSONProgressPrinter(term, 1, true)
+	printer.Finish(State{0, 11, 2, 0, 47, 5, 3}, 5*time.Second)
+	test.Equals(t, []string{"{\"message_type\":\"summary\",\"seconds_elapsed\":5,\"total_files\":11,\"files_restored\":0,\"files_skipped\":2,\"files_deleted\":3,\"total_bytes\":47,\"bytes_restored\":0,\"bytes_skipped\":5,\"dry_run\":true}\n"}, term.output)
+}
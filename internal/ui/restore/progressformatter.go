@@ -14,10 +14,7 @@ type Progress struct {
 	m       sync.Mutex
 
 	progressInfoMap map[string]progressInfoEntry
-	filesFinished   uint64
-	filesTotal      uint64
-	allBytesWritten uint64
-	allBytesTotal   uint64
+	s               State
 	started         time.Time
 
 	printer ProgressPrinter
@@ -28,9 +25,35 @@ type progressInfoEntry struct {
 	bytesTotal   uint64
 }
 
+// State contains the current progress of a restore operation.
+type State struct {
+	FilesFinished   uint64
+	FilesTotal      uint64
+	FilesSkipped    uint64
+	AllBytesWritten uint64
+	AllBytesTotal   uint64
+	AllBytesSkipped uint64
+	FilesDeleted    uint64
+}
+
+// ItemAction describes what happens to an item in the target directory.
+type ItemAction string
+
+// Constants for the different actions.
+const (
+	ActionCreated     ItemAction = "created"
+	ActionOverwritten ItemAction = "overwritten"
+	ActionUpdated     ItemAction = "updated"
+	ActionUnchanged   ItemAction = "unchanged"
+	ActionSkipped     ItemAction = "skipped"
+	ActionDeleted     ItemAction = "deleted"
+)
+
 type ProgressPrinter interface {
-	Update(filesFinished, filesTotal, allBytesWritten, allBytesTotal uint64, duration time.Duration)
-	Finish(filesFinished, filesTotal, allBytesWritten, allBytesTotal uint64, duration time.Duration)
+	Update(progress State, duration time.Duration)
+	Error(item string, err error) error
+	ReportAction(action ItemAction, item string, size uint64)
+	Finish(progress State, duration time.Duration)
 }
 
 func NewProgress(printer ProgressPrinter, interval time.Duration) *Progress {
@@ -48,9 +71,9 @@ func (p *Progress) update(runtime time.Duration, final bool) {
 	defer p.m.Unlock()
 
 	if !final {
-		p.printer.Update(p.filesFinished, p.filesTotal, p.allBytesWritten, p.allBytesTotal, runtime)
+		p.printer.Update(p.s, runtime)
 	} else {
-		p.printer.Finish(p.filesFinished, p.filesTotal, p.allBytesWritten, p.allBytesTotal, runtime)
+		p.printer.Finish(p.s, runtime)
 	}
 }
 
@@ -59,8 +82,38 @@ func (p *Progress) AddFile(size uint64) {
 	p.m.Lock()
 	defer p.m.Unlock()
 
-	p.filesTotal++
-	p.allBytesTotal += size
+	p.s.FilesTotal++
+	p.s.AllBytesTotal += size
+}
+
+// AddSkippedFile records a file of the given size that is not restored
+// because it already exists in the target
+func (p *Progress) AddSkippedFile(size uint64) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	p.s.FilesSkipped++
+	p.s.AllBytesSkipped += size
+}
+
+// Error reports an error for the item, it returns the error returned by the
+// printer.
+func (p *Progress) Error(name string, err error) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	return p.printer.Error(name, err)
+}
+
+// ReportAction reports what happens to the item in the target directory
+func (p *Progress) ReportAction(action ItemAction, name string, size uint64) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if action == ActionDeleted {
+		p.s.FilesDeleted++
+	}
+	p.printer.ReportAction(action, name, size)
 }
 
 // AddProgress accumulates the number of bytes written for a file
@@ -75,10 +128,10 @@ func (p *Progress) AddProgress(name string, bytesWrittenPortion uint64, bytesTot
 	entry.bytesWritten += bytesWrittenPortion
 	p.progressInfoMap[name] = entry
 
-	p.allBytesWritten += bytesWrittenPortion
+	p.s.AllBytesWritten += bytesWrittenPortion
 	if entry.bytesWritten == entry.bytesTotal {
 		delete(p.progressInfoMap, name)
-		p.filesFinished++
+		p.s.FilesFinished++
 	}
 }
 
@@ -88,43 +141,89 @@ func (p *Progress) Finish() {
 
 type term interface {
 	Print(line string)
+	Error(line string)
 	SetStatus(lines []string)
 }
 
 type textPrinter struct {
-	terminal term
+	terminal  term
+	verbosity uint
+	dryRun    bool
 }
 
-func NewProgressPrinter(terminal term) ProgressPrinter {
+// NewProgressPrinter returns a printer which reports the progress as text.
+// The action for each item is only printed for dry runs or if verbosity is
+// at least 3.
+func NewProgressPrinter(terminal term, verbosity uint, dryRun bool) ProgressPrinter {
 	return &textPrinter{
-		terminal: terminal,
+		terminal:  terminal,
+		verbosity: verbosity,
+		dryRun:    dryRun,
 	}
 }
 
-func (t *textPrinter) Update(filesFinished, filesTotal, allBytesWritten, allBytesTotal uint64, duration time.Duration) {
+func (t *textPrinter) Update(p State, duration time.Duration) {
+	if t.dryRun {
+		// nothing is written during a dry run
+		return
+	}
+
 	timeLeft := ui.FormatDuration(duration)
-	formattedAllBytesWritten := ui.FormatBytes(allBytesWritten)
-	formattedAllBytesTotal := ui.FormatBytes(allBytesTotal)
-	allPercent := ui.FormatPercent(allBytesWritten, allBytesTotal)
+	formattedAllBytesWritten := ui.FormatBytes(p.AllBytesWritten)
+	formattedAllBytesTotal := ui.FormatBytes(p.AllBytesTotal)
+	allPercent := ui.FormatPercent(p.AllBytesWritten, p.AllBytesTotal)
 	progress := fmt.Sprintf("[%s] %s  %v files %s, total %v files %v",
-		timeLeft, allPercent, filesFinished, formattedAllBytesWritten, filesTotal, formattedAllBytesTotal)
+		timeLeft, allPercent, p.FilesFinished, formattedAllBytesWritten, p.FilesTotal, formattedAllBytesTotal)
+	if p.FilesSkipped > 0 {
+		progress += fmt.Sprintf(", skipped %v files %v", p.FilesSkipped, ui.FormatBytes(p.AllBytesSkipped))
+	}
 
 	t.terminal.SetStatus([]string{progress})
 }
 
-func (t *textPrinter) Finish(filesFinished, filesTotal, allBytesWritten, allBytesTotal uint64, duration time.Duration) {
+func (t *textPrinter) Error(item string, err error) error {
+	t.terminal.Error(fmt.Sprintf("ignoring error for %s: %s\n", item, err))
+	return nil
+}
+
+func (t *textPrinter) ReportAction(action ItemAction, item string, size uint64) {
+	if !t.dryRun && t.verbosity < 3 {
+		return
+	}
+
+	if size > 0 {
+		t.terminal.Print(fmt.Sprintf("%-11v %v (%v)", action, item, ui.FormatBytes(size)))
+	} else {
+		t.terminal.Print(fmt.Sprintf("%-11v %v", action, item))
+	}
+}
+
+func (t *textPrinter) Finish(p State, duration time.Duration) {
 	t.terminal.SetStatus([]string{})
 
 	timeLeft := ui.FormatDuration(duration)
-	formattedAllBytesTotal := ui.FormatBytes(allBytesTotal)
+	formattedAllBytesTotal := ui.FormatBytes(p.AllBytesTotal)
 
 	var summary string
-	if filesFinished == filesTotal && allBytesWritten == allBytesTotal {
-		summary = fmt.Sprintf("Summary: Restored %d Files (%s) in %s", filesTotal, formattedAllBytesTotal, timeLeft)
-	} else {
-		formattedAllBytesWritten := ui.FormatBytes(allBytesWritten)
+	switch {
+	case t.dryRun:
+		summary = fmt.Sprintf("Summary: Would restore %d Files (%s)", p.FilesTotal, formattedAllBytesTotal)
+	case p.FilesFinished == p.FilesTotal && p.AllBytesWritten == p.AllBytesTotal:
+		summary = fmt.Sprintf("Summary: Restored %d Files (%s) in %s", p.FilesTotal, formattedAllBytesTotal, timeLeft)
+	default:
+		formattedAllBytesWritten := ui.FormatBytes(p.AllBytesWritten)
 		summary = fmt.Sprintf("Summary: Restored %d / %d Files (%s / %s) in %s",
-			filesFinished, filesTotal, formattedAllBytesWritten, formattedAllBytesTotal, timeLeft)
+			p.FilesFinished, p.FilesTotal, formattedAllBytesWritten, formattedAllBytesTotal, timeLeft)
+	}
+	skipped, deleted := "skipped", "deleted"
+	if t.dryRun {
+		skipped, deleted = "skip", "delete"
+	}
+	if p.FilesSkipped > 0 {
+		summary += fmt.Sprintf(", %s %v files (%s)", skipped, p.FilesSkipped, ui.FormatBytes(p.AllBytesSkipped))
+	}
+	if p.FilesDeleted > 0 {
+		summary += fmt.Sprintf(", %s %v files", deleted, p.FilesDeleted)
 	}
 
 	t.terminal.Print(summary)
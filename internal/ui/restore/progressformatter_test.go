@@ -8,7 +8,7 @@ import (
 )
 
 type printerTraceEntry struct {
-	filesFinished, filesTotal, allBytesWritten, allBytesTotal uint64
+	progress State
 
 	duration   time.Duration
 	isFinished bool
@@ -22,11 +22,16 @@ type mockPrinter struct {
 
 const mockFinishDuration = 42 * time.Second
 
-func (p *mockPrinter) Update(filesFinished, filesTotal, allBytesWritten, allBytesTotal uint64, duration time.Duration) {
-	p.trace = append(p.trace, printerTraceEntry{filesFinished, filesTotal, allBytesWritten, allBytesTotal, duration, false})
+func (p *mockPrinter) Update(progress State, duration time.Duration) {
+	p.trace = append(p.trace, printerTraceEntry{progress, duration, false})
 }
-func (p *mockPrinter) Finish(filesFinished, filesTotal, allBytesWritten, allBytesTotal uint64, _ time.Duration) {
-	p.trace = append(p.trace, printerTraceEntry{filesFinished, filesTotal, allBytesWritten, allBytesTotal, mockFinishDuration, true})
+func (p *mockPrinter) Error(_ string, _ error) error {
+	return nil
+}
+func (p *mockPrinter) ReportAction(_ ItemAction, _ string, _ uint64) {
+}
+func (p *mockPrinter) Finish(progress State, _ time.Duration) {
+	p.trace = append(p.trace, printerTraceEntry{progress, mockFinishDuration, true})
 }
 
 func testProgress(fn func(progress *Progress) bool) printerTrace {
@@ -45,7 +50,7 @@ func TestNew(t *testing.T) {
 		return false
 	})
 	test.Equals(t, printerTrace{
-		printerTraceEntry{0, 0, 0, 0, 0, false},
+		printerTraceEntry{State{0, 0, 0, 0, 0, 0, 0}, 0, false},
 	}, result)
 }
 
@@ -57,7 +62,7 @@ func TestAddFile(t *testing.T) {
 		return false
 	})
 	test.Equals(t, printerTrace{
-		printerTraceEntry{0, 1, 0, fileSize, 0, false},
+		printerTraceEntry{State{0, 1, 0, 0, fileSize, 0, 0}, 0, false},
 	}, result)
 }
 
@@ -71,7 +76,7 @@ func TestFirstProgressOnAFile(t *testing.T) {
 		return false
 	})
 	test.Equals(t, printerTrace{
-		printerTraceEntry{0, 1, expectedBytesWritten, expectedBytesTotal, 0, false},
+		printerTraceEntry{State{0, 1, 0, expectedBytesWritten, expectedBytesTotal, 0, 0}, 0, false},
 	}, result)
 }
 
@@ -86,7 +91,7 @@ func TestLastProgressOnAFile(t *testing.T) {
 		return false
 	})
 	test.Equals(t, printerTrace{
-		printerTraceEntry{1, 1, fileSize, fileSize, 0, false},
+		printerTraceEntry{State{1, 1, 0, fileSize, fileSize, 0, 0}, 0, false},
 	}, result)
 }
 
@@ -102,7 +107,7 @@ func TestLastProgressOnLastFile(t *testing.T) {
 		return false
 	})
 	test.Equals(t, printerTrace{
-		printerTraceEntry{2, 2, 50 + fileSize, 50 + fileSize, 0, false},
+		printerTraceEntry{State{2, 2, 0, 50 + fileSize, 50 + fileSize, 0, 0}, 0, false},
 	}, result)
 }
 
@@ -117,7 +122,7 @@ func TestSummaryOnSuccess(t *testing.T) {
 		return true
 	})
 	test.Equals(t, printerTrace{
-		printerTraceEntry{2, 2, 50 + fileSize, 50 + fileSize, mockFinishDuration, true},
+		printerTraceEntry{State{2, 2, 0, 50 + fileSize, 50 + fileSize, 0, 0}, mockFinishDuration, true},
 	}, result)
 }
 
@@ -132,7 +137,30 @@ func TestSummaryOnErrors(t *testing.T) {
 		return true
 	})
 	test.Equals(t, printerTrace{
-		printerTraceEntry{1, 2, 50 + fileSize/2, 50 + fileSize, mockFinishDuration, true},
+		printerTraceEntry{State{1, 2, 0, 50 + fileSize/2, 50 + fileSize, 0, 0}, mockFinishDuration, true},
+	}, result)
+}
+
+func TestSkipFile(t *testing.T) {
+	fileSize := uint64(100)
+
+	result := testProgress(func(progress *Progress) bool {
+		progress.AddSkippedFile(fileSize)
+		return true
+	})
+	test.Equals(t, printerTrace{
+		printerTraceEntry{State{0, 0, 1, 0, 0, fileSize, 0}, mockFinishDuration, true},
+	}, result)
+}
+
+func TestReportDeleted(t *testing.T) {
+	result := testProgress(func(progress *Progress) bool {
+		progress.ReportAction(ActionDeleted, "test", 0)
+		progress.ReportAction(ActionCreated, "test2", 0)
+		return true
+	})
+	test.Equals(t, printerTrace{
+		printerTraceEntry{State{0, 0, 0, 0, 0, 0, 1}, mockFinishDuration, true},
 	}, result)
 }
 
@@ -144,27 +172,59 @@ func (m *mockTerm) Print(line string) {
 	m.output = append(m.output, line)
 }
 
+func (m *mockTerm) Error(line string) {
+	m.output = append(m.output, line)
+}
+
 func (m *mockTerm) SetStatus(lines []string) {
 	m.output = append([]string{}, lines...)
 }
 
 func TestPrintUpdate(t *testing.T) {
 	term := &mockTerm{}
-	printer := NewProgressPrinter(term)
-	printer.Update(3, 11, 29, 47, 5*time.Second)
+	printer := NewProgressPrinter(term, 1, false)
+	printer.Update(State{3, 11, 0, 29, 47, 0, 0}, 5*time.Second)
 	test.Equals(t, []string{"[0:05] 61.70%  3 files 29 B, total 11 files 47 B"}, term.output)
 }
 
 func TestPrintSummaryOnSuccess(t *testing.T) {
 	term := &mockTerm{}
-	printer := NewProgressPrinter(term)
-	printer.Finish(11, 11, 47, 47, 5*time.Second)
+	printer := NewProgressPrinter(term, 1, false)
+	printer.Finish(State{11, 11, 0, 47, 47, 0, 0}, 5*time.Second)
 	test.Equals(t, []string{"Summary: Restored 11 Files (47 B) in 0:05"}, term.output)
 }
 
 func TestPrintSummaryOnErrors(t *testing.T) {
 	term := &mockTerm{}
-	printer := NewProgressPrinter(term)
-	printer.Finish(3, 11, 29, 47, 5*time.Second)
+	printer := NewProgressPrinter(term, 1, false)
+	printer.Finish(State{3, 11, 0, 29, 47, 0, 0}, 5*time.Second)
 	test.Equals(t, []string{"Summary: Restored 3 / 11 Files (29 B / 47 B) in 0:05"}, term.output)
 }
+
+func TestPrintSummaryOnSkipped(t *testing.T) {
+	term := &mockTerm{}
+	printer := NewProgressPrinter(term, 1, false)
+	printer.Finish(State{5, 5, 6, 29, 29, 18, 0}, 5*time.Second)
+	test.Equals(t, []string{"Summary: Restored 5 Files (29 B) in 0:05, skipped 6 files (18 B)"}, term.output)
+}
+
+func TestPrintReportAction(t *testing.T) {
+	term := &mockTerm{}
+	printer := NewProgressPrinter(term, 1, false)
+	printer.ReportAction(ActionCreated, "/foo", 47)
+	test.Equals(t, []string(nil), term.output)
+
+	printer = NewProgressPrinter(term, 3, false)
+	printer.ReportAction(ActionCreated, "/foo", 47)
+	printer.ReportAction(ActionDeleted, "/bar", 0)
+	test.Equals(t, []string{"created     /foo (47 B)", "deleted     /bar"}, term.output)
+}
+
+func TestPrintSummaryDryRun(t *testing.T) {
+	term := &mockTerm{}
+	printer := NewProgressPrinter(term, 1, true)
+	printer.Update(State{0, 11, 0, 0, 47, 0, 0}, 5*time.Second)
+	test.Equals(t, []string(nil), term.output)
+	printer.Finish(State{0, 11, 2, 0, 47, 5, 3}, 5*time.Second)
+	test.Equals(t, []string{"Summary: Would restore 11 Files (47 B), skip 2 files (5 B), delete 3 files"}, term.output)
+}
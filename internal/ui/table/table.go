@@ -6,6 +6,8 @@ import (
 	"strings"
 
 	"text/template"
+
+	"golang.org/x/text/width"
 )
 
 // Table contains data for a table to be printed.
@@ -23,7 +25,51 @@ type Table struct {
 }
 
 var funcmap = template.FuncMap{
-	"join": strings.Join,
+	"join":     strings.Join,
+	"truncate": truncate,
+}
+
+// truncate shortens s to at most w terminal cells. If s is truncated, it ends
+// with "...".
+func truncate(w int, s string) string {
+	if displayWidth(s) <= w {
+		return s
+	}
+	if w <= 3 {
+		return truncateWidth(s, w)
+	}
+	return truncateWidth(s, w-3) + "..."
+}
+
+// truncateWidth returns the longest prefix of s which fits into w terminal
+// cells.
+func truncateWidth(s string, w int) string {
+	for i, r := range s {
+		w -= runeWidth(r)
+		if w < 0 {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// displayWidth returns the number of terminal cells s occupies.
+func displayWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		w += runeWidth(r)
+	}
+	return w
+}
+
+// runeWidth returns two for wide East Asian characters and one for all
+// others.
+func runeWidth(r rune) int {
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
 }
 
 // New initializes a new Table
@@ -89,7 +135,7 @@ func printLine(w io.Writer, print func(io.Writer, string) error, sep string, dat
 			}
 
 			// apply padding
-			pad := widths[fieldNum] - len(v)
+			pad := widths[fieldNum] - displayWidth(v)
 			if pad > 0 {
 				v += strings.Repeat(" ", pad)
 			}
@@ -147,8 +193,8 @@ func (t *Table) Write(w io.Writer) error {
 	for _, line := range lines {
 		for i, content := range line {
 			for _, l := range strings.Split(content, "\n") {
-				if columnWidths[i] < len(l) {
-					columnWidths[i] = len(l)
+				if w := displayWidth(l); columnWidths[i] < w {
+					columnWidths[i] = w
 				}
 			}
 		}
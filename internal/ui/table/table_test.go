@@ -140,6 +140,52 @@ foo        2018-08-19 22:22:22  xxx  other  /home/user/other
 foo        2018-08-19 22:22:22  xxx  other  /home/user/other
                                      bar
 ------------------------------------------------------------
+`,
+		},
+		{
+			func(t testing.TB) *Table {
+				table := New()
+				table.AddColumn("name", `{{.Name}}`)
+				table.AddColumn("note", `{{truncate 10 .Note}}`)
+
+				type data struct {
+					Name, Note string
+				}
+				table.AddRow(data{"foo", "short"})
+				table.AddRow(data{"bar", "a much longer note"})
+				table.AddRow(data{"baz", "exactly 10"})
+				return table
+			},
+			`
+name  note
+----------------
+foo   short
+bar   a much ...
+baz   exactly 10
+----------------
+`,
+		},
+		{
+			func(t testing.TB) *Table {
+				table := New()
+				table.AddColumn("note", `{{truncate 10 .Note}}`)
+				table.AddColumn("name", `{{.Name}}`)
+
+				type data struct {
+					Name, Note string
+				}
+				table.AddRow(data{"foo", "日本語のメモです"})
+				table.AddRow(data{"bar", "äöü"})
+				table.AddRow(data{"baz", "日本"})
+				return table
+			},
+			`
+note       name
+---------------
+日本語...  foo
+äöü        bar
+日本       baz
+---------------
 `,
 		},
 	}